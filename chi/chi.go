@@ -0,0 +1,66 @@
+// Package chi sends the errors of chi (github.com/go-chi/chi) routers as RFC 7807 problems with the
+// problem handler of package middleware. chi routers use standard net/http handlers so the package
+// does not depend on chi.
+package chi
+
+import (
+	"net/http"
+
+	"github.com/goadesign/goa"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+// Middleware returns the net/http middleware of p, register it with the Use method of the router
+// so that handlers can send problems with middleware.WriteProblem and that panics are sent as 500
+// problems.
+func Middleware(p *middleware.ProblemHandler) func(http.Handler) http.Handler {
+	return p.HTTPMiddleware()
+}
+
+// NotFoundHandler returns a handler sending 404 problems with p, register it with the NotFound
+// method of the router.
+func NotFoundHandler(p *middleware.ProblemHandler) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		p.WriteProblem(rw, req, goa.ErrNotFound(http.StatusText(http.StatusNotFound)))
+	}
+}
+
+// MethodNotAllowedHandler returns a handler sending 405 problems with p, register it with the
+// MethodNotAllowed method of the router. chi only sets the Allow header in its own 405 handler so
+// the allowed methods of the request are computed by allowed, see AllowedMethods, for example:
+//
+//	r := chi.NewRouter()
+//	r.MethodNotAllowed(goanschi.MethodNotAllowedHandler(p, goanschi.AllowedMethods(func(method, path string) bool {
+//		return r.Match(chi.NewRouteContext(), method, path)
+//	})))
+func MethodNotAllowedHandler(p *middleware.ProblemHandler, allowed func(*http.Request) []string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		var methods []string
+		if allowed != nil {
+			methods = allowed(req)
+		}
+		p.WriteProblem(rw, req, goa.MethodNotAllowedError(req.Method, methods))
+	}
+}
+
+// standardMethods are the methods probed by AllowedMethods.
+var standardMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
+
+// AllowedMethods returns a function computing the allowed methods of a request by probing the
+// routes with match for each standard HTTP method, match is typically the Match method of the chi
+// router.
+func AllowedMethods(match func(method, path string) bool) func(*http.Request) []string {
+	return func(req *http.Request) []string {
+		var methods []string
+		for _, m := range standardMethods {
+			if match(m, req.URL.Path) {
+				methods = append(methods, m)
+			}
+		}
+		return methods
+	}
+}
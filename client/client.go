@@ -0,0 +1,129 @@
+package client
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+type (
+	// ProblemError is the error returned by Client.Do and the round trippers created with
+	// Transport for problem responses.
+	ProblemError struct {
+		// Problem is the decoded problem.
+		Problem *middleware.Rfc7807Response
+		// Header contains the headers of the problem response, e.g. Retry-After.
+		Header http.Header
+	}
+
+	// Client sends requests with an http.Client and converts problem responses into errors.
+	Client struct {
+		c *http.Client
+		o *options
+	}
+
+	// transport converts problem responses into errors.
+	transport struct {
+		base http.RoundTripper
+		o    *options
+	}
+)
+
+// NewClient returns a client sending requests with c, or http.DefaultClient if c is nil.
+func NewClient(c *http.Client, opts ...Option) *Client {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	return &Client{c: c, o: newOptions(opts...)}
+}
+
+// Do sends req and converts the responses with an error status and a problem media type, or a
+// vendor media type configured with WithVendorMediaTypes, into *ProblemError errors so that
+// callers use errors.As to retrieve them:
+//
+//	var perr *client.ProblemError
+//	if errors.As(err, &perr) && perr.Problem.Status == http.StatusNotFound {
+//		...
+//	}
+//
+// Goa handlers may return these errors as is: the Rfc7807Handler middleware unwraps them and
+// re-emits the upstream problem with its status, type, detail, trace ID and meta values, see
+// ProblemError.Unwrap, instead of masking them as internal errors. Other responses, including
+// the problems of successful responses such as 207 batch responses, are returned unchanged.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	resp, err := c.c.Do(req)
+	if err != nil {
+		return resp, err
+	}
+	if err := c.o.problemError(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Transport returns a round tripper that sends requests with base, or http.DefaultTransport if
+// base is nil, and converts problem responses into *ProblemError errors like Client.Do, for the
+// clients that cannot be replaced with a Client such as the ones of generated goa clients. The
+// http.Client returns the errors wrapped in a *url.Error, errors.As retrieves them the same way.
+func Transport(base http.RoundTripper, opts ...Option) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, o: newOptions(opts...)}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if err := t.o.problemError(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Error returns the status, title and detail of the problem.
+func (e *ProblemError) Error() string {
+	msg := fmt.Sprintf("%d %s", e.Problem.Status, e.Problem.Title)
+	if e.Problem.Detail != "" {
+		msg += ": " + e.Problem.Detail
+	}
+	return msg
+}
+
+// StatusCode returns the status of the problem.
+func (e *ProblemError) StatusCode() int {
+	return e.Problem.Status
+}
+
+// problemError returns the *ProblemError error of resp if it is a problem response, the error
+// decoding it if it fails and nil otherwise. The body of problem responses is closed.
+func (o *options) problemError(resp *http.Response) error {
+	if !o.isProblem(resp) {
+		return nil
+	}
+	defer resp.Body.Close()
+	problem, err := o.parseProblem(resp)
+	if err != nil {
+		return err
+	}
+	return &ProblemError{Problem: problem, Header: resp.Header}
+}
+
+// isProblem returns true if resp has an error status and a problem media type or a configured
+// vendor media type.
+func (o *options) isProblem(resp *http.Response) bool {
+	if resp.StatusCode < 400 {
+		return false
+	}
+	mt, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return o.problemFormat(strings.ToLower(mt)) != ""
+}
@@ -0,0 +1,204 @@
+// Package client helps Go clients of services that use the goans middleware handle problem
+// responses.
+package client
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+const (
+	// maxProblemSize is the maximum number of bytes read from problem response bodies.
+	maxProblemSize = 1 << 20
+
+	// defaultBodySnippetSize is the default maximum size of the body snippet of synthetic
+	// problems.
+	defaultBodySnippetSize = 256
+
+	// upstreamDetail is the detail of synthetic problems.
+	upstreamDetail = "upstream returned non-problem response"
+)
+
+type (
+	// Option configures the parsing of problem responses.
+	Option func(*options)
+
+	// options holds the settings used to parse problem responses.
+	options struct {
+		// bodySnippetSize is the maximum size of the body snippet of synthetic problems.
+		bodySnippetSize int
+		// rewrites are applied to the parsed problems.
+		rewrites []func(*middleware.Rfc7807Response)
+		// vendorMediaTypes are the lowercase vendor media types of problems.
+		vendorMediaTypes []string
+	}
+)
+
+// WithVendorMediaTypes sets the vendor media types of the problems sent by services configured
+// with middleware.WithVendorMediaType, e.g. "application/vnd.acme.problem+json". They are decoded
+// as JSON or XML problems according to their structured syntax suffix, other vendor media types
+// are not problems.
+func WithVendorMediaTypes(types ...string) Option {
+	return func(o *options) {
+		for _, t := range types {
+			if mt, _, err := mime.ParseMediaType(t); err == nil {
+				o.vendorMediaTypes = append(o.vendorMediaTypes, mt)
+			}
+		}
+	}
+}
+
+// WithUpstreamBodySnippet sets the maximum number of bytes of the response body included in the
+// "body" meta value of synthetic problems created for error responses that are not problems. No
+// snippet is included if max is 0 or less. The default is 256 bytes.
+func WithUpstreamBodySnippet(max int) Option {
+	return func(o *options) {
+		o.bodySnippetSize = max
+	}
+}
+
+// ParseProblem reads and decodes the problem contained in the body of resp. Error responses that
+// do not contain a JSON or XML problem, such as HTML error pages returned by proxies, are turned
+// into a synthetic problem with the response status, the detail "upstream returned non-problem
+// response" and a snippet of the body in the "body" meta value. The delay of the Retry-After
+// header is recorded in the retry_after meta value of problems that do not have one so that it
+// is sent again when the problem is re-emitted. ParseProblem returns an error if resp is not an
+// error response and does not contain a problem. The caller is responsible for closing the
+// response body.
+func ParseProblem(resp *http.Response, opts ...Option) (*middleware.Rfc7807Response, error) {
+	return newOptions(opts...).parseProblem(resp)
+}
+
+// newOptions returns the settings resulting from applying opts in order.
+func newOptions(opts ...Option) *options {
+	o := &options{bodySnippetSize: defaultBodySnippetSize}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// parseProblem implements ParseProblem.
+func (o *options) parseProblem(resp *http.Response) (*middleware.Rfc7807Response, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxProblemSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read problem: %s", err)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mt = strings.ToLower(contentType)
+	}
+	var problem middleware.Rfc7807Response
+	switch o.problemFormat(mt) {
+	case "json":
+		if err := json.Unmarshal(body, &problem); err != nil {
+			return nil, fmt.Errorf("failed to decode JSON problem: %s", err)
+		}
+		if problem.Type == "" {
+			// Problems of services using middleware.WithLegacyFieldNames
+			var legacy struct {
+				Type string `json:"tye"`
+			}
+			json.Unmarshal(body, &legacy)
+			problem.Type = legacy.Type
+		}
+	case "xml":
+		if err := xml.Unmarshal(body, &problem); err != nil {
+			return nil, fmt.Errorf("failed to decode XML problem: %s", err)
+		}
+	default:
+		if resp.StatusCode < 400 {
+			return nil, fmt.Errorf("response with status %d and content type %q is not a problem", resp.StatusCode, contentType)
+		}
+		problem = middleware.Rfc7807Response{
+			Title:  http.StatusText(resp.StatusCode),
+			Status: resp.StatusCode,
+			Detail: upstreamDetail,
+			Meta:   map[string]interface{}{"content_type": contentType},
+		}
+		if o.bodySnippetSize > 0 && len(body) > 0 {
+			problem.Meta["body"] = snippet(body, o.bodySnippetSize)
+		}
+	}
+	if problem.Status == 0 {
+		problem.Status = resp.StatusCode
+	}
+	setRetryAfter(&problem, resp.Header.Get("Retry-After"))
+	for _, f := range o.rewrites {
+		f(&problem)
+	}
+	return &problem, nil
+}
+
+// problemFormat returns the format, "json" or "xml", of the problems of the lowercase media type mt,
+// an empty string if mt is not a problem media type nor a configured vendor media type.
+func (o *options) problemFormat(mt string) string {
+	switch mt {
+	case middleware.Rfc7807JsonMediaIdentifier:
+		return "json"
+	case middleware.Rfc7807XmlMediaIdentifier:
+		return "xml"
+	}
+	for _, vt := range o.vendorMediaTypes {
+		if mt == vt {
+			switch {
+			case strings.HasSuffix(vt, "+json"):
+				return "json"
+			case strings.HasSuffix(vt, "+xml"):
+				return "xml"
+			}
+		}
+	}
+	return ""
+}
+
+// setRetryAfter sets the retry_after meta value of problem to the number of seconds of the
+// Retry-After header value v, a number of seconds or an HTTP date, unless problem has one.
+func setRetryAfter(problem *middleware.Rfc7807Response, v string) {
+	if v == "" {
+		return
+	}
+	if _, ok := problem.Meta[middleware.RetryAfterMetaKey]; ok {
+		return
+	}
+	secs, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil {
+		t, err := http.ParseTime(v)
+		if err != nil {
+			return
+		}
+		secs = int64(math.Ceil(time.Until(t).Seconds()))
+	}
+	if secs < 0 {
+		secs = 0
+	}
+	if problem.Meta == nil {
+		problem.Meta = make(map[string]interface{})
+	}
+	problem.Meta[middleware.RetryAfterMetaKey] = secs
+}
+
+// snippet returns at most max bytes of b as a string without splitting UTF-8 sequences.
+func snippet(b []byte, max int) string {
+	if len(b) <= max {
+		return string(b)
+	}
+	b = b[:max]
+	for len(b) > 0 && !utf8.Valid(b) {
+		b = b[:len(b)-1]
+	}
+	return string(b)
+}
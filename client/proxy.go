@@ -0,0 +1,61 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+// WithProblemRewrite applies f to the parsed problems, for example to move the type and instance
+// of the problems of an upstream API to the namespace of the local service, see RewriteNamespace.
+func WithProblemRewrite(f func(*middleware.Rfc7807Response)) Option {
+	return func(o *options) {
+		o.rewrites = append(o.rewrites, f)
+	}
+}
+
+// RewriteNamespace returns a rewrite replacing the upstream prefix of the type and instance of
+// problems with local, e.g. "https://billing.internal/probs/" with "https://api.example.com/probs/".
+func RewriteNamespace(upstream, local string) func(*middleware.Rfc7807Response) {
+	return func(problem *middleware.Rfc7807Response) {
+		if strings.HasPrefix(problem.Type, upstream) {
+			problem.Type = local + strings.TrimPrefix(problem.Type, upstream)
+		}
+		if strings.HasPrefix(problem.Instance, upstream) {
+			problem.Instance = local + strings.TrimPrefix(problem.Instance, upstream)
+		}
+	}
+}
+
+// Unwrap returns the problem so that the Rfc7807Handler middleware and WriteProblem re-emit the
+// upstream problem, with its status, type and members, when handlers return the errors of the
+// requests sent with Client.Do or Transport.
+func (e *ProblemError) Unwrap() error {
+	return e.Problem
+}
+
+// ModifyResponse returns a function to use as the ModifyResponse field of a
+// httputil.ReverseProxy. It converts upstream problem responses with an error status into
+// *ProblemError errors, which the proxy gives to its error handler, see ProxyErrorHandler. Other
+// responses are proxied as is.
+func ModifyResponse(opts ...Option) func(*http.Response) error {
+	o := newOptions(opts...)
+	return func(resp *http.Response) error {
+		return o.problemError(resp)
+	}
+}
+
+// ProxyErrorHandler returns a function to use as the ErrorHandler field of a httputil.ReverseProxy
+// that sends errors with p. The upstream problems converted by ModifyResponse are re-emitted and
+// other errors, which are failures to reach the upstream, are sent as 502 Bad Gateway problems.
+func ProxyErrorHandler(p *middleware.ProblemHandler) func(http.ResponseWriter, *http.Request, error) {
+	return func(rw http.ResponseWriter, req *http.Request, err error) {
+		var perr *ProblemError
+		if !errors.As(err, &perr) {
+			err = middleware.NewProblem(http.StatusBadGateway).Detail("upstream unavailable").Err()
+		}
+		p.WriteProblem(rw, req, err)
+	}
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+// RetryPolicy tells callers whether and when to retry the requests that failed with a problem so
+// that the consumers of goans services handle transient problems consistently, for example:
+//
+//	policy := client.DefaultRetryPolicy()
+//	for attempt := 1; ; attempt++ {
+//		resp, err := c.Do(req)
+//		d, retry := policy.Retry(err, attempt)
+//		if !retry {
+//			return resp, err
+//		}
+//		time.Sleep(d)
+//	}
+//
+// The zero value retries the default statuses up to 3 attempts without jitter.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, the first one included, it defaults to 3.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled on each retry, it defaults to 100
+	// milliseconds.
+	BaseDelay time.Duration
+	// MaxDelay caps the delays, it defaults to 30 seconds. Problems asking to retry after a
+	// longer delay with their retry_after meta value are not retried.
+	MaxDelay time.Duration
+	// Jitter is the fraction of the backoff delays that is randomized, between 0 and 1, so that
+	// clients do not retry in lockstep. A jitter of 0.5 gives delays between half and the whole
+	// backoff delay.
+	Jitter float64
+	// Statuses lists the statuses of the problems that are retried, DefaultRetryStatuses when
+	// nil.
+	Statuses []int
+	// Types lists the types of the problems that are retried whatever their status, e.g.
+	// "https://example.com/probs/dependency-unavailable".
+	Types []string
+}
+
+// DefaultRetryStatuses lists the statuses of transient problems retried by default: 408, 425,
+// 429, 502, 503 and 504.
+var DefaultRetryStatuses = []int{
+	http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests,
+	http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout,
+}
+
+// DefaultRetryPolicy returns a policy retrying transient problems up to 3 attempts with an
+// exponential backoff starting at 100 milliseconds and a jitter of 0.5.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{Jitter: 0.5}
+}
+
+// Retry returns the delay before retrying the request that failed with err at the given attempt,
+// 1 for the first one, and false if the request must not be retried. Only the errors wrapping a
+// problem, such as the *ProblemError errors returned by Client.Do, are retried: their status or
+// type must be retried by the policy and the number of attempts must be below the maximum. The
+// delay is the one of the retry_after meta value of the problem, which ParseProblem sets from the
+// Retry-After header, or the backoff delay otherwise.
+func (p RetryPolicy) Retry(err error, attempt int) (time.Duration, bool) {
+	var problem *middleware.Rfc7807Response
+	if !errors.As(err, &problem) {
+		return 0, false
+	}
+	return p.RetryProblem(problem, attempt)
+}
+
+// RetryProblem is Retry for a decoded problem.
+func (p RetryPolicy) RetryProblem(problem *middleware.Rfc7807Response, attempt int) (time.Duration, bool) {
+	maxAttempts, base, maxDelay := p.MaxAttempts, p.BaseDelay, p.MaxDelay
+	if maxAttempts <= 0 {
+		maxAttempts = 3
+	}
+	if base <= 0 {
+		base = 100 * time.Millisecond
+	}
+	if maxDelay <= 0 {
+		maxDelay = 30 * time.Second
+	}
+	if problem == nil || attempt >= maxAttempts || !p.retries(problem) {
+		return 0, false
+	}
+	if d, ok := retryAfter(problem); ok {
+		return d, d <= maxDelay
+	}
+	d := time.Duration(math.Min(float64(base)*math.Pow(2, float64(attempt-1)), float64(maxDelay)))
+	if j := math.Max(0, math.Min(p.Jitter, 1)); j > 0 {
+		d = time.Duration(float64(d) * (1 - j*rand.Float64()))
+	}
+	return d, true
+}
+
+// retries returns true if the status or the type of problem is retried.
+func (p RetryPolicy) retries(problem *middleware.Rfc7807Response) bool {
+	for _, t := range p.Types {
+		if problem.Type == t {
+			return true
+		}
+	}
+	statuses := p.Statuses
+	if statuses == nil {
+		statuses = DefaultRetryStatuses
+	}
+	for _, s := range statuses {
+		if problem.Status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfter returns the delay of the retry_after meta value of problem, a number of seconds
+// decoded from JSON or XML.
+func retryAfter(problem *middleware.Rfc7807Response) (time.Duration, bool) {
+	var secs float64
+	switch v := problem.Meta[middleware.RetryAfterMetaKey].(type) {
+	case int:
+		secs = float64(v)
+	case int64:
+		secs = float64(v)
+	case float64:
+		secs = v
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return 0, false
+		}
+		secs = f
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, false
+		}
+		secs = f
+	default:
+		return 0, false
+	}
+	if secs < 0 {
+		secs = 0
+	}
+	return time.Duration(secs * float64(time.Second)), true
+}
@@ -0,0 +1,259 @@
+// Command goansgen generates Go code from a catalog of problem types so that error definitions
+// are single-sourced. It reads a YAML or JSON catalog such as:
+//
+//	package: apierrors
+//	base_uri: https://example.com/probs/
+//	problems:
+//	  - name: OrderNotFound
+//	    code: order_not_found
+//	    status: 404
+//	    type: order-not-found
+//	    title: Order not found
+//	    description: The order does not exist or was deleted.
+//
+// and writes a Go file with one constant per problem type URI, one constructor per problem type,
+// e.g. ErrOrderNotFound(detail string) error, and a RegisterProblemTypes function registering the
+// problem types by code in a middleware.ProblemTypeRegistry, as well as a Markdown documentation
+// stub listing the problem types. The name defaults to the camel-cased code and the type to the
+// code with dashes, relative types are resolved against the base URI when it is set and versioned
+// types get the versioned URI of middleware.WithVersion. Typical use is with go:generate:
+//
+//	//go:generate go run github.com/blueoceans/goans/cmd/goansgen -catalog problems.yaml
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+type (
+	// catalog is a catalog of problem types.
+	catalog struct {
+		// Package is the name of the package of the generated code.
+		Package string `json:"package" yaml:"package"`
+		// BaseURI is the URI relative problem types are resolved against.
+		BaseURI string `json:"base_uri" yaml:"base_uri"`
+		// Problems lists the problem types.
+		Problems []problem `json:"problems" yaml:"problems"`
+	}
+
+	// problem is a problem type of a catalog.
+	problem struct {
+		// Name is the name of the problem type in the generated identifiers.
+		Name string `json:"name" yaml:"name"`
+		// Code is the error code the problem type is registered with and the code of the
+		// problems.
+		Code string `json:"code" yaml:"code"`
+		// Status is the HTTP status of the problems.
+		Status int `json:"status" yaml:"status"`
+		// Type is the URI reference identifying the problem type.
+		Type string `json:"type" yaml:"type"`
+		// Title is the title of the problems, the status text if empty.
+		Title string `json:"title" yaml:"title"`
+		// Description explains the problem type and how to resolve it.
+		Description string `json:"description" yaml:"description"`
+		// Version is the version of the problem type, see middleware.WithVersion.
+		Version int `json:"version" yaml:"version"`
+		// URI is the URI of the problem type without the version.
+		URI string `json:"-" yaml:"-"`
+	}
+)
+
+// codeTemplate is the template of the generated Go code.
+var codeTemplate = template.Must(template.New("code").Parse(`// Code generated by goansgen from {{.Source}}. DO NOT EDIT.
+
+package {{.Package}}
+
+import "github.com/blueoceans/goans/middleware"
+
+// The URIs of the problem types of the catalog.
+const (
+{{- range .Problems}}
+	// {{.Name}}Type is the URI of the {{.Code}} problem type.
+	{{.Name}}Type = {{printf "%q" .Type}}
+{{- end}}
+)
+
+{{range .Problems}}
+// Err{{.Name}} returns a {{.Status}} {{printf "%q" .Title}} problem with the given detail.
+{{- if .Description}}
+//
+// {{.Description}}
+{{- end}}
+func Err{{.Name}}(detail string) error {
+	return middleware.NewProblem({{.Status}}).
+		Type({{.Name}}Type).
+		Title({{printf "%q" .Title}}).
+		Code({{printf "%q" .Code}}).
+		Detail(detail).
+		Err()
+}
+{{end}}
+// RegisterProblemTypes registers the problem types of the catalog by code in r, or in
+// middleware.ProblemTypes if r is nil, so that the goa errors with these codes are sent with the
+// problem types.
+func RegisterProblemTypes(r *middleware.ProblemTypeRegistry) {
+	if r == nil {
+		r = middleware.ProblemTypes
+	}
+{{- range .Problems}}
+	r.Register({{printf "%q" .Code}}, middleware.ProblemType{
+		URI:         {{printf "%q" .URI}},
+		Title:       {{printf "%q" .Title}},
+		Status:      {{.Status}},
+		Description: {{printf "%q" .Description}},
+		{{- if .Version}}
+		Version:     {{.Version}},
+		{{- end}}
+	})
+{{- end}}
+}
+`))
+
+// docTemplate is the template of the generated documentation stub.
+var docTemplate = template.Must(template.New("doc").Parse(`# Problem types
+
+<!-- Generated by goansgen from {{.Source}}, edit the catalog instead. -->
+{{range .Problems}}
+## {{.Title}}
+
+- Type: ` + "`{{.Type}}`" + `
+- Code: ` + "`{{.Code}}`" + `
+- Status: {{.Status}}
+
+{{if .Description}}{{.Description}}{{else}}TODO: explain the problem and how to resolve it.{{end}}
+{{end}}`))
+
+func main() {
+	var (
+		source = flag.String("catalog", "problems.yaml", "path of the YAML or JSON `catalog` of problem types")
+		out    = flag.String("o", "problems_gen.go", "path of the generated Go `file`")
+		doc    = flag.String("doc", "problem_types.md", "path of the generated Markdown `file`, none if empty")
+	)
+	flag.Parse()
+	if err := run(*source, *out, *doc); err != nil {
+		fmt.Fprintf(os.Stderr, "goansgen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// run generates the code and documentation of the catalog at path source.
+func run(source, out, doc string) error {
+	c, err := loadCatalog(source)
+	if err != nil {
+		return err
+	}
+	data := struct {
+		*catalog
+		Source string
+	}{c, filepath.Base(source)}
+	var buf bytes.Buffer
+	if err := codeTemplate.Execute(&buf, data); err != nil {
+		return err
+	}
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("invalid generated code: %s", err)
+	}
+	if err := ioutil.WriteFile(out, code, 0644); err != nil {
+		return err
+	}
+	if doc == "" {
+		return nil
+	}
+	buf.Reset()
+	if err := docTemplate.Execute(&buf, data); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(doc, buf.Bytes(), 0644)
+}
+
+// loadCatalog reads, validates and completes the catalog at path, files with the ".json"
+// extension are decoded as JSON and the others as YAML.
+func loadCatalog(path string) (*catalog, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c catalog
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(b, &c)
+	} else {
+		err = yaml.Unmarshal(b, &c)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid catalog %s: %s", path, err)
+	}
+	if c.Package == "" {
+		return nil, fmt.Errorf("invalid catalog %s: missing package", path)
+	}
+	var base *url.URL
+	if c.BaseURI != "" {
+		if base, err = url.Parse(c.BaseURI); err != nil {
+			return nil, fmt.Errorf("invalid catalog %s: invalid base URI: %s", path, err)
+		}
+	}
+	seen := make(map[string]bool, len(c.Problems))
+	for i := range c.Problems {
+		p := &c.Problems[i]
+		if p.Code == "" {
+			return nil, fmt.Errorf("invalid catalog %s: problem %d has no code", path, i)
+		}
+		if p.Name == "" {
+			p.Name = camelCase(p.Code)
+		}
+		if !token.IsIdentifier(p.Name) || !token.IsExported(p.Name) {
+			return nil, fmt.Errorf("invalid catalog %s: problem %q has invalid name %q", path, p.Code, p.Name)
+		}
+		if seen[p.Name] || seen[p.Code] {
+			return nil, fmt.Errorf("invalid catalog %s: duplicate problem %q", path, p.Code)
+		}
+		seen[p.Name], seen[p.Code] = true, true
+		if p.Status < 400 || p.Status > 599 {
+			return nil, fmt.Errorf("invalid catalog %s: problem %q has invalid status %d, must be a 4xx or 5xx status", path, p.Code, p.Status)
+		}
+		if p.Type == "" {
+			p.Type = strings.Replace(p.Code, "_", "-", -1)
+		}
+		ref, err := url.Parse(p.Type)
+		if err != nil {
+			return nil, fmt.Errorf("invalid catalog %s: problem %q has invalid type: %s", path, p.Code, err)
+		}
+		if base != nil {
+			p.Type = base.ResolveReference(ref).String()
+		}
+		p.URI = p.Type
+		p.Type = (middleware.ProblemType{URI: p.URI, Version: p.Version}).VersionedURI()
+		if p.Title == "" {
+			p.Title = http.StatusText(p.Status)
+		}
+		p.Description = strings.Join(strings.Fields(p.Description), " ")
+	}
+	return &c, nil
+}
+
+// camelCase returns the exported camel-cased form of the snake-cased, kebab-cased or dotted code.
+func camelCase(code string) string {
+	parts := strings.FieldsFunc(code, func(r rune) bool {
+		return r == '_' || r == '-' || r == '.' || r == ' '
+	})
+	for i, p := range parts {
+		parts[i] = strings.ToUpper(p[:1]) + p[1:]
+	}
+	return strings.Join(parts, "")
+}
@@ -0,0 +1,110 @@
+// Package design provides goa v1 design definitions describing the RFC 7807 problems sent by the
+// middleware so that the generated Swagger specification and controllers declare the exact schema
+// of the error responses, for example:
+//
+//	var _ = Resource("account", func() {
+//		Action("show", func() {
+//			Routing(GET("/:id"))
+//			Response(OK, AccountMedia)
+//			problem.ProblemResponse(404, "https://example.com/probs/no-such-account")
+//			problem.ProblemResponse(403, "https://example.com/probs/out-of-credit")
+//		})
+//	})
+//
+// where problem is the import name of this package.
+package design
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/blueoceans/goans/middleware"
+	. "github.com/goadesign/goa/design"
+	. "github.com/goadesign/goa/design/apidsl"
+)
+
+// ProblemTypeExtension is the Swagger extension of problem responses containing their problem
+// type.
+const ProblemTypeExtension = "x-problem-type"
+
+// ProblemMedia is the media type of the problems serialized by the middleware.
+var ProblemMedia = MediaType(middleware.Rfc7807JsonMediaIdentifier, func() {
+	Description("Problem details for HTTP APIs (RFC 7807)")
+	TypeName("Problem")
+	ContentType(middleware.Rfc7807JsonMediaIdentifier)
+	Attributes(func() {
+		Attribute("type", String, "URI reference that identifies the problem type", func() {
+			Example("https://example.com/probs/out-of-credit")
+		})
+		Attribute("title", String, "Short, human-readable summary of the problem type", func() {
+			Example("You do not have enough credit.")
+		})
+		Attribute("status", Integer, "HTTP status code", func() {
+			Minimum(400)
+			Maximum(599)
+			Example(403)
+		})
+		Attribute("detail", String, "Human-readable explanation specific to this occurrence of the problem", func() {
+			Example("Your current balance is 30, but that costs 50.")
+		})
+		Attribute("instance", String, "URI reference that identifies the specific occurrence of the problem", func() {
+			Example("/account/12345/msgs/abc")
+		})
+		Attribute("trace_id", String, "Unique error instance identifier", func() {
+			Example("kBEi7Xej")
+		})
+		Attribute("code", String, "Stable machine-readable code of the error", func() {
+			Example("invalid_request")
+		})
+		Attribute("meta", HashOf(String, Any), "Additional key/value pairs useful to clients")
+		Required("type", "title", "status", "detail", "instance", "trace_id")
+	})
+	View("default", func() {
+		Attribute("type")
+		Attribute("title")
+		Attribute("status")
+		Attribute("detail")
+		Attribute("instance")
+		Attribute("trace_id")
+		Attribute("code")
+		Attribute("meta")
+	})
+})
+
+// ProblemResponse defines the response of the enclosing action or resource with the given status
+// and ProblemMedia as media type. The response is named after the standard goa response with the
+// same status, e.g. NotFound for 404, or "Problem" followed by the status otherwise. typ is the
+// problem type of the response, it is added to the description and to the Swagger definition
+// with the ProblemTypeExtension extension, it may be empty.
+func ProblemResponse(status int, typ string) {
+	Response(responseName(status), ProblemMedia, func() {
+		Status(status)
+		description := http.StatusText(status)
+		if description == "" {
+			description = "Problem " + strconv.Itoa(status)
+		}
+		if typ != "" {
+			description += " (" + typ + ")"
+			Metadata("swagger:extension:"+ProblemTypeExtension, typ)
+		}
+		Description(description)
+	})
+}
+
+// ProblemResponses defines a response without problem type for each status, see ProblemResponse.
+func ProblemResponses(statuses ...int) {
+	for _, s := range statuses {
+		ProblemResponse(s, "")
+	}
+}
+
+// responseName returns the name of the goa default response with status, or "Problem" followed by
+// the status.
+func responseName(status int) string {
+	for name, r := range Design.DefaultResponses {
+		if r.Status == status {
+			return name
+		}
+	}
+	return "Problem" + strconv.Itoa(status)
+}
@@ -0,0 +1,28 @@
+// Package echo sends the errors of Echo (github.com/labstack/echo/v4) applications as RFC 7807
+// problems with the problem handler of package middleware.
+package echo
+
+import (
+	"errors"
+	"fmt"
+
+	labstack "github.com/labstack/echo/v4"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+// HTTPErrorHandler returns an Echo error handler that sends errors as problems with p, set it as
+// the HTTPErrorHandler of the Echo instance. *echo.HTTPError errors, such as the 404 and 405
+// errors of the Echo router, are sent with their status and message as detail.
+func HTTPErrorHandler(p *middleware.ProblemHandler) labstack.HTTPErrorHandler {
+	return func(err error, c labstack.Context) {
+		if c.Response().Committed {
+			return
+		}
+		var he *labstack.HTTPError
+		if errors.As(err, &he) {
+			err = middleware.NewProblem(he.Code).Detail(fmt.Sprint(he.Message)).Err()
+		}
+		p.WriteProblem(c.Response(), c.Request(), err)
+	}
+}
@@ -0,0 +1,23 @@
+// Package gin sends the errors of Gin (github.com/gin-gonic/gin) applications as RFC 7807 problems
+// with the problem handler of package middleware.
+package gin
+
+import (
+	gingonic "github.com/gin-gonic/gin"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+// ErrorHandler returns a Gin middleware that sends the last error added to the context of
+// downstream handlers with c.Error as a problem with p. Handlers must not write a response when
+// they fail, and so must not use c.AbortWithError which writes the status, for the problem to be
+// sent.
+func ErrorHandler(p *middleware.ProblemHandler) gingonic.HandlerFunc {
+	return func(c *gingonic.Context) {
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		p.WriteProblem(c.Writer, c.Request, c.Errors.Last().Err)
+	}
+}
@@ -0,0 +1,89 @@
+package goanstest
+
+import (
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/blueoceans/goans/client"
+	"github.com/blueoceans/goans/middleware"
+)
+
+// ProblemRecorder is a httptest.ResponseRecorder that decodes the recorded problem.
+type ProblemRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+// NewProblemRecorder returns an initialized recorder.
+func NewProblemRecorder() *ProblemRecorder {
+	return &ProblemRecorder{ResponseRecorder: httptest.NewRecorder()}
+}
+
+// Problem decodes the recorded JSON or XML problem like client.ParseProblem, the +json and +xml
+// vendor media types of handlers configured with middleware.WithVendorMediaType included. It
+// returns an error if the recorded response is not an error response.
+func (r *ProblemRecorder) Problem() (*middleware.Rfc7807Response, error) {
+	resp := r.Result()
+	defer resp.Body.Close()
+	return client.ParseProblem(resp, vendorMediaTypes(resp)...)
+}
+
+// AssertProblem reports a fatal error to t unless resp is a problem response with the given status
+// and type, wantType is not checked if empty. The +json and +xml vendor media types of handlers
+// configured with middleware.WithVendorMediaType are problem media types. It returns the decoded problem so that tests can
+// check its other members, for example:
+//
+//	problem := goanstest.AssertProblem(t, resp, http.StatusNotFound, "https://example.com/probs/not-found")
+//	if problem.Detail != "no such user" {
+//		t.Errorf("got detail %q", problem.Detail)
+//	}
+//
+// The caller is responsible for closing the response body.
+func AssertProblem(t testing.TB, resp *http.Response, wantStatus int, wantType string) *middleware.Rfc7807Response {
+	t.Helper()
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("got status %d, want %d", resp.StatusCode, wantStatus)
+	}
+	ct := resp.Header.Get("Content-Type")
+	if mt, _, err := mime.ParseMediaType(ct); err != nil || !isProblemMediaType(mt) {
+		t.Fatalf("got content type %q, want a problem media type", ct)
+	}
+	problem, err := client.ParseProblem(resp, vendorMediaTypes(resp)...)
+	if err != nil {
+		t.Fatalf("invalid problem: %s", err)
+	}
+	if problem.Status != wantStatus {
+		t.Fatalf("got problem status %d, want %d", problem.Status, wantStatus)
+	}
+	if wantType != "" && problem.Type != wantType {
+		t.Fatalf("got problem type %q, want %q", problem.Type, wantType)
+	}
+	return problem
+}
+
+// AssertRecordedProblem is AssertProblem for the response recorded by rec.
+func AssertRecordedProblem(t testing.TB, rec *httptest.ResponseRecorder, wantStatus int, wantType string) *middleware.Rfc7807Response {
+	t.Helper()
+	resp := rec.Result()
+	defer resp.Body.Close()
+	return AssertProblem(t, resp, wantStatus, wantType)
+}
+
+// isProblemMediaType returns true if the lowercase media type mt is a problem media type or a
+// +json or +xml vendor media type.
+func isProblemMediaType(mt string) bool {
+	return mt == middleware.Rfc7807JsonMediaIdentifier || mt == middleware.Rfc7807XmlMediaIdentifier ||
+		strings.HasSuffix(mt, "+json") || strings.HasSuffix(mt, "+xml")
+}
+
+// vendorMediaTypes returns the options decoding the problems of resp if its media type is a
+// +json or +xml vendor media type.
+func vendorMediaTypes(resp *http.Response) []client.Option {
+	mt, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !isProblemMediaType(mt) {
+		return nil
+	}
+	return []client.Option{client.WithVendorMediaTypes(mt)}
+}
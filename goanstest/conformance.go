@@ -0,0 +1,286 @@
+package goanstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+// Conformance rules reported in violations.
+const (
+	// RuleTransport is reported when the request of an endpoint cannot be sent.
+	RuleTransport = "transport"
+	// RuleStatus is reported for responses that are not 4xx or 5xx responses or whose status is
+	// not the one expected for the endpoint.
+	RuleStatus = "status"
+	// RuleMediaType is reported for responses whose Content-Type is not a problem media type or
+	// a +json or +xml vendor media type.
+	RuleMediaType = "media-type"
+	// RuleSyntax is reported for bodies that are not a JSON object or an XML problem element.
+	RuleSyntax = "syntax"
+	// RuleMemberType is reported for standard members of the wrong type.
+	RuleMemberType = "member-type"
+	// RuleStatusMember is reported when the status member does not match the response status.
+	RuleStatusMember = "status-member"
+	// RuleURI is reported when the type or instance member is not a valid URI reference.
+	RuleURI = "uri"
+	// RuleExtension is reported for extension members whose name is not recommended by RFC 9457,
+	// i.e. does not start with a letter, contains characters other than letters, digits and
+	// underscores or is shorter than three characters.
+	RuleExtension = "extension"
+)
+
+// extensionNameRegexp matches the extension member names recommended by RFC 9457 section 3.2.
+var extensionNameRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{2,}$`)
+
+type (
+	// Endpoint is an error endpoint checked by CheckConformance.
+	Endpoint struct {
+		// Name identifies the endpoint in violations, it defaults to the method and path.
+		Name string
+		// Method is the request method, GET when empty.
+		Method string
+		// Path is the request path and query relative to the base URL.
+		Path string
+		// Header contains the request headers, e.g. Accept to check the XML representation.
+		Header http.Header
+		// Body is the request body.
+		Body string
+		// Status is the expected response status, any 4xx or 5xx status when zero.
+		Status int
+	}
+
+	// Violation is a conformance rule broken by the response of an endpoint.
+	Violation struct {
+		// Endpoint is the name of the endpoint.
+		Endpoint string
+		// Rule is the broken rule, one of the Rule constants.
+		Rule string
+		// Message describes the violation.
+		Message string
+	}
+
+	// xmlMember is an element of an XML problem.
+	xmlMember struct {
+		XMLName xml.Name
+		Value   string `xml:",innerxml"`
+	}
+
+	// xmlProblem is an XML problem document.
+	xmlProblem struct {
+		XMLName xml.Name
+		Members []xmlMember `xml:",any"`
+	}
+)
+
+// Conformance runs CheckConformance as a subtest of t per endpoint and reports the violations as
+// errors, for example:
+//
+//	srv := goanstest.NewTestServer(handler)
+//	defer srv.Close()
+//	goanstest.Conformance(t, srv.Client(), srv.URL,
+//		goanstest.Endpoint{Path: "/users/unknown", Status: http.StatusNotFound},
+//		goanstest.Endpoint{Path: "/users/unknown", Header: http.Header{"Accept": {"application/xml"}}},
+//	)
+func Conformance(t *testing.T, c *http.Client, baseURL string, endpoints ...Endpoint) {
+	t.Helper()
+	for _, e := range endpoints {
+		e := e
+		t.Run(endpointName(e), func(t *testing.T) {
+			for _, v := range CheckConformance(c, baseURL, e) {
+				t.Errorf("%s: %s", v.Rule, v.Message)
+			}
+		})
+	}
+}
+
+// CheckConformance sends the requests of endpoints to the service at baseURL with c, or
+// http.DefaultClient if nil, and returns the violations of the RFC 7807 and RFC 9457 rules by
+// their responses, see CheckResponse.
+func CheckConformance(c *http.Client, baseURL string, endpoints ...Endpoint) []Violation {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	var violations []Violation
+	for _, e := range endpoints {
+		name := endpointName(e)
+		method := e.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+		req, err := http.NewRequest(method, strings.TrimSuffix(baseURL, "/")+e.Path, strings.NewReader(e.Body))
+		if err != nil {
+			violations = append(violations, Violation{Endpoint: name, Rule: RuleTransport, Message: err.Error()})
+			continue
+		}
+		for k, vs := range e.Header {
+			req.Header[k] = vs
+		}
+		resp, err := c.Do(req)
+		if err != nil {
+			violations = append(violations, Violation{Endpoint: name, Rule: RuleTransport, Message: err.Error()})
+			continue
+		}
+		violations = append(violations, checkResponse(name, resp, e.Status)...)
+		resp.Body.Close()
+	}
+	return violations
+}
+
+// CheckResponse returns the violations of the RFC 7807 and RFC 9457 rules by resp, the response
+// of the endpoint with the given name: the status must be a 4xx or 5xx status, the media type a
+// problem media type or a +json or +xml vendor media type, the body a JSON object or an XML
+// problem element in the RFC 7807 namespace whose standard members have the right types, whose
+// status member matches the response status, whose type and instance are valid URI references
+// and whose extension members have the names recommended by RFC 9457. The body is read but not
+// closed.
+func CheckResponse(name string, resp *http.Response) []Violation {
+	return checkResponse(name, resp, 0)
+}
+
+// checkResponse is CheckResponse checking that the status is want if not zero.
+func checkResponse(name string, resp *http.Response, want int) []Violation {
+	var violations []Violation
+	report := func(rule, format string, args ...interface{}) {
+		violations = append(violations, Violation{Endpoint: name, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+	switch {
+	case want != 0 && resp.StatusCode != want:
+		report(RuleStatus, "got status %d, want %d", resp.StatusCode, want)
+	case resp.StatusCode < 400 || resp.StatusCode > 599:
+		report(RuleStatus, "got status %d, want a 4xx or 5xx status", resp.StatusCode)
+	}
+	ct := resp.Header.Get("Content-Type")
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		report(RuleMediaType, "invalid content type %q", ct)
+		return violations
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		report(RuleTransport, "reading body: %s", err)
+		return violations
+	}
+	var members map[string]interface{}
+	switch {
+	case mt == middleware.Rfc7807JsonMediaIdentifier || strings.HasSuffix(mt, "+json"):
+		d := json.NewDecoder(bytes.NewReader(body))
+		d.UseNumber()
+		if err := d.Decode(&members); err != nil {
+			report(RuleSyntax, "body is not a JSON object: %s", err)
+			return violations
+		}
+	case mt == middleware.Rfc7807XmlMediaIdentifier || strings.HasSuffix(mt, "+xml"):
+		var p xmlProblem
+		if err := xml.Unmarshal(body, &p); err != nil {
+			report(RuleSyntax, "body is not an XML document: %s", err)
+			return violations
+		}
+		if p.XMLName.Local != "problem" || p.XMLName.Space != middleware.Rfc7807XmlNamespace {
+			report(RuleSyntax, "got root element {%s}%s, want {%s}problem", p.XMLName.Space, p.XMLName.Local, middleware.Rfc7807XmlNamespace)
+		}
+		members = make(map[string]interface{}, len(p.Members))
+		for _, m := range p.Members {
+			v := interface{}(m.Value)
+			if m.XMLName.Local == "status" {
+				v = json.Number(strings.TrimSpace(m.Value))
+			}
+			members[m.XMLName.Local] = v
+		}
+	default:
+		report(RuleMediaType, "got content type %q, want a problem media type", ct)
+		return violations
+	}
+	for _, k := range []string{"type", "title", "detail", "instance"} {
+		if v, ok := members[k]; ok {
+			if _, ok := v.(string); !ok {
+				report(RuleMemberType, "member %q is not a string", k)
+			}
+		}
+	}
+	if v, ok := members["status"]; ok {
+		n, isNumber := v.(json.Number)
+		status, err := strconv.Atoi(string(n))
+		switch {
+		case !isNumber || err != nil:
+			report(RuleMemberType, "member \"status\" is not an integer")
+		case status != resp.StatusCode:
+			report(RuleStatusMember, "member \"status\" is %d, the response status is %d", status, resp.StatusCode)
+		}
+	}
+	for _, k := range []string{"type", "instance"} {
+		if s, ok := members[k].(string); ok {
+			if err := checkURIReference(s); err != nil {
+				report(RuleURI, "member %q is not a URI reference: %s", k, err)
+			}
+		}
+	}
+	for k := range members {
+		switch k {
+		case "type", "title", "status", "detail", "instance":
+			continue
+		}
+		if !extensionNameRegexp.MatchString(k) {
+			report(RuleExtension, "extension member name %q is not recommended", k)
+		}
+	}
+	return violations
+}
+
+// uriChars are the characters allowed in URI references by RFC 3986 besides letters, digits and
+// percent-encoded octets.
+const uriChars = "-._~:/?#[]@!$&'()*+,;="
+
+// checkURIReference returns an error if s is not a URI reference as defined by RFC 3986: it may
+// only contain unreserved and reserved characters and percent-encoded octets, raw spaces and
+// control characters are not allowed.
+func checkURIReference(s string) error {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', strings.IndexByte(uriChars, c) >= 0:
+		case c == '%':
+			if i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+				return fmt.Errorf("invalid percent-encoding at offset %d", i)
+			}
+			i += 2
+		default:
+			return fmt.Errorf("invalid character %q at offset %d", c, i)
+		}
+	}
+	_, err := url.Parse(s)
+	return err
+}
+
+// isHex returns true if c is a hexadecimal digit.
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// endpointName returns the name of e.
+func endpointName(e Endpoint) string {
+	if e.Name != "" {
+		return e.Name
+	}
+	method := e.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	return method + " " + e.Path
+}
+
+// String returns the violation as "endpoint: rule: message".
+func (v Violation) String() string {
+	return v.Endpoint + ": " + v.Rule + ": " + v.Message
+}
@@ -0,0 +1,141 @@
+// Package goanstest provides helpers for testing services that use the goans middleware.
+package goanstest
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/goadesign/goa"
+
+	"github.com/blueoceans/goans/client"
+	"github.com/blueoceans/goans/middleware"
+)
+
+// methods lists the HTTP methods routed to the handler by NewTestServer.
+var methods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodOptions,
+}
+
+// NewTestServer starts and returns a server that handles all requests by calling handler wrapped
+// by the Rfc7807Handler middleware configured with opts, internal error details are masked unless
+// opts say otherwise. The Client method of the server returns a client configured to send requests
+// to it. The caller must call Close when done to shut the server down.
+func NewTestServer(handler goa.Handler, opts ...middleware.Option) *httptest.Server {
+	service := goa.New("goanstest")
+	service.WithLogger(goa.NewLogger(log.New(ioutil.Discard, "", 0)))
+	service.Encoder.Register(goa.NewJSONEncoder, "application/json", "*/*")
+	service.Use(middleware.Rfc7807Handler(service, false, opts...))
+	ctrl := service.NewController("goanstest")
+	h := ctrl.MuxHandler("handle", handler, nil)
+	for _, m := range methods {
+		service.Mux.Handle(m, "/", h)
+		service.Mux.Handle(m, "/*path", h)
+	}
+	return httptest.NewServer(service.Mux)
+}
+
+// Server is a test server running a minimal goa service with the Rfc7807Handler middleware
+// mounted, tests register the handlers of the routes they exercise and get the decoded problems
+// returned for their requests, for example:
+//
+//	srv := goanstest.NewServer(middleware.WithVerbose(true))
+//	defer srv.Close()
+//	srv.Fail(http.MethodGet, "/users/1", goa.ErrNotFound("no such user"))
+//	problem, resp, err := srv.Problem(http.MethodGet, "/users/1")
+//
+// Requests to routes without handler fail with a 404 problem.
+type Server struct {
+	*httptest.Server
+	mu       sync.RWMutex
+	handlers map[string]goa.Handler
+}
+
+// NewServer starts and returns a server whose middleware is configured with opts, see
+// NewTestServer. The caller must call Close when done to shut the server down.
+func NewServer(opts ...middleware.Option) *Server {
+	s := &Server{handlers: make(map[string]goa.Handler)}
+	s.Server = NewTestServer(s.dispatch, opts...)
+	return s
+}
+
+// Handle registers h as the handler of the requests with the given method and path. The path is
+// matched exactly, without the query string.
+func (s *Server) Handle(method, path string, h goa.Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[routeKey(method, path)] = h
+}
+
+// Fail registers a handler that returns err for the requests with the given method and path.
+func (s *Server) Fail(method, path string, err error) {
+	s.Handle(method, path, func(context.Context, http.ResponseWriter, *http.Request) error {
+		return err
+	})
+}
+
+// Problem sends a request with the given method and path to the server and returns the decoded
+// problem and the response, whose body is closed. It returns an error if the request fails or if
+// the response is not an error response.
+func (s *Server) Problem(method, path string) (*middleware.Rfc7807Response, *http.Response, error) {
+	req, err := http.NewRequest(method, s.URL+path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.Do(req)
+}
+
+// Do sends req to the server and returns the decoded problem and the response, see Problem. The
+// scheme and host of req are replaced with those of the server so that tests may build requests
+// with relative URLs using httptest.NewRequest.
+func (s *Server) Do(req *http.Request) (*middleware.Rfc7807Response, *http.Response, error) {
+	u := *req.URL
+	if err := s.rewriteURL(&u); err != nil {
+		return nil, nil, err
+	}
+	out := req.Clone(req.Context())
+	out.URL, out.Host, out.RequestURI = &u, u.Host, ""
+	resp, err := s.Client().Do(out)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	problem, err := client.ParseProblem(resp)
+	if err != nil {
+		return nil, resp, err
+	}
+	return problem, resp, nil
+}
+
+// dispatch calls the handler registered for req.
+func (s *Server) dispatch(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+	s.mu.RLock()
+	h, ok := s.handlers[routeKey(req.Method, req.URL.Path)]
+	s.mu.RUnlock()
+	if !ok {
+		return goa.ErrNotFound(fmt.Sprintf("no handler registered for %s %s", req.Method, req.URL.Path))
+	}
+	return h(ctx, rw, req)
+}
+
+// rewriteURL sets the scheme and host of u to those of the server.
+func (s *Server) rewriteURL(u *url.URL) error {
+	base, err := url.Parse(s.URL)
+	if err != nil {
+		return err
+	}
+	u.Scheme, u.Host = base.Scheme, base.Host
+	return nil
+}
+
+// routeKey returns the key of the handler of the requests with the given method and path.
+func routeKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
@@ -0,0 +1,254 @@
+// Package grpc maps gRPC (google.golang.org/grpc) statuses to RFC 7807 problems and back so that
+// services exposing both HTTP and gRPC APIs, or calling gRPC backends, report errors consistently.
+package grpc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/goadesign/goa"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+// ErrorInfoDomain is the domain of the google.rpc.ErrorInfo details of the statuses rendered from
+// problems.
+const ErrorInfoDomain = "goans"
+
+// statusCodes maps HTTP statuses to gRPC codes, see Code.
+var statusCodes = map[int]codes.Code{
+	http.StatusBadRequest:                   codes.InvalidArgument,
+	http.StatusUnauthorized:                 codes.Unauthenticated,
+	http.StatusForbidden:                    codes.PermissionDenied,
+	http.StatusNotFound:                     codes.NotFound,
+	http.StatusConflict:                     codes.AlreadyExists,
+	http.StatusPreconditionFailed:           codes.FailedPrecondition,
+	http.StatusRequestedRangeNotSatisfiable: codes.OutOfRange,
+	http.StatusTooManyRequests:              codes.ResourceExhausted,
+	499:                                     codes.Canceled,
+	http.StatusNotImplemented:               codes.Unimplemented,
+	http.StatusServiceUnavailable:           codes.Unavailable,
+	http.StatusGatewayTimeout:               codes.DeadlineExceeded,
+}
+
+// httpStatuses maps gRPC codes to HTTP statuses, see HTTPStatus.
+var httpStatuses = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           499,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+// HTTPStatus returns the HTTP status corresponding to the gRPC code c as defined by the gRPC
+// gateway mapping, unknown codes map to 500.
+func HTTPStatus(c codes.Code) int {
+	if s, ok := httpStatuses[c]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// Code returns the gRPC code corresponding to the HTTP status s. Unlisted 4xx statuses map to
+// FailedPrecondition and unlisted 5xx statuses to Internal.
+func Code(s int) codes.Code {
+	if c, ok := statusCodes[s]; ok {
+		return c
+	}
+	if s >= 400 && s < 500 {
+		return codes.FailedPrecondition
+	}
+	if s >= 500 {
+		return codes.Internal
+	}
+	return codes.Unknown
+}
+
+// ErrorMapper is a middleware.ErrorMapper that converts the gRPC status errors returned by
+// backend calls into problems, register it with middleware.RegisterErrorMapper or
+// middleware.WithErrorMappers. The status message is the detail of the problem and the details
+// added by Status are restored: the type, title, trace ID and code of google.rpc.ErrorInfo
+// details, the field violations of google.rpc.BadRequest details as validation errors and the
+// retry delay of google.rpc.RetryInfo details.
+func ErrorMapper(_ context.Context, err error) (*middleware.Rfc7807Response, bool) {
+	var se interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &se) {
+		return nil, false
+	}
+	st := se.GRPCStatus()
+	if st.Code() == codes.OK {
+		return nil, false
+	}
+	problem := &middleware.Rfc7807Response{
+		Status: HTTPStatus(st.Code()),
+		Detail: st.Message(),
+	}
+	for _, d := range st.Details() {
+		switch actual := d.(type) {
+		case *errdetails.ErrorInfo:
+			problem.Type = actual.Metadata["type"]
+			problem.Title = actual.Metadata["title"]
+			problem.Instance = actual.Metadata["instance"]
+			problem.TraceID = actual.Metadata["trace_id"]
+			if actual.Reason != problem.Type {
+				problem.Code = actual.Reason
+			}
+		case *errdetails.BadRequest:
+			errs := make([]middleware.FieldError, len(actual.FieldViolations))
+			for i, v := range actual.FieldViolations {
+				errs[i] = middleware.FieldError{Field: v.Field, Code: v.Reason, Message: v.Description}
+			}
+			setMeta(problem, middleware.ValidationErrorsMetaKey, errs)
+		case *errdetails.RetryInfo:
+			setMeta(problem, middleware.RetryAfterMetaKey, actual.RetryDelay.AsDuration())
+		}
+	}
+	return problem, true
+}
+
+// Status renders err as a gRPC status. Problems and goa.ServiceError errors get the gRPC code
+// corresponding to their status, their detail as message and a google.rpc.ErrorInfo detail with
+// their error code as reason and their type, title, instance and trace ID as metadata, validation
+// errors and retry delays are added as google.rpc.BadRequest and google.rpc.RetryInfo details.
+// The messages of other errors are only included if verbose is true, they are rendered as
+// Internal statuses otherwise. Problems are masked like the ones of a handler configured with
+// opts, see middleware.MaskProblem: unless verbose is true internal errors get a generic message
+// with their trace ID and only their public meta values are rendered. Errors that are already
+// gRPC status errors are returned as is.
+func Status(err error, verbose bool, opts ...middleware.Option) *status.Status {
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+	var problem *middleware.Rfc7807Response
+	var se goa.ServiceError
+	switch {
+	case errors.As(err, &problem):
+	case errors.As(err, &se):
+		problem = &middleware.Rfc7807Response{Status: se.ResponseStatus(), Detail: se.Error(), TraceID: se.Token(), Code: se.Token()}
+		if resp, ok := se.(*goa.ErrorResponse); ok {
+			problem.Detail = resp.Detail
+			problem.Code = resp.Code
+			problem.Meta = resp.Meta
+		}
+	default:
+		msg := http.StatusText(http.StatusInternalServerError)
+		if verbose {
+			msg = err.Error()
+		}
+		return status.New(codes.Internal, msg)
+	}
+	problem = middleware.MaskProblem(problem, verbose, opts...)
+	st := status.New(Code(problem.Status), problem.Detail)
+	details := []protoadapt.MessageV1{errorInfo(problem)}
+	if errs, ok := problem.Meta[middleware.ValidationErrorsMetaKey].([]middleware.FieldError); ok {
+		br := &errdetails.BadRequest{}
+		for _, e := range errs {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field: e.Field, Reason: e.Code, Description: e.Message,
+			})
+		}
+		details = append(details, br)
+	}
+	if d, ok := retryDelay(problem.Meta[middleware.RetryAfterMetaKey]); ok {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(d)})
+	}
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		return withDetails
+	}
+	return st
+}
+
+// UnaryServerInterceptor returns a gRPC server interceptor that renders the errors returned by
+// unary handlers with Status and the handler options opts.
+func UnaryServerInterceptor(verbose bool, opts ...middleware.Option) grpclib.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpclib.UnaryServerInfo, h grpclib.UnaryHandler) (interface{}, error) {
+		resp, err := h(ctx, req)
+		if err != nil {
+			return resp, Status(err, verbose, opts...).Err()
+		}
+		return resp, nil
+	}
+}
+
+// StreamServerInterceptor returns a gRPC server interceptor that renders the errors returned by
+// streaming handlers with Status and the handler options opts.
+func StreamServerInterceptor(verbose bool, opts ...middleware.Option) grpclib.StreamServerInterceptor {
+	return func(srv interface{}, ss grpclib.ServerStream, _ *grpclib.StreamServerInfo, h grpclib.StreamHandler) error {
+		if err := h(srv, ss); err != nil {
+			return Status(err, verbose, opts...).Err()
+		}
+		return nil
+	}
+}
+
+// errorInfo returns the google.rpc.ErrorInfo detail of problem, its reason is the code of problem
+// or its type if it has no code.
+func errorInfo(problem *middleware.Rfc7807Response) *errdetails.ErrorInfo {
+	reason := problem.Type
+	if problem.Code != "" {
+		reason = problem.Code
+	}
+	md := make(map[string]string)
+	for k, v := range map[string]string{
+		"type":     problem.Type,
+		"title":    problem.Title,
+		"instance": problem.Instance,
+		"trace_id": problem.TraceID,
+	} {
+		if v != "" {
+			md[k] = v
+		}
+	}
+	return &errdetails.ErrorInfo{Reason: reason, Domain: ErrorInfoDomain, Metadata: md}
+}
+
+// retryDelay converts the retry_after meta value v, a number of seconds or a delay, into a delay.
+func retryDelay(v interface{}) (time.Duration, bool) {
+	switch actual := v.(type) {
+	case int:
+		return time.Duration(actual) * time.Second, true
+	case int64:
+		return time.Duration(actual) * time.Second, true
+	case float64:
+		return time.Duration(actual * float64(time.Second)), true
+	case time.Duration:
+		return actual, true
+	case string:
+		f, err := strconv.ParseFloat(actual, 64)
+		if err != nil || math.IsNaN(f) {
+			return 0, false
+		}
+		return time.Duration(f * float64(time.Second)), true
+	}
+	return 0, false
+}
+
+// setMeta sets the meta value of problem with key k to v.
+func setMeta(problem *middleware.Rfc7807Response, k string, v interface{}) {
+	if problem.Meta == nil {
+		problem.Meta = make(map[string]interface{})
+	}
+	problem.Meta[k] = v
+}
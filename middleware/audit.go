@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/goadesign/goa"
+)
+
+type (
+	// AuditRecord describes a problem response for the audit trail. Records are passed by value
+	// so sinks cannot alter the records received by other sinks.
+	AuditRecord struct {
+		// Time is the time the problem was sent.
+		Time time.Time `json:"time"`
+		// Principal identifies the authenticated user or client, see WithAuditPrincipal.
+		Principal string `json:"principal,omitempty"`
+		// Method is the request HTTP method.
+		Method string `json:"method"`
+		// Path is the request URL path.
+		Path string `json:"path"`
+		// Controller is the name of the goa controller that handled the request, "<unknown>"
+		// outside of goa controllers.
+		Controller string `json:"controller"`
+		// Action is the name of the goa action that handled the request, "<unknown>" outside of
+		// goa actions.
+		Action string `json:"action"`
+		// Status is the status of the response.
+		Status int `json:"status"`
+		// Type is the problem type, "about:blank" for problems without type.
+		Type string `json:"type"`
+		// TraceID is the problem trace ID.
+		TraceID string `json:"trace_id"`
+	}
+
+	// AuditSink stores the audit records of problem responses, for example in environments that
+	// must retain an audit log of errors for compliance.
+	AuditSink interface {
+		// Audit stores r. It is called synchronously after the problem is sent, sinks that are
+		// slow should buffer records or be wrapped with AsyncAuditSink.
+		Audit(ctx context.Context, r AuditRecord) error
+	}
+
+	// WriterAuditSink writes audit records to a writer as JSON lines. It is safe for concurrent
+	// use.
+	WriterAuditSink struct {
+		mu sync.Mutex
+		w  io.Writer
+	}
+
+	// HTTPAuditSink posts audit records as JSON to an HTTP endpoint.
+	HTTPAuditSink struct {
+		// URL is the endpoint records are posted to.
+		URL string
+		// Client is the client used to post records, http.DefaultClient when nil.
+		Client *http.Client
+	}
+)
+
+// WithAuditSink adds s to the sinks that receive an AuditRecord for every problem response. Sink
+// failures are logged at the error level and do not alter the response.
+func WithAuditSink(s AuditSink) Option {
+	return func(o *options) {
+		o.auditSinks = append(o.auditSinks, s)
+	}
+}
+
+// WithAuditPrincipal sets the function that returns the principal of the request with context
+// ctx recorded in audit records, e.g. the subject of the JWT token of the request.
+func WithAuditPrincipal(f func(ctx context.Context) string) Option {
+	return func(o *options) {
+		o.auditPrincipal = f
+	}
+}
+
+// audit sends the audit record of the problem sent with the given status to the audit sinks.
+func (o *options) audit(ctx context.Context, req *http.Request, status int, problem *Rfc7807Response) {
+	if len(o.auditSinks) == 0 {
+		return
+	}
+	typ := problem.Type
+	if typ == "" {
+		typ = BlankProblemType
+	}
+	r := AuditRecord{
+		Time:       o.now().UTC(),
+		Method:     req.Method,
+		Path:       req.URL.Path,
+		Controller: goa.ContextController(ctx),
+		Action:     goa.ContextAction(ctx),
+		Status:     status,
+		Type:       typ,
+		TraceID:    problem.TraceID,
+	}
+	if o.auditPrincipal != nil {
+		r.Principal = o.auditPrincipal(ctx)
+	}
+	for _, s := range o.auditSinks {
+		if err := s.Audit(ctx, r); err != nil {
+			o.log(ctx, LevelError, "audit failed", "err", err, "trace_id", r.TraceID)
+		}
+	}
+}
+
+// NewWriterAuditSink returns a sink writing audit records to w.
+func NewWriterAuditSink(w io.Writer) *WriterAuditSink {
+	return &WriterAuditSink{w: w}
+}
+
+// NewFileAuditSink returns a sink appending audit records to the file with the given name, the
+// file is created with mode 0600 if it does not exist. The caller closes the file with Close.
+func NewFileAuditSink(name string) (*WriterAuditSink, error) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterAuditSink(f), nil
+}
+
+// Audit writes r followed by a newline.
+func (s *WriterAuditSink) Audit(_ context.Context, r AuditRecord) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(b, '\n'))
+	return err
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (s *WriterAuditSink) Close() error {
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Audit posts r, responses with a status other than 2xx are reported as errors.
+func (s *HTTPAuditSink) Audit(ctx context.Context, r AuditRecord) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c := s.Client
+	if c == nil {
+		c = http.DefaultClient
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("audit sink %s: unexpected status %d", s.URL, resp.StatusCode)
+	}
+	return nil
+}
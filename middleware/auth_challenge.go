@@ -0,0 +1,51 @@
+package middleware
+
+import "net/http"
+
+// challengeError is an error wrapped with authentication challenges.
+type challengeError struct {
+	wrappedError
+	challenges []string
+}
+
+// Challenge wraps err with the authentication challenges sent in the WWW-Authenticate header of
+// 401 Unauthorized problems as required by RFC 7235, for example:
+//
+//	return middleware.Challenge(goa.ErrUnauthorized("token expired"),
+//		`Bearer realm="api", error="invalid_token"`)
+func Challenge(err error, challenges ...string) error {
+	return &challengeError{wrappedError: wrappedError{err}, challenges: challenges}
+}
+
+// Challenges returns the authentication challenges.
+func (e *challengeError) Challenges() []string {
+	return e.challenges
+}
+
+// WithAuthChallenge sets the authentication challenges sent in the WWW-Authenticate header of 401
+// problems whose error does not carry challenges, see Challenge. RFC 7235 requires at least one
+// challenge in 401 responses, e.g. `Bearer realm="api"`.
+func WithAuthChallenge(challenges ...string) Option {
+	return func(o *options) {
+		o.authChallenges = challenges
+	}
+}
+
+// setAuthChallenge adds the WWW-Authenticate headers of 401 problems from the chain of errors
+// wrapped by e or the default challenges. Headers already set by the downstream handlers are kept.
+func (o *options) setAuthChallenge(h http.Header, e error, status int) {
+	if status != http.StatusUnauthorized || len(h.Values("WWW-Authenticate")) > 0 {
+		return
+	}
+	challenges := o.authChallenges
+	unwrap := o.unwrap()
+	for err := e; err != nil; err = unwrap(err) {
+		if c, ok := err.(interface{ Challenges() []string }); ok {
+			challenges = c.Challenges()
+			break
+		}
+	}
+	for _, c := range challenges {
+		h.Add("WWW-Authenticate", c)
+	}
+}
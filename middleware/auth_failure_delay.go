@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// WithAuthFailureDelay delays 401 and 403 responses by d plus a random duration of up to jitter.
+// This slows down credential stuffing and makes response timing less useful to attackers.
+// The delay is skipped or cut short when the request context is canceled, for example because
+// the client disconnected. Auth failures aliased to another status are delayed too, see
+// WithStatusAliasing.
+func WithAuthFailureDelay(d time.Duration, jitter time.Duration) Option {
+	return func(o *options) {
+		o.authFailureDelay = d
+		o.authFailureJitter = jitter
+	}
+}
+
+// delayAuthFailure blocks for the configured auth failure delay if the status of the error before
+// aliasing, unaliased, or the response status is 401 or 403 so that aliased auth failures are
+// delayed too, see WithStatusAliasing.
+func (o *options) delayAuthFailure(ctx context.Context, unaliased, status int) {
+	if o.shadow || (!isAuthFailure(unaliased) && !isAuthFailure(status)) {
+		return
+	}
+	d := o.authFailureDelay
+	if o.authFailureJitter > 0 {
+		d += time.Duration(rand.Int63n(int64(o.authFailureJitter)))
+	}
+	if d <= 0 || ctx.Err() != nil {
+		return
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// isAuthFailure returns true if status is 401 or 403.
+func isAuthFailure(status int) bool {
+	return status == http.StatusUnauthorized || status == http.StatusForbidden
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/goadesign/goa"
+)
+
+type (
+	// ItemResult is the outcome of one of the sub-operations of a batch request.
+	ItemResult struct {
+		// Status is the HTTP status of a successful sub-operation, 200 if zero. It is ignored
+		// when Err is not nil.
+		Status int
+		// Err is the error of the sub-operation, nil if it succeeded.
+		Err error
+	}
+
+	// BatchError is the error produced by AggregateErrors. It is rendered by the Rfc7807Handler as
+	// a 207 problem listing the outcome of each item in the "items" meta value.
+	BatchError struct {
+		// ID is the unique error occurrence identifier.
+		ID string
+		// Items contains the outcome of each sub-operation in the request order.
+		Items []BatchItem
+	}
+
+	// BatchItem describes the outcome of a sub-operation in BatchError.
+	BatchItem struct {
+		// Index is the position of the sub-operation in the batch.
+		Index int `json:"index" xml:"index" form:"index"`
+		// Status is the HTTP status of the sub-operation.
+		Status int `json:"status" xml:"status" form:"status"`
+		// Detail describes the error of failed sub-operations.
+		Detail string `json:"detail,omitempty" xml:"detail,omitempty" form:"detail,omitempty"`
+	}
+)
+
+// AggregateErrors returns a *BatchError describing results if any of them failed and nil
+// otherwise. The detail of goa.ServiceError errors is reported as is while other errors are
+// reported as internal errors without detail, like in non verbose mode.
+func AggregateErrors(results []ItemResult) error {
+	var failed bool
+	items := make([]BatchItem, len(results))
+	for i, r := range results {
+		items[i] = BatchItem{Index: i, Status: r.Status}
+		if r.Err == nil {
+			if items[i].Status == 0 {
+				items[i].Status = http.StatusOK
+			}
+			continue
+		}
+		failed = true
+		items[i].Status = http.StatusInternalServerError
+		items[i].Detail = http.StatusText(http.StatusInternalServerError)
+		if se, ok := cause(r.Err, unwrapCause).(goa.ServiceError); ok {
+			items[i].Status = se.ResponseStatus()
+			items[i].Detail = se.Error()
+			if resp, ok := se.(*goa.ErrorResponse); ok {
+				items[i].Detail = resp.Detail
+			}
+		}
+	}
+	if !failed {
+		return nil
+	}
+	return &BatchError{ID: shortID(), Items: items}
+}
+
+// Error returns a summary of the batch outcome.
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("[%s] %s", e.ID, e.detail())
+}
+
+// ResponseStatus returns 207 (Multi-Status).
+func (e *BatchError) ResponseStatus() int { return http.StatusMultiStatus }
+
+// Token is the unique error occurrence identifier.
+func (e *BatchError) Token() string { return e.ID }
+
+// detail returns the number of failed items.
+func (e *BatchError) detail() string {
+	var n int
+	for _, item := range e.Items {
+		if item.Status >= 400 {
+			n++
+		}
+	}
+	return fmt.Sprintf("%d of %d operations failed", n, len(e.Items))
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/goadesign/goa"
+)
+
+// ProblemTypeHeader is the name of the header that carries the type of the problems sent without
+// body, see WithProblemTypeHeader.
+const ProblemTypeHeader = "Problem-Type"
+
+// WithProblemTypeHeader sets whether the problems sent without body carry their type in the
+// Problem-Type header so that clients can still tell them apart, problems without type are
+// reported with the "about:blank" type. Problems are sent without body in response to HEAD
+// requests and when their status forbids a body, i.e. the 204 and 304 statuses which status
+// overrides and interceptors may produce: only the status line and the headers, Content-Type and
+// problem specific headers such as Retry-After included, are written.
+func WithProblemTypeHeader(enabled bool) Option {
+	return func(o *options) {
+		o.problemTypeHeader = enabled
+	}
+}
+
+// isBodiless returns true if the response to req with the given status must not have a body.
+func isBodiless(req *http.Request, status int) bool {
+	return req.Method == http.MethodHead || status == http.StatusNoContent || status == http.StatusNotModified
+}
+
+// sendBodiless writes the status line and the headers of problem sent without body.
+func (o *options) sendBodiless(ctx context.Context, rw http.ResponseWriter, status int, problem *Rfc7807Response) {
+	if o.problemTypeHeader {
+		typ := problem.Type
+		if typ == "" {
+			typ = BlankProblemType
+		}
+		rw.Header().Set(ProblemTypeHeader, trailerValue(typ))
+	}
+	rw.WriteHeader(status)
+	_, shadow := rw.(*shadowWriter)
+	if resp := goa.ContextResponse(ctx); resp != nil && resp != rw && !shadow {
+		resp.Status = status
+	}
+}
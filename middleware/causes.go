@@ -0,0 +1,53 @@
+package middleware
+
+import "fmt"
+
+// CausesMetaKey is the meta key of the chain of causes of errors, see WithCauseChain.
+const CausesMetaKey = "causes"
+
+// maxCauses is the maximum number of causes included in problems.
+const maxCauses = 32
+
+// Cause is an error of the chain of errors wrapped by the error of a problem.
+type Cause struct {
+	// Type is the Go type of the error, e.g. "*fs.PathError".
+	Type string `json:"type" xml:"type"`
+	// Message is the message of the error.
+	Message string `json:"message" xml:"message"`
+}
+
+// WithCauseChain makes the problems whose details are sent, e.g. in verbose mode, carry the chain
+// of errors wrapped by the error as an array of {type, message} objects in the "causes" meta
+// value, from the error itself to its root cause. The chain is walked with the Unwrap and Cause
+// methods, and the extra methods enabled with WithExtraUnwrapMethods, and the errors joined with
+// errors.Join or wrapping several errors are walked depth first. Messages go through the PII
+// scrubber and at most 32 causes are included.
+func WithCauseChain(enabled bool) Option {
+	return func(o *options) {
+		o.causeChain = enabled
+	}
+}
+
+// setCauses adds the chain of causes of e to problem if enabled.
+func (o *options) setCauses(problem *Rfc7807Response, e error) {
+	if !o.causeChain || e == nil {
+		return
+	}
+	var causes []Cause
+	o.appendCauses(&causes, e)
+	problem.setMeta(CausesMetaKey, causes)
+}
+
+// appendCauses appends e and the errors it wraps to causes.
+func (o *options) appendCauses(causes *[]Cause, e error) {
+	unwrap := o.unwrap()
+	for ; e != nil && len(*causes) < maxCauses; e = unwrap(e) {
+		*causes = append(*causes, Cause{Type: fmt.Sprintf("%T", e), Message: o.scrub(e.Error())})
+		if m, ok := e.(interface{ Unwrap() []error }); ok {
+			for _, err := range m.Unwrap() {
+				o.appendCauses(causes, err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+const (
+	// Rfc7807CborMediaIdentifier is the media type of CBOR problems.
+	Rfc7807CborMediaIdentifier = "application/problem+cbor"
+	// ConciseProblemDetailsMediaIdentifier is the media type of the concise problem details
+	// defined in RFC 9290.
+	ConciseProblemDetailsMediaIdentifier = "application/concise-problem-details+cbor"
+)
+
+// The keys of the standard problem detail entries defined in RFC 9290 Section 3.1. The response
+// code entry, key -4, holds CoAP response codes and is not used for HTTP statuses.
+const (
+	cborTitleKey    = -1
+	cborDetailKey   = -2
+	cborInstanceKey = -3
+)
+
+// The CBOR major types.
+const (
+	cborUnsigned byte = iota << 5
+	cborNegative
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+// WithCBOR registers EncodeCBOR as the serializer of the application/problem+cbor and
+// application/concise-problem-details+cbor media types so that constrained clients negotiate
+// CBOR problems with the Accept header. The problems go through the same processing as the other
+// representations.
+func WithCBOR() Option {
+	return func(o *options) {
+		WithSerializer(Rfc7807CborMediaIdentifier, EncodeCBOR)(o)
+		WithSerializer(ConciseProblemDetailsMediaIdentifier, EncodeCBOR)(o)
+	}
+}
+
+// EncodeCBOR writes problem to w as a CBOR map using the keys of RFC 9290: the title, detail and
+// instance are encoded with the negative integer keys of the standard problem detail entries and
+// the type, HTTP status, trace ID, code, meta values and extension members with text keys, the
+// status under "status" since the response code entry holds CoAP response codes. Empty members
+// are omitted and the text keys are sorted so that the output is deterministic. Values that are
+// not strings, numbers, booleans, slices or maps are encoded like their JSON representation.
+func EncodeCBOR(w io.Writer, problem *Rfc7807Response) error {
+	entries := make(map[string]interface{})
+	if problem.Type != "" {
+		entries["type"] = problem.Type
+	}
+	if problem.Status != 0 {
+		entries["status"] = problem.Status
+	}
+	if problem.TraceID != "" {
+		entries["trace_id"] = problem.TraceID
+	}
+	if problem.Code != "" {
+		entries["code"] = problem.Code
+	}
+	if len(problem.Meta) > 0 {
+		entries["meta"] = problem.Meta
+	}
+	if problem.Extensions != nil {
+		ext, err := extensionValues(problem.Extensions)
+		if err != nil {
+			return err
+		}
+		for k, v := range ext {
+			entries[k] = v
+		}
+	}
+	var std [][2]interface{}
+	if problem.Title != "" {
+		std = append(std, [2]interface{}{cborTitleKey, problem.Title})
+	}
+	if problem.Detail != "" {
+		std = append(std, [2]interface{}{cborDetailKey, problem.Detail})
+	}
+	if problem.Instance != "" {
+		std = append(std, [2]interface{}{cborInstanceKey, problem.Instance})
+	}
+	var buf bytes.Buffer
+	writeCBORHead(&buf, cborMap, uint64(len(std)+len(entries)))
+	for _, e := range std {
+		if err := writeCBOR(&buf, e[0]); err != nil {
+			return err
+		}
+		if err := writeCBOR(&buf, e[1]); err != nil {
+			return err
+		}
+	}
+	if err := writeCBORMembers(&buf, entries); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeCBOR writes the CBOR encoding of v to buf.
+func writeCBOR(buf *bytes.Buffer, v interface{}) error {
+	switch actual := v.(type) {
+	case nil:
+		buf.WriteByte(cborSimple | 22)
+	case bool:
+		if actual {
+			buf.WriteByte(cborSimple | 21)
+		} else {
+			buf.WriteByte(cborSimple | 20)
+		}
+	case string:
+		writeCBORHead(buf, cborText, uint64(len(actual)))
+		buf.WriteString(actual)
+	case []byte:
+		writeCBORHead(buf, cborBytes, uint64(len(actual)))
+		buf.Write(actual)
+	case int:
+		writeCBORInt(buf, int64(actual))
+	case int64:
+		writeCBORInt(buf, actual)
+	case int32:
+		writeCBORInt(buf, int64(actual))
+	case uint:
+		writeCBORHead(buf, cborUnsigned, uint64(actual))
+	case uint64:
+		writeCBORHead(buf, cborUnsigned, actual)
+	case uint32:
+		writeCBORHead(buf, cborUnsigned, uint64(actual))
+	case float64:
+		writeCBORFloat(buf, actual)
+	case float32:
+		writeCBORFloat(buf, float64(actual))
+	case json.Number:
+		if n, err := strconv.ParseInt(string(actual), 10, 64); err == nil {
+			writeCBORInt(buf, n)
+			return nil
+		}
+		f, err := actual.Float64()
+		if err != nil {
+			return err
+		}
+		writeCBORFloat(buf, f)
+	case []interface{}:
+		writeCBORHead(buf, cborArray, uint64(len(actual)))
+		for _, e := range actual {
+			if err := writeCBOR(buf, e); err != nil {
+				return err
+			}
+		}
+	case []string:
+		writeCBORHead(buf, cborArray, uint64(len(actual)))
+		for _, e := range actual {
+			writeCBOR(buf, e)
+		}
+	case map[string]interface{}:
+		writeCBORHead(buf, cborMap, uint64(len(actual)))
+		return writeCBORMembers(buf, actual)
+	default:
+		b, err := json.Marshal(actual)
+		if err != nil {
+			return fmt.Errorf("cbor: %s", err)
+		}
+		var generic interface{}
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber()
+		if err := d.Decode(&generic); err != nil {
+			return fmt.Errorf("cbor: %s", err)
+		}
+		return writeCBOR(buf, generic)
+	}
+	return nil
+}
+
+// writeCBORMembers writes the keys and values of m sorted by key, the map head must have been
+// written.
+func writeCBORMembers(buf *bytes.Buffer, m map[string]interface{}) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		writeCBOR(buf, k)
+		if err := writeCBOR(buf, m[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCBORInt writes the CBOR encoding of the integer n to buf.
+func writeCBORInt(buf *bytes.Buffer, n int64) {
+	if n >= 0 {
+		writeCBORHead(buf, cborUnsigned, uint64(n))
+		return
+	}
+	writeCBORHead(buf, cborNegative, uint64(-1-n))
+}
+
+// writeCBORFloat writes f to buf as a double precision float.
+func writeCBORFloat(buf *bytes.Buffer, f float64) {
+	var b [9]byte
+	b[0] = cborSimple | 27
+	binary.BigEndian.PutUint64(b[1:], math.Float64bits(f))
+	buf.Write(b[:])
+}
+
+// writeCBORHead writes the head of a data item of the given major type and argument n.
+func writeCBORHead(buf *bytes.Buffer, major byte, n uint64) {
+	switch {
+	case n < 24:
+		buf.WriteByte(major | byte(n))
+	case n <= math.MaxUint8:
+		buf.Write([]byte{major | 24, byte(n)})
+	case n <= math.MaxUint16:
+		var b [3]byte
+		b[0] = major | 25
+		binary.BigEndian.PutUint16(b[1:], uint16(n))
+		buf.Write(b[:])
+	case n <= math.MaxUint32:
+		var b [5]byte
+		b[0] = major | 26
+		binary.BigEndian.PutUint32(b[1:], uint32(n))
+		buf.Write(b[:])
+	default:
+		var b [9]byte
+		b[0] = major | 27
+		binary.BigEndian.PutUint64(b[1:], n)
+		buf.Write(b[:])
+	}
+}
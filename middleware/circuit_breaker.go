@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DependencyUnavailableType is the type of the problems of the calls rejected by open circuit
+// breakers, a relative reference resolved against the base set with WithTypeBaseURI.
+const DependencyUnavailableType = "dependency-unavailable"
+
+// The meta keys of the problems of the calls rejected by circuit breakers.
+const (
+	// DependencyMetaKey is the meta key of the name of the unavailable dependency.
+	DependencyMetaKey = "dependency"
+	// BreakerStateMetaKey is the meta key of the state of the circuit breaker.
+	BreakerStateMetaKey = "breaker_state"
+)
+
+// Breaker describes a circuit breaker registered with RegisterBreaker, for example for a
+// github.com/sony/gobreaker breaker:
+//
+//	middleware.RegisterBreaker("billing", middleware.Breaker{
+//		Rejects:    func(err error) bool { return errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) },
+//		State:      func() string { return cb.State().String() },
+//		RetryAfter: 60 * time.Second,
+//	})
+//
+// and for a github.com/afex/hystrix-go command:
+//
+//	middleware.RegisterBreaker("billing", middleware.Breaker{
+//		Rejects: func(err error) bool { return errors.Is(err, hystrix.ErrCircuitOpen) },
+//	})
+type Breaker struct {
+	// Rejects returns true if err is, or wraps, the error returned by the breaker when it
+	// rejects a call.
+	Rejects func(err error) bool
+	// State returns the current state of the breaker, e.g. "open" or "half-open", the state is
+	// "open" if nil.
+	State func() string
+	// RetryAfter is the delay after which the breaker may let calls through again, e.g. its open
+	// timeout, no delay is suggested if 0.
+	RetryAfter time.Duration
+}
+
+var (
+	// breakersMu protects breakers.
+	breakersMu sync.RWMutex
+	// breakers contains the circuit breakers registered with RegisterBreaker by name.
+	breakers = make(map[string]Breaker)
+)
+
+// breakerOpenError is an error of a call rejected by a circuit breaker.
+type breakerOpenError struct {
+	wrappedError
+	dependency string
+	delay      time.Duration
+}
+
+// RegisterBreaker registers the circuit breaker b protecting the calls to the dependency name.
+// The errors rejected by b that no error mapper handles are sent as 503 problems of type
+// DependencyUnavailableType with the dependency name, the breaker state and the suggested retry
+// delay in the dependency, breaker_state and retry_after meta values and the Retry-After header.
+// Breakers are checked in name order, wrap errors with BreakerOpen when several breakers return
+// the same error, as gobreaker breakers do.
+func RegisterBreaker(name string, b Breaker) {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	breakers[name] = b
+}
+
+// BreakerOpen wraps err, the error of a call to the dependency rejected by its circuit breaker,
+// so that it is sent as a 503 problem like the errors of the breakers registered with
+// RegisterBreaker, d is the suggested retry delay. The state is the one of the breaker registered
+// with the dependency name if any, "open" otherwise.
+func BreakerOpen(err error, dependency string, d time.Duration) error {
+	return &breakerOpenError{wrappedError: wrappedError{err}, dependency: dependency, delay: d}
+}
+
+// RetryAfter returns the retry delay.
+func (e *breakerOpenError) RetryAfter() time.Duration {
+	return e.delay
+}
+
+// mapBreakerError returns the problem of the errors of the calls rejected by circuit breakers.
+func mapBreakerError(e error) (*Rfc7807Response, bool) {
+	breakersMu.RLock()
+	defer breakersMu.RUnlock()
+	var boe *breakerOpenError
+	if errors.As(e, &boe) {
+		return breakerProblem(boe.dependency, breakers[boe.dependency], boe.delay), true
+	}
+	names := make([]string, 0, len(breakers))
+	for name := range breakers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if b := breakers[name]; b.Rejects != nil && b.Rejects(e) {
+			return breakerProblem(name, b, b.RetryAfter), true
+		}
+	}
+	return nil, false
+}
+
+// breakerProblem returns the problem of a call to dependency rejected by b.
+func breakerProblem(dependency string, b Breaker, d time.Duration) *Rfc7807Response {
+	state := "open"
+	if b.State != nil {
+		state = b.State()
+	}
+	problem := &Rfc7807Response{
+		Type:   DependencyUnavailableType,
+		Status: http.StatusServiceUnavailable,
+		Detail: fmt.Sprintf("The %s dependency is unavailable, its circuit breaker is %s.", dependency, state),
+	}
+	problem.setMeta(DependencyMetaKey, dependency)
+	problem.setMeta(BreakerStateMetaKey, state)
+	if d > 0 {
+		problem.setMeta(RetryAfterMetaKey, int64(math.Ceil(d.Seconds())))
+	}
+	return problem
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/goadesign/goa"
+)
+
+// CommittedAction is what the handler does besides logging when a downstream handler fails after
+// the response status line has been written, e.g. while streaming a chunked response, so that no
+// problem can be sent.
+type CommittedAction int
+
+const (
+	// CommittedLog only logs the error, this is the default.
+	CommittedLog CommittedAction = iota
+	// CommittedTrailers logs the error and sends the problem status and trace ID in the
+	// X-Problem-Status and X-Trace-Id trailers.
+	CommittedTrailers
+	// CommittedAbort logs the error and aborts the response by panicking with
+	// http.ErrAbortHandler so that net/http closes the connection and the client does not mistake
+	// the truncated body for a complete one.
+	CommittedAbort
+)
+
+// ProblemStatusTrailer is the name of the trailer that carries the status of the problems of
+// committed responses, see CommittedTrailers.
+const ProblemStatusTrailer = "X-Problem-Status"
+
+// WithCommittedResponse sets the action taken when a downstream handler fails after the response
+// status line has been written. The handler never writes a problem body into a committed
+// response. Responses are detected as committed when the response writer has a Written() bool
+// method returning true, as goa.ResponseData and the writers wrapped by HTTPMiddleware do.
+// Committed Server-Sent Events streams end with a problem event instead, see SSEWriter.
+func WithCommittedResponse(a CommittedAction) Option {
+	return func(o *options) {
+		o.committedAction = a
+	}
+}
+
+// committedWriter is a response writer that records whether the response status line has been
+// written.
+type committedWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+// WriteHeader records the response as committed and writes the status line.
+func (w *committedWriter) WriteHeader(status int) {
+	w.written = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the response as committed and writes b.
+func (w *committedWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush flushes the underlying writer if it supports it.
+func (w *committedWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.written = true
+		f.Flush()
+	}
+}
+
+// Written returns true if the response status line has been written.
+func (w *committedWriter) Written() bool {
+	return w.written
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *committedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// logCommitted logs e which occurred after the response to req was committed and returns the
+// status of e and the ID of the entry.
+func (o *options) logCommitted(ctx context.Context, req *http.Request, e error) (int, string) {
+	status := http.StatusInternalServerError
+	if se, ok := cause(e, o.unwrap()).(goa.ServiceError); ok {
+		status = se.ResponseStatus()
+	}
+	id, ok := RequestID(ctx)
+	if !ok {
+		id = o.newTraceID(ctx, req)
+	}
+	keyvals := []interface{}{"err", o.truncateLogError(o.scrub(fmt.Sprintf("%+v", e))), "id", id, "status", status}
+	keyvals = append(keyvals, identityFields(o.identity(ctx, req))...)
+	o.log(ctx, LevelError, "error after response committed", append(keyvals, o.logFields(ctx)...)...)
+	return status, id
+}
+
+// isCommitted returns true if the status line of the response written with rw has been written.
+func isCommitted(rw http.ResponseWriter) bool {
+	w, ok := rw.(interface{ Written() bool })
+	return ok && w.Written()
+}
+
+// sendCommitted logs e which occurred after the response was committed and takes the configured
+// action.
+func (o *options) sendCommitted(ctx context.Context, rw http.ResponseWriter, req *http.Request, e error) {
+	status, id := o.logCommitted(ctx, req, e)
+	o.setProblemTrailers(ctx, rw, req, e, status)
+	switch o.committedAction {
+	case CommittedTrailers:
+		rw.Header().Set(http.TrailerPrefix+ProblemStatusTrailer, strconv.Itoa(status))
+		if !o.traceIDTrailer {
+			rw.Header().Set(http.TrailerPrefix+TraceIDTrailer, id)
+		}
+	case CommittedAbort:
+		panic(http.ErrAbortHandler)
+	}
+}
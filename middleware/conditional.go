@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Problem types of conditional request failures, relative references resolved against the base
+// set with WithTypeBaseURI.
+const (
+	// PreconditionFailedType is the type of the problems returned by PreconditionFailed.
+	PreconditionFailedType = "precondition-failed"
+	// IdempotencyConflictType is the type of the problems returned by IdempotencyConflict.
+	IdempotencyConflictType = "idempotency-key-conflict"
+)
+
+// Meta keys of the problems of conditional request failures.
+const (
+	// ExpectedETagMetaKey is the meta key of the entity tag the client expected.
+	ExpectedETagMetaKey = "expected_etag"
+	// ActualETagMetaKey is the meta key of the current entity tag of the resource.
+	ActualETagMetaKey = "actual_etag"
+	// IdempotencyKeyMetaKey is the meta key of the idempotency key of the conflicting request.
+	IdempotencyKeyMetaKey = "idempotency_key"
+	// OriginalRequestIDMetaKey is the meta key of the ID of the request that first used an
+	// idempotency key.
+	OriginalRequestIDMetaKey = "original_request_id"
+)
+
+// IdempotencyKeyHeader is the name of the header carrying the idempotency key of requests.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// PreconditionFailed returns an error sent as a 412 Precondition Failed problem for a conditional
+// request, e.g. with If-Match, whose precondition does not hold. expected is the entity tag sent
+// by the client and actual the current entity tag of the resource, they are sent in the
+// expected_etag and actual_etag meta values and the current entity tag in the ETag header so
+// that clients can refresh their copy and retry. Either may be empty.
+func PreconditionFailed(expected, actual string) error {
+	b := NewProblem(http.StatusPreconditionFailed).Type(PreconditionFailedType)
+	if expected != "" {
+		b.Meta(ExpectedETagMetaKey, entityTag(expected))
+	}
+	if actual != "" {
+		b.Meta(ActualETagMetaKey, entityTag(actual))
+		b.Detail(fmt.Sprintf("The resource has been modified, its current entity tag is %s.", entityTag(actual)))
+	} else {
+		b.Detail("The precondition of the request does not hold.")
+	}
+	return b.Err()
+}
+
+// IdempotencyConflict returns an error sent as a 409 Conflict problem for a request reusing the
+// idempotency key of a previous request with a different payload, or while the previous request
+// is still being processed. The key and the ID of the original request are sent in the
+// idempotency_key and original_request_id meta values and the key in the Idempotency-Key
+// header.
+func IdempotencyConflict(key, originalRequestID string) error {
+	return NewProblem(http.StatusConflict).
+		Type(IdempotencyConflictType).
+		Detail(fmt.Sprintf("The idempotency key %q was already used by request %s.", key, originalRequestID)).
+		Meta(IdempotencyKeyMetaKey, key).
+		Meta(OriginalRequestIDMetaKey, originalRequestID).
+		Err()
+}
+
+// setConditionalHeaders sets the ETag header of 412 problems carrying the current entity tag of
+// the resource and the Idempotency-Key header of 409 problems carrying an idempotency key.
+func setConditionalHeaders(h http.Header, problem *Rfc7807Response) {
+	switch problem.Status {
+	case http.StatusPreconditionFailed:
+		if v, ok := problem.Meta[ActualETagMetaKey].(string); ok && v != "" {
+			h.Set("ETag", v)
+		}
+	case http.StatusConflict:
+		if v, ok := problem.Meta[IdempotencyKeyMetaKey].(string); ok && v != "" {
+			h.Set(IdempotencyKeyHeader, v)
+		}
+	}
+}
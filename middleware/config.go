@@ -0,0 +1,223 @@
+package middleware
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config contains the settings of the handler that deployments tune without recompiling, see
+// LoadFromEnv. The zero value configures the default handler.
+type Config struct {
+	// Verbose is the verbose flag, GOANS_VERBOSE.
+	Verbose bool
+	// TypeBaseURI is the base of relative problem types, GOANS_TYPE_BASE_URI.
+	TypeBaseURI string
+	// IDGenerator is the generator of trace IDs, one of "short" (the default), "uuid", "ulid" and
+	// "ksuid", GOANS_ID_GENERATOR.
+	IDGenerator string
+	// IDPrefix is prepended to the trace IDs, GOANS_ID_PREFIX.
+	IDPrefix string
+	// DefaultLanguage is the language of the catalog messages used when none of the languages
+	// accepted by the client is available, GOANS_DEFAULT_LANGUAGE.
+	DefaultLanguage string
+	// PseudoLocalization enables the pseudo-localization of catalog messages,
+	// GOANS_PSEUDO_LOCALIZATION.
+	PseudoLocalization bool
+	// RFC9457 enables RFC 9457 mode, GOANS_RFC9457.
+	RFC9457 bool
+	// LogClientErrors enables the logging of 4xx responses, GOANS_LOG_CLIENT_ERRORS.
+	LogClientErrors bool
+	// LogRateLimit is the maximum number of error log entries per client and LogRateWindow, 0
+	// disables the limit, GOANS_LOG_RATE_LIMIT.
+	LogRateLimit int
+	// LogRateWindow is the window of LogRateLimit, one minute when zero, GOANS_LOG_RATE_WINDOW.
+	LogRateWindow time.Duration
+	// LogSampling is the maximum number of log entries of 5xx problems per error fingerprint and
+	// LogSamplingWindow, 0 disables sampling, GOANS_LOG_SAMPLING.
+	LogSampling int
+	// LogSamplingWindow is the window of LogSampling, one minute when zero,
+	// GOANS_LOG_SAMPLING_WINDOW.
+	LogSamplingWindow time.Duration
+	// DebugToken is the token of the X-Debug-Token header making requests verbose,
+	// GOANS_DEBUG_TOKEN.
+	DebugToken string
+	// DebugNetworks lists the CIDRs of the networks whose requests are verbose, the environment
+	// variable GOANS_DEBUG_NETWORKS is a comma separated list.
+	DebugNetworks []string
+	// TrustedProxies lists the CIDRs of the trusted reverse proxies, the environment variable
+	// GOANS_TRUSTED_PROXIES is a comma separated list.
+	TrustedProxies []string
+}
+
+// LoadFromEnv returns the configuration read from the GOANS_* environment variables documented on
+// the fields of Config. Booleans are parsed with strconv.ParseBool, durations with
+// time.ParseDuration and networks with net.ParseCIDR, an error is returned for invalid values.
+// Unset variables keep their zero value, for example:
+//
+//	cfg, err := middleware.LoadFromEnv()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	service.Use(middleware.Rfc7807HandlerWithOptions(service, cfg.Options()...))
+func LoadFromEnv() (Config, error) {
+	var c Config
+	var err error
+	env := func(name string, parse func(string) error) {
+		v, ok := os.LookupEnv(name)
+		if !ok || err != nil {
+			return
+		}
+		if perr := parse(strings.TrimSpace(v)); perr != nil {
+			err = fmt.Errorf("invalid %s: %s", name, perr)
+		}
+	}
+	env("GOANS_VERBOSE", parseBool(&c.Verbose))
+	env("GOANS_TYPE_BASE_URI", parseString(&c.TypeBaseURI))
+	env("GOANS_ID_GENERATOR", func(v string) error {
+		switch strings.ToLower(v) {
+		case "", "short", "uuid", "ulid", "ksuid":
+			c.IDGenerator = strings.ToLower(v)
+			return nil
+		}
+		return fmt.Errorf("unknown generator %q", v)
+	})
+	env("GOANS_ID_PREFIX", parseString(&c.IDPrefix))
+	env("GOANS_DEFAULT_LANGUAGE", parseString(&c.DefaultLanguage))
+	env("GOANS_PSEUDO_LOCALIZATION", parseBool(&c.PseudoLocalization))
+	env("GOANS_RFC9457", parseBool(&c.RFC9457))
+	env("GOANS_LOG_CLIENT_ERRORS", parseBool(&c.LogClientErrors))
+	env("GOANS_LOG_RATE_LIMIT", parseInt(&c.LogRateLimit))
+	env("GOANS_LOG_RATE_WINDOW", parseDuration(&c.LogRateWindow))
+	env("GOANS_LOG_SAMPLING", parseInt(&c.LogSampling))
+	env("GOANS_LOG_SAMPLING_WINDOW", parseDuration(&c.LogSamplingWindow))
+	env("GOANS_DEBUG_TOKEN", parseString(&c.DebugToken))
+	env("GOANS_DEBUG_NETWORKS", parseNetworkList(&c.DebugNetworks))
+	env("GOANS_TRUSTED_PROXIES", parseNetworkList(&c.TrustedProxies))
+	return c, err
+}
+
+// Options returns the handler options corresponding to c. It panics if the debug networks or
+// trusted proxies of c contain invalid CIDRs, which LoadFromEnv reports as errors.
+func (c Config) Options() []Option {
+	opts := []Option{WithVerbose(c.Verbose)}
+	if c.TypeBaseURI != "" {
+		opts = append(opts, WithTypeBaseURI(c.TypeBaseURI))
+	}
+	var g IDGenerator
+	switch c.IDGenerator {
+	case "uuid":
+		g = UUIDGenerator()
+	case "ulid":
+		g = ULIDGenerator()
+	case "ksuid":
+		g = KSUIDGenerator()
+	}
+	if c.IDPrefix != "" {
+		if g == nil {
+			g = IDGeneratorFunc(shortID)
+		}
+		g = PrefixedGenerator(c.IDPrefix, g)
+	}
+	if g != nil {
+		opts = append(opts, WithIDGenerator(g))
+	}
+	if c.DefaultLanguage != "" {
+		opts = append(opts, WithDefaultLanguage(c.DefaultLanguage))
+	}
+	if c.PseudoLocalization {
+		opts = append(opts, WithPseudoLocalization(true))
+	}
+	if c.RFC9457 {
+		opts = append(opts, WithRFC9457(true))
+	}
+	if c.LogClientErrors {
+		opts = append(opts, WithLogClientErrors(true))
+	}
+	if c.LogRateLimit > 0 {
+		opts = append(opts, WithPerClientLogRateLimit(c.LogRateLimit, orMinute(c.LogRateWindow)))
+	}
+	if c.LogSampling > 0 {
+		opts = append(opts, WithLogSampling(c.LogSampling, orMinute(c.LogSamplingWindow), nil))
+	}
+	if c.DebugToken != "" {
+		opts = append(opts, WithDebugToken("", c.DebugToken))
+	}
+	if len(c.DebugNetworks) > 0 {
+		opts = append(opts, WithDebugNetworks(c.DebugNetworks...))
+	}
+	if len(c.TrustedProxies) > 0 {
+		opts = append(opts, WithTrustedProxies(c.TrustedProxies...))
+	}
+	return opts
+}
+
+// orMinute returns d or one minute if d is not positive.
+func orMinute(d time.Duration) time.Duration {
+	if d <= 0 {
+		return time.Minute
+	}
+	return d
+}
+
+// parseString returns a function storing its argument in s.
+func parseString(s *string) func(string) error {
+	return func(v string) error {
+		*s = v
+		return nil
+	}
+}
+
+// parseList returns a function parsing its comma separated argument into l.
+func parseList(l *[]string) func(string) error {
+	return func(v string) error {
+		for _, e := range strings.Split(v, ",") {
+			if e = strings.TrimSpace(e); e != "" {
+				*l = append(*l, e)
+			}
+		}
+		return nil
+	}
+}
+
+// parseNetworkList returns a function parsing its comma separated argument into l and returning an
+// error if one of the elements is not a valid CIDR.
+func parseNetworkList(l *[]string) func(string) error {
+	return func(v string) error {
+		var cidrs []string
+		if err := parseList(&cidrs)(v); err != nil {
+			return err
+		}
+		if _, err := parseNetworks(cidrs); err != nil {
+			return err
+		}
+		*l = append(*l, cidrs...)
+		return nil
+	}
+}
+
+// parseBool returns a function parsing its argument into b.
+func parseBool(b *bool) func(string) error {
+	return func(v string) (err error) {
+		*b, err = strconv.ParseBool(v)
+		return
+	}
+}
+
+// parseInt returns a function parsing its argument into n.
+func parseInt(n *int) func(string) error {
+	return func(v string) (err error) {
+		*n, err = strconv.Atoi(v)
+		return
+	}
+}
+
+// parseDuration returns a function parsing its argument into d.
+func parseDuration(d *time.Duration) func(string) error {
+	return func(v string) (err error) {
+		*d, err = time.ParseDuration(v)
+		return
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Meta keys read from 409 problems by WithConflictDetail.
+const (
+	// CurrentVersionMetaKey is the meta key of the current version of the conflicting resource.
+	CurrentVersionMetaKey = "current_version"
+	// ExpectedVersionMetaKey is the meta key of the version the client expected.
+	ExpectedVersionMetaKey = "expected_version"
+)
+
+// WithConflictDetail helps clients retry 409 responses such as optimistic locking failures. When
+// the error meta values contain the current_version key, the ETag header is set to the current
+// version. The current_version and expected_version values are sent in the problem meta.
+func WithConflictDetail(enabled bool) Option {
+	return func(o *options) {
+		o.conflictDetail = enabled
+	}
+}
+
+// setConflictDetail sets the ETag header of 409 problems that carry the current version of the
+// resource.
+func (o *options) setConflictDetail(h http.Header, problem *Rfc7807Response) {
+	if !o.conflictDetail || problem.Status != http.StatusConflict {
+		return
+	}
+	v, ok := problem.Meta[CurrentVersionMetaKey]
+	if !ok {
+		return
+	}
+	h.Set("ETag", entityTag(fmt.Sprintf("%v", v)))
+}
+
+// entityTag returns v as a quoted entity tag unless it already is one.
+func entityTag(v string) string {
+	if strings.HasPrefix(v, `"`) || strings.HasPrefix(v, `W/"`) {
+		return v
+	}
+	return `"` + v + `"`
+}
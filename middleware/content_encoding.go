@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/goadesign/goa"
+)
+
+// encodingState is the response writer and the Content-Encoding header of a response before the
+// downstream handlers run.
+type encodingState struct {
+	// resp is the goa response of the request, if any.
+	resp *goa.ResponseData
+	// writer is the writer of resp.
+	writer http.ResponseWriter
+	// encoding is the Content-Encoding header.
+	encoding string
+}
+
+// captureEncoding returns the encoding state of the response written with rw before the
+// downstream handlers run.
+func captureEncoding(ctx context.Context, rw http.ResponseWriter) encodingState {
+	s := encodingState{encoding: rw.Header().Get("Content-Encoding")}
+	if resp := goa.ContextResponse(ctx); resp != nil {
+		s.resp, s.writer = resp, resp.ResponseWriter
+	}
+	return s
+}
+
+// restore resets the response written with rw to its encoding state before a downstream handler
+// failed so that problems are never sent with a mislabeled body. Compression middlewares placed
+// above the handler wrap rw and encode problems like any other body. The ones placed below, such
+// as the goa gzip middleware, leave a compressing writer in the goa response and may have set the
+// Content-Encoding header when they return an error: the original writer of the goa response is
+// restored and the encoding headers are reset so that the small problem body is sent
+// uncompressed. Committed responses are left untouched.
+func (s encodingState) restore(rw http.ResponseWriter) {
+	if isCommitted(rw) {
+		return
+	}
+	if s.resp != nil {
+		s.resp.SwitchWriter(s.writer)
+	}
+	h := rw.Header()
+	if h.Get("Content-Encoding") == s.encoding {
+		return
+	}
+	if s.encoding == "" {
+		h.Del("Content-Encoding")
+	} else {
+		h.Set("Content-Encoding", s.encoding)
+	}
+	h.Del("Content-Length")
+}
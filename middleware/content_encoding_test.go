@@ -0,0 +1,93 @@
+package middleware_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goadesign/goa"
+	goagzip "github.com/goadesign/goa/middleware/gzip"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+// newTestService returns a goa service encoding JSON and discarding its logs.
+func newTestService() *goa.Service {
+	s := goa.New("test")
+	s.Encoder.Register(goa.NewJSONEncoder, "application/json", "*/*")
+	s.WithLogger(goa.NewLogger(log.New(ioutil.Discard, "", 0)))
+	return s
+}
+
+// serve runs h wrapped by the middlewares mws, the first one being the outermost, with a goa
+// context and returns the recorded response.
+func serve(h goa.Handler, req *http.Request, mws ...goa.Middleware) *httptest.ResponseRecorder {
+	rw := httptest.NewRecorder()
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	ctx := goa.NewContext(context.Background(), rw, req, nil)
+	h(ctx, goa.ContextResponse(ctx), req)
+	return rw
+}
+
+func TestProblemEncoding(t *testing.T) {
+	notFound := func(context.Context, http.ResponseWriter, *http.Request) error {
+		return goa.ErrNotFound("no such order")
+	}
+	partial := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Header().Set("Content-Encoding", "gzip")
+		return goa.ErrNotFound("no such order")
+	}
+	handler := middleware.Rfc7807HandlerWithOptions(newTestService())
+	gzipped := goagzip.Middleware(gzip.BestSpeed, goagzip.MinSize(0),
+		goagzip.AddContentTypes(middleware.Rfc7807JsonMediaIdentifier), goagzip.AddStatusCodes(http.StatusNotFound))
+	cases := []struct {
+		name     string
+		h        goa.Handler
+		mws      []goa.Middleware
+		encoding string
+		gzip     bool
+	}{
+		{"gzip below handler", notFound, []goa.Middleware{handler, gzipped}, "gzip", false},
+		{"encoding set by failed handler", partial, []goa.Middleware{handler, gzipped}, "gzip", false},
+		{"gzip above handler", notFound, []goa.Middleware{gzipped, handler}, "gzip", true},
+		{"gzip not accepted", notFound, []goa.Middleware{handler, gzipped}, "", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/orders/1", nil)
+			if c.encoding != "" {
+				req.Header.Set("Accept-Encoding", c.encoding)
+			}
+			rw := serve(c.h, req, c.mws...)
+			body := rw.Body.Bytes()
+			if got := rw.Header().Get("Content-Encoding"); c.gzip != (got == "gzip") {
+				t.Fatalf("got Content-Encoding %q", got)
+			}
+			if c.gzip {
+				r, err := gzip.NewReader(bytes.NewReader(body))
+				if err != nil {
+					t.Fatalf("invalid gzip body: %s", err)
+				}
+				if body, err = ioutil.ReadAll(r); err != nil {
+					t.Fatalf("invalid gzip body: %s", err)
+				}
+			}
+			var problem middleware.Rfc7807Response
+			if err := json.Unmarshal(body, &problem); err != nil {
+				t.Fatalf("invalid problem %q: %s", body, err)
+			}
+			if rw.Code != http.StatusNotFound || problem.Status != http.StatusNotFound {
+				t.Errorf("got status %d and problem status %d, want 404", rw.Code, problem.Status)
+			}
+		})
+	}
+}
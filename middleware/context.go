@@ -1,5 +1,13 @@
 package middleware
 
+import (
+	"context"
+	"sync"
+	"time"
+
+	goamiddleware "github.com/goadesign/goa/middleware"
+)
+
 // middlewareKey is the private type used for goa middlewares to store values in the context.
 // It is private to avoid possible collisions with keys used by other packages.
 type middlewareKey int
@@ -12,4 +20,70 @@ const (
 	traceKey
 	spanKey
 	parentSpanKey
+
+	// requestStartKey is the context key used to store the time the request started.
+	requestStartKey
+
+	// problemHandlerKey is the context key used by HTTPMiddleware to store the problem handler.
+	problemHandlerKey
+
+	// problemEnrichmentKey is the context key used to store the values recorded by
+	// WithProblemDetail and WithProblemMeta.
+	problemEnrichmentKey
+
+	// settingsKey is the context key used to store the settings a request is handled with.
+	settingsKey
 )
+
+// WithRequestID returns a copy of ctx that records id as the request ID. The ID is used by the
+// middlewares of this package for log correlation and as the trace ID of internal errors.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, reqIDKey, id)
+}
+
+var (
+	// requestIDSourcesMu protects requestIDSources.
+	requestIDSourcesMu sync.RWMutex
+	// requestIDSources are the functions returning the request IDs recorded by other
+	// middlewares, the goa RequestID middleware is consulted by default.
+	requestIDSources = []func(context.Context) string{goamiddleware.ContextRequestID}
+)
+
+// RequestID returns the request ID recorded in ctx by WithRequestID or by the middlewares of this
+// package if any. Otherwise the request ID recorded by the RequestID middleware of goa or by the
+// sources registered with RegisterRequestIDSource is returned so that the ID in logs, in the
+// request ID header and in problems is the same value.
+func RequestID(ctx context.Context) (string, bool) {
+	if id, ok := ctx.Value(reqIDKey).(string); ok {
+		return id, true
+	}
+	requestIDSourcesMu.RLock()
+	defer requestIDSourcesMu.RUnlock()
+	for _, f := range requestIDSources {
+		if id := f(ctx); id != "" {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// RegisterRequestIDSource adds f to the functions RequestID consults when ctx carries no ID
+// recorded with WithRequestID, for example the GetReqID function of the chi middleware package.
+// f returns the empty string when ctx carries no ID.
+func RegisterRequestIDSource(f func(ctx context.Context) string) {
+	requestIDSourcesMu.Lock()
+	defer requestIDSourcesMu.Unlock()
+	requestIDSources = append(requestIDSources, f)
+}
+
+// WithRequestStartTime returns a copy of ctx that records t as the time the request started.
+func WithRequestStartTime(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, requestStartKey, t)
+}
+
+// RequestStartTime returns the time the request started as recorded in ctx by
+// WithRequestStartTime if any.
+func RequestStartTime(ctx context.Context) (time.Time, bool) {
+	t, ok := ctx.Value(requestStartKey).(time.Time)
+	return t, ok
+}
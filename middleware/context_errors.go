@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/goadesign/goa"
+)
+
+// StatusClientClosedRequest is the non standard status of requests canceled by the client, as
+// logged by nginx.
+const StatusClientClosedRequest = 499
+
+// Problem types of context errors, relative references resolved against the base set with
+// WithTypeBaseURI.
+const (
+	// DeadlineExceededType is the type of the problems of errors caused by
+	// context.DeadlineExceeded.
+	DeadlineExceededType = "deadline-exceeded"
+	// CanceledType is the type of the problems of errors caused by context.Canceled.
+	CanceledType = "request-canceled"
+)
+
+// WithContextErrors maps the errors caused by context.DeadlineExceeded to 504 Gateway Timeout
+// problems and the errors caused by context.Canceled to problems with the canceled status in place
+// of 500 internal errors. Problems with the StatusClientClosedRequest status are not written
+// since the client is gone: only the status line is sent so that access logs and metrics record
+// the cancellation. The canceled status defaults to StatusClientClosedRequest when it is not a
+// valid status between 100 and 599, e.g. 0. Mappers registered with RegisterErrorMapper or
+// WithErrorMappers take precedence.
+func WithContextErrors(canceled int) Option {
+	return func(o *options) {
+		if canceled < 100 || canceled > 599 {
+			canceled = StatusClientClosedRequest
+		}
+		o.contextErrors = true
+		o.canceledStatus = canceled
+	}
+}
+
+// mapContextError returns the problem of context errors if enabled.
+func (o *options) mapContextError(e error) (*Rfc7807Response, bool) {
+	if !o.contextErrors {
+		return nil, false
+	}
+	switch {
+	case errors.Is(e, context.DeadlineExceeded):
+		return &Rfc7807Response{
+			Type:   DeadlineExceededType,
+			Status: http.StatusGatewayTimeout,
+			Detail: "The request did not complete before its deadline.",
+		}, true
+	case errors.Is(e, context.Canceled):
+		return &Rfc7807Response{
+			Type:   CanceledType,
+			Status: o.canceledStatus,
+			Detail: "The request was canceled.",
+		}, true
+	}
+	return nil, false
+}
+
+// skipBody returns true if the problem with the given status must not be written, it then
+// writes the status line.
+func (o *options) skipBody(ctx context.Context, rw http.ResponseWriter, status int) bool {
+	if !o.contextErrors || status != StatusClientClosedRequest {
+		return false
+	}
+	rw.WriteHeader(status)
+	if resp := goa.ContextResponse(ctx); resp != nil && resp != rw {
+		resp.Status = status
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CORSConfig configures the CORS headers of problem responses, see WithCORS.
+type CORSConfig struct {
+	// AllowedOrigins lists the origins allowed to read problems, "*" allows all origins.
+	AllowedOrigins []string
+	// AllowCredentials sets Access-Control-Allow-Credentials for the origins listed explicitly
+	// in AllowedOrigins. Origins only allowed by "*" get a literal "*" without credentials so
+	// that no site can read the problems sent in response to credentialed requests.
+	AllowCredentials bool
+	// ExposedHeaders lists the headers exposed to scripts in addition to the problem headers
+	// Retry-After, WWW-Authenticate, Allow, X-Support-Code and X-Trace-Id.
+	ExposedHeaders []string
+}
+
+// problemExposedHeaders are the response headers set by the handler that scripts need to read.
+var problemExposedHeaders = []string{"Retry-After", "WWW-Authenticate", "Allow", "X-Support-Code", TraceIDTrailer}
+
+// WithCORS sets the CORS headers of the problems sent in response to cross-origin requests from
+// allowed origins. Errors produced before the CORS middleware runs, or by middlewares that do not
+// add CORS headers to error responses, are otherwise hidden from browser scripts which then
+// cannot read the problem. Access-Control-Allow-Origin headers already set on the response, e.g.
+// by a CORS middleware, are kept. The Vary header gets Origin whenever the response depends on
+// the origin of the request.
+func WithCORS(c CORSConfig) Option {
+	return func(o *options) {
+		o.cors = &c
+	}
+}
+
+// setCORS sets the CORS headers of the response to req on h.
+func (o *options) setCORS(h http.Header, req *http.Request) {
+	if o.cors == nil || h.Get("Access-Control-Allow-Origin") != "" {
+		return
+	}
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return
+	}
+	var allowed, wildcard bool
+	for _, ao := range o.cors.AllowedOrigins {
+		if ao == "*" {
+			wildcard = true
+		} else if strings.EqualFold(ao, origin) {
+			allowed = true
+			break
+		}
+	}
+	switch {
+	case allowed:
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Add("Vary", "Origin")
+		if o.cors.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+	case wildcard:
+		h.Set("Access-Control-Allow-Origin", "*")
+	default:
+		h.Add("Vary", "Origin")
+		return
+	}
+	exposed := append(append([]string{}, problemExposedHeaders...), o.cors.ExposedHeaders...)
+	h.Set("Access-Control-Expose-Headers", strings.Join(exposed, ", "))
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// DefaultDeadLetterHeaders lists the request headers recorded in dead letter entries unless
+// configured otherwise with WithDeadLetterHeaders.
+var DefaultDeadLetterHeaders = []string{"Accept", "Content-Type", "User-Agent", "X-Forwarded-For", "X-Request-Id"}
+
+// DeadLetterEntry describes a problem response recorded for post-mortem analysis.
+type DeadLetterEntry struct {
+	// Time is the time the problem was sent.
+	Time time.Time
+	// Method is the request HTTP method.
+	Method string
+	// Path is the request URL path.
+	Path string
+	// Header contains the selected request headers.
+	Header http.Header
+	// Status is the problem response status.
+	Status int
+	// TraceID is the problem trace ID.
+	TraceID string
+	// ContentType is the media type of Problem.
+	ContentType string
+	// Problem is the serialized problem as sent to the client.
+	Problem []byte
+}
+
+// WithDeadLetterSink sets a function called with a DeadLetterEntry for each 5xx problem response.
+// The function is called off the request path so that slow sinks do not delay responses, by a
+// worker reading the entries from a queue of 1024 entries: the entries sent while the queue is
+// full are dropped.
+func WithDeadLetterSink(f func(DeadLetterEntry)) Option {
+	d := NewDispatcher(DispatcherConfig{})
+	return func(o *options) {
+		o.deadLetterSink = f
+		o.deadLetterDispatcher = d
+	}
+}
+
+// WithDeadLetterHeaders sets the names of the request headers recorded in dead letter entries,
+// DefaultDeadLetterHeaders is used by default.
+func WithDeadLetterHeaders(names ...string) Option {
+	return func(o *options) {
+		o.deadLetterHeaders = names
+	}
+}
+
+// WithDeadLetterStatus sets the function deciding whether responses with a given status are sent
+// to the dead letter sink, by default only 5xx responses are.
+func WithDeadLetterStatus(f func(status int) bool) Option {
+	return func(o *options) {
+		o.deadLetterStatus = f
+	}
+}
+
+// deadLetter sends the entry describing the response to req to the dead letter sink if any.
+func (o *options) deadLetter(req *http.Request, status int, mediaType string, problem *Rfc7807Response, body []byte) {
+	if o.deadLetterSink == nil {
+		return
+	}
+	if o.deadLetterStatus != nil {
+		if !o.deadLetterStatus(status) {
+			return
+		}
+	} else if status < 500 {
+		return
+	}
+	names := o.deadLetterHeaders
+	if names == nil {
+		names = DefaultDeadLetterHeaders
+	}
+	header := make(http.Header, len(names))
+	for _, name := range names {
+		if vals := req.Header.Values(name); len(vals) > 0 {
+			header[http.CanonicalHeaderKey(name)] = append([]string(nil), vals...)
+		}
+	}
+	entry := DeadLetterEntry{
+		Time:        o.now(),
+		Method:      req.Method,
+		Path:        req.URL.Path,
+		Header:      header,
+		Status:      status,
+		TraceID:     problem.TraceID,
+		ContentType: mediaType,
+		Problem:     append([]byte(nil), body...),
+	}
+	o.deadLetterDispatcher.Dispatch(req.Context(), func(context.Context) error {
+		o.deadLetterSink(entry)
+		return nil
+	})
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// DefaultDebugTokenHeader is the request header carrying the debug token by default.
+const DefaultDebugTokenHeader = "X-Debug-Token"
+
+// WithDebugToken makes the handler verbose for the requests whose header carries token, so that
+// production responses stay terse for customers while on-call engineers can reproduce failures
+// with their full details. The header defaults to DefaultDebugTokenHeader when empty, an empty
+// token disables the check. Tokens are compared in constant time.
+func WithDebugToken(header, token string) Option {
+	return func(o *options) {
+		if header == "" {
+			header = DefaultDebugTokenHeader
+		}
+		o.debugTokenHeader = header
+		o.debugToken = token
+	}
+}
+
+// WithDebugNetworks makes the handler verbose for the requests originating from one of the
+// networks given in CIDR notation, e.g. "10.0.0.0/8". The origin is the remote address of the
+// connection, X-Forwarded-For is ignored as clients control it. It panics if one of the CIDRs is
+// invalid.
+func WithDebugNetworks(cidrs ...string) Option {
+	networks := mustParseNetworks(cidrs)
+	return func(o *options) {
+		o.debugNetworks = append(o.debugNetworks, networks...)
+	}
+}
+
+// isDebugRequest returns true if req carries the debug token or originates from a debug network.
+func (o *options) isDebugRequest(req *http.Request) bool {
+	if o.debugToken != "" {
+		t := req.Header.Get(o.debugTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(t), []byte(o.debugToken)) == 1 {
+			return true
+		}
+	}
+	return inNetworks(o.debugNetworks, remoteIP(req))
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// SupersededByMetaKey is the key of the meta value containing the URI of the problem type that
+// replaces a deprecated problem type.
+const SupersededByMetaKey = "superseded_by"
+
+// setDeprecation advertises the deprecation of the problem type t of problem: the Deprecation
+// header (RFC 9745) is set to the deprecation date, the Sunset header (RFC 8594) to the sunset
+// date and the replacement type is sent in a Link header with the "successor-version" relation
+// as well as in the SupersededByMetaKey meta value so that API consumers learn about the
+// migration. Relative replacement types are resolved like problem types.
+func (o *options) setDeprecation(h http.Header, t ProblemType, problem *Rfc7807Response) {
+	if t.Deprecated.IsZero() {
+		return
+	}
+	h.Set("Deprecation", "@"+strconv.FormatInt(t.Deprecated.Unix(), 10))
+	if !t.Sunset.IsZero() {
+		h.Set("Sunset", t.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if t.SupersededBy != "" {
+		uri := resolveTypeURI(o.typeBaseURI, t.SupersededBy)
+		h.Add("Link", "<"+uri+`>; rel="successor-version"`)
+		problem.setMeta(SupersededByMetaKey, uri)
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import "strings"
+
+// WithDetailCharset removes the characters for which allowed returns false from problem details
+// before they are sent, for example to protect clients that cannot cope with control characters
+// or emojis.
+func WithDetailCharset(allowed func(rune) bool) Option {
+	return func(o *options) {
+		o.detailCharset = allowed
+	}
+}
+
+// WithASCIIOnlyDetail removes all characters but printable ASCII characters from problem details
+// when enabled.
+func WithASCIIOnlyDetail(enabled bool) Option {
+	return func(o *options) {
+		o.detailCharset = nil
+		if enabled {
+			o.detailCharset = isPrintableASCII
+		}
+	}
+}
+
+// filterDetail removes the disallowed characters from the detail of problem.
+func (o *options) filterDetail(problem *Rfc7807Response) {
+	if o.detailCharset == nil {
+		return
+	}
+	problem.Detail = strings.Map(func(r rune) rune {
+		if !o.detailCharset(r) {
+			return -1
+		}
+		return r
+	}, problem.Detail)
+}
+
+// isPrintableASCII returns true if r is a printable ASCII character.
+func isPrintableASCII(r rune) bool {
+	return r >= ' ' && r <= '~'
+}
@@ -0,0 +1,21 @@
+package middleware
+
+// WithDetailFromMeta sets a function that may synthesize the detail of problems from their meta
+// values, for example producing "Order 42 could not be processed." from {resource: "order",
+// id: "42"}. The detail is replaced when the function returns true, it is not called for problems
+// without meta values.
+func WithDetailFromMeta(f func(meta map[string]interface{}) (string, bool)) Option {
+	return func(o *options) {
+		o.detailFromMeta = f
+	}
+}
+
+// synthesizeDetail replaces the detail of problem with the one produced from its meta values.
+func (o *options) synthesizeDetail(problem *Rfc7807Response) {
+	if o.detailFromMeta == nil || len(problem.Meta) == 0 {
+		return
+	}
+	if detail, ok := o.detailFromMeta(problem.Meta); ok {
+		problem.Detail = detail
+	}
+}
@@ -0,0 +1,308 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrDispatchQueueFull is the error passed to the OnError function of dispatchers for the
+	// tasks dropped because the queue is full.
+	ErrDispatchQueueFull = errors.New("dispatcher: queue full")
+	// ErrDispatcherClosed is the error passed to the OnError function of dispatchers for the
+	// tasks dispatched after Close or blocked by a full queue when Close is called.
+	ErrDispatcherClosed = errors.New("dispatcher: closed")
+)
+
+// DispatchPolicy is what a dispatcher does with the tasks dispatched when its queue is full.
+type DispatchPolicy int
+
+const (
+	// DispatchDrop drops the tasks, this is the default. Responses are never delayed but
+	// records may be lost under load.
+	DispatchDrop DispatchPolicy = iota
+	// DispatchBlock blocks the request until there is room in the queue, responses are delayed
+	// under load but no record is lost.
+	DispatchBlock
+)
+
+type (
+	// DispatcherConfig configures a Dispatcher.
+	DispatcherConfig struct {
+		// Workers is the number of goroutines running tasks, it defaults to 1.
+		Workers int
+		// QueueSize is the maximum number of tasks waiting for a worker, it defaults to 1024.
+		QueueSize int
+		// Policy is what the dispatcher does when the queue is full.
+		Policy DispatchPolicy
+		// OnError is called with the errors of the tasks, ErrDispatchQueueFull for dropped
+		// tasks and ErrDispatcherClosed for the tasks dropped by Close. It is called by
+		// the workers or the request goroutine and must be safe for concurrent use.
+		OnError func(error)
+	}
+
+	// Dispatcher runs the calls of error reporters and audit sinks off the request path with a
+	// bounded queue and a pool of workers, see AsyncReporter and AsyncAuditSink. Services call
+	// Close on shutdown so that queued tasks are not lost.
+	Dispatcher struct {
+		tasks   chan dispatchTask
+		policy  DispatchPolicy
+		onError func(error)
+		dropped uint64
+
+		pmu     sync.Mutex
+		pending int
+		idle    chan struct{} // closed when no task is pending
+
+		mu      sync.RWMutex
+		closed  bool
+		closing chan struct{}
+		once    sync.Once
+		done    sync.WaitGroup
+	}
+
+	// dispatchTask is a task with the context it runs with.
+	dispatchTask struct {
+		ctx context.Context
+		f   func(context.Context) error
+	}
+
+	// detachedContext carries the values of a request context without its deadline and
+	// cancellation so that tasks outlive the request.
+	detachedContext struct {
+		parent context.Context
+	}
+
+	// asyncReporter reports errors with a dispatcher.
+	asyncReporter struct {
+		r ErrorReporter
+		d *Dispatcher
+	}
+
+	// asyncAuditSink stores audit records with a dispatcher.
+	asyncAuditSink struct {
+		s AuditSink
+		d *Dispatcher
+	}
+)
+
+// NewDispatcher returns a dispatcher started with the given configuration.
+func NewDispatcher(c DispatcherConfig) *Dispatcher {
+	if c.Workers <= 0 {
+		c.Workers = 1
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = 1024
+	}
+	d := &Dispatcher{
+		tasks:   make(chan dispatchTask, c.QueueSize),
+		policy:  c.Policy,
+		onError: c.OnError,
+		idle:    make(chan struct{}),
+		closing: make(chan struct{}),
+	}
+	close(d.idle)
+	d.done.Add(c.Workers)
+	for i := 0; i < c.Workers; i++ {
+		go d.work()
+	}
+	return d
+}
+
+// Dispatch queues f to be run by a worker with a context carrying the values of ctx but not its
+// deadline and cancellation. It returns false if f was dropped because the queue is full or the
+// dispatcher is closed.
+func (d *Dispatcher) Dispatch(ctx context.Context, f func(context.Context) error) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		d.fail(ErrDispatcherClosed)
+		return false
+	}
+	t := dispatchTask{ctx: detachedContext{ctx}, f: f}
+	d.addPending()
+	if d.policy == DispatchBlock {
+		select {
+		case d.tasks <- t:
+			return true
+		case <-d.closing:
+			d.donePending()
+			d.fail(ErrDispatcherClosed)
+			return false
+		}
+	}
+	select {
+	case d.tasks <- t:
+		return true
+	default:
+		d.donePending()
+		atomic.AddUint64(&d.dropped, 1)
+		d.fail(ErrDispatchQueueFull)
+		return false
+	}
+}
+
+// Dropped returns the number of tasks dropped because the queue was full.
+func (d *Dispatcher) Dropped() uint64 {
+	return atomic.LoadUint64(&d.dropped)
+}
+
+// Flush waits until no task is pending, including the tasks dispatched while it waits, or ctx is
+// done, in which case it returns the context error.
+func (d *Dispatcher) Flush(ctx context.Context) error {
+	d.pmu.Lock()
+	idle := d.idle
+	d.pmu.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting tasks and waits until the queued tasks have run and the workers have
+// stopped or ctx is done, in which case it returns the context error and the remaining tasks
+// keep running in the background. The tasks blocked by a full queue with the DispatchBlock
+// policy are dropped with ErrDispatcherClosed.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.once.Do(func() {
+		close(d.closing)
+	})
+	stopped := make(chan struct{})
+	go func() {
+		d.mu.Lock()
+		if !d.closed {
+			d.closed = true
+			close(d.tasks)
+		}
+		d.mu.Unlock()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return wait(ctx, &d.done)
+}
+
+// addPending records a dispatched task as pending.
+func (d *Dispatcher) addPending() {
+	d.pmu.Lock()
+	if d.pending == 0 {
+		d.idle = make(chan struct{})
+	}
+	d.pending++
+	d.pmu.Unlock()
+}
+
+// donePending records a pending task as run or dropped.
+func (d *Dispatcher) donePending() {
+	d.pmu.Lock()
+	d.pending--
+	if d.pending == 0 {
+		close(d.idle)
+	}
+	d.pmu.Unlock()
+}
+
+// work runs the queued tasks until the dispatcher is closed.
+func (d *Dispatcher) work() {
+	defer d.done.Done()
+	for t := range d.tasks {
+		d.run(t)
+	}
+}
+
+// run runs t, panics are reported to the OnError function as errors.
+func (d *Dispatcher) run(t dispatchTask) {
+	defer d.donePending()
+	defer func() {
+		if r := recover(); r != nil {
+			d.fail(newPanicError(r, debug.Stack(), nil))
+		}
+	}()
+	if err := t.f(t.ctx); err != nil {
+		d.fail(err)
+	}
+}
+
+// fail calls the OnError function with err if any.
+func (d *Dispatcher) fail(err error) {
+	if d.onError != nil {
+		d.onError(err)
+	}
+}
+
+// wait waits until wg is done or ctx is done.
+func wait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Deadline returns no deadline.
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+// Done returns nil, detached contexts are never canceled.
+func (detachedContext) Done() <-chan struct{} { return nil }
+
+// Err returns nil.
+func (detachedContext) Err() error { return nil }
+
+// Value returns the value of the parent context for key.
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// AsyncReporter returns a reporter reporting errors with r in tasks dispatched with d so that
+// slow error tracking services do not delay error responses. Reports are not identified in the
+// problems since their IDs are only known once the report is sent, the event_id meta value is
+// not set. The request is cloned since it may be reused once the response is sent.
+func AsyncReporter(r ErrorReporter, d *Dispatcher) ErrorReporter {
+	return asyncReporter{r: r, d: d}
+}
+
+// Report implements ErrorReporter.
+func (a asyncReporter) Report(ctx context.Context, req *http.Request, err error, problem *Rfc7807Response) string {
+	req = req.Clone(detachedContext{ctx})
+	p := *problem
+	if problem.Meta != nil {
+		p.Meta = make(map[string]interface{}, len(problem.Meta))
+		for k, v := range problem.Meta {
+			p.Meta[k] = v
+		}
+	}
+	a.d.Dispatch(ctx, func(ctx context.Context) error {
+		a.r.Report(ctx, req, err, &p)
+		return nil
+	})
+	return ""
+}
+
+// AsyncAuditSink returns a sink storing audit records with s in tasks dispatched with d so that
+// slow sinks such as HTTPAuditSink do not delay error responses. Failures are passed to the
+// OnError function of d instead of being logged by the handler.
+func AsyncAuditSink(s AuditSink, d *Dispatcher) AuditSink {
+	return asyncAuditSink{s: s, d: d}
+}
+
+// Audit implements AuditSink.
+func (a asyncAuditSink) Audit(ctx context.Context, r AuditRecord) error {
+	a.d.Dispatch(ctx, func(ctx context.Context) error {
+		return a.s.Audit(ctx, r)
+	})
+	return nil
+}
@@ -0,0 +1,25 @@
+// Package middleware sends the errors of goa services as RFC 7807 problem responses. The
+// Rfc7807Handler middleware converts the errors returned by goa handlers into problems negotiated
+// as JSON or XML with the Accept header, and HTTPMiddleware does the same for net/http handlers.
+//
+// The package only depends on the standard library and goa so that minimal services do not pull
+// transitive dependencies they never use: the XML representation, the message catalogs, the ID
+// generators and the metrics and reporting hooks are implemented with the standard library, and
+// the integrations with third party libraries live in subpackages that plug into the handler
+// through options and the interfaces of this package:
+//
+//   - middleware/i18n/toml loads message catalogs from TOML files, see RegisterCatalogFormat.
+//   - middleware/logging/logrus, middleware/logging/zap and middleware/logging/zerolog adapt
+//     structured loggers, see Logger.
+//   - middleware/mappers/sqlerrors maps database/sql and driver errors, see ErrorMapper.
+//   - middleware/otel records problems on OpenTelemetry spans.
+//   - middleware/prometheus exposes problem metrics, see WithMetricsHook.
+//   - middleware/sentry reports internal errors to Sentry, see ErrorReporter.
+//   - middleware/v3 adapts the problems to goa v3 services.
+//
+// Services that do not use goa controllers create a handler without service, JSON problems are
+// then serialized with encoding/json:
+//
+//	p := middleware.NewProblemHandler(nil, false)
+//	http.ListenAndServe(":8080", p.HTTPMiddleware()(mux))
+package middleware
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// ErrorMapper translates errors that are not goa.ServiceError, e.g. domain errors, into problems.
+// It returns false if it does not handle err. Mappers must return a new problem on each call as
+// the handler modifies it before sending it, a problem without status is sent as a 500 problem.
+type ErrorMapper func(ctx context.Context, err error) (*Rfc7807Response, bool)
+
+var (
+	// mappersMu protects mappers.
+	mappersMu sync.RWMutex
+	// mappers lists the error mappers registered with RegisterErrorMapper.
+	mappers []ErrorMapper
+)
+
+// RegisterErrorMapper registers m with all the handlers. Mappers run in registration order, after
+// the ones given with WithErrorMappers, for errors whose cause is not a goa.ServiceError and the
+// first problem returned is sent. Errors that no mapper handles are sent as 500 problems. For
+// example:
+//
+//	middleware.RegisterErrorMapper(func(_ context.Context, err error) (*middleware.Rfc7807Response, bool) {
+//		if !errors.Is(err, sql.ErrNoRows) {
+//			return nil, false
+//		}
+//		return &middleware.Rfc7807Response{Status: http.StatusNotFound, Detail: "not found"}, true
+//	})
+func RegisterErrorMapper(m ErrorMapper) {
+	mappersMu.Lock()
+	defer mappersMu.Unlock()
+	mappers = append(mappers, m)
+}
+
+// WithErrorMappers sets mappers specific to the handler, they run before the registered ones.
+func WithErrorMappers(ms ...ErrorMapper) Option {
+	return func(o *options) {
+		o.errorMappers = append(o.errorMappers, ms...)
+	}
+}
+
+// mapError returns the problem produced by the first mapper handling err.
+func (o *options) mapError(ctx context.Context, err error) (*Rfc7807Response, bool) {
+	mappersMu.RLock()
+	ms := append(append([]ErrorMapper{}, o.errorMappers...), mappers...)
+	mappersMu.RUnlock()
+	for _, m := range ms {
+		if problem, ok := m(ctx, err); ok && problem != nil {
+			if problem.Status == 0 {
+				problem.Status = http.StatusInternalServerError
+			}
+			return problem, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+// ReportIDMetaKey is the meta key of the ID of the error report of internal errors.
+const ReportIDMetaKey = "event_id"
+
+// ErrorReporter reports internal errors to an error tracking service such as Sentry, see the
+// middleware/sentry package.
+type ErrorReporter interface {
+	// Report reports err, the error returned by the handler of req, and returns the ID of the
+	// report or an empty string if err was not reported.
+	Report(ctx context.Context, req *http.Request, err error, problem *Rfc7807Response) string
+}
+
+// WithErrorReporter reports the errors of 5xx problems with r and adds the ID of the report to
+// the event_id meta value of the problems, even in non verbose mode, so that support can find
+// the report of the error a user got. The error is reported with its whole chain so that the
+// reporter can extract stack traces.
+func WithErrorReporter(r ErrorReporter) Option {
+	return func(o *options) {
+		o.errorReporter = r
+	}
+}
+
+// report reports e if the problem is a 5xx problem and adds the report ID to its meta values.
+func (o *options) report(ctx context.Context, req *http.Request, e error, problem *Rfc7807Response) {
+	if o.errorReporter == nil || problem.Status < 500 {
+		return
+	}
+	if id := o.errorReporter.Report(ctx, req, e, problem); id != "" {
+		problem.setMeta(ReportIDMetaKey, id)
+	}
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// standardMembers lists the names of the members of problems that extensions cannot override.
+var standardMembers = map[string]bool{
+	"type": true, "title": true, "status": true, "detail": true, "instance": true,
+	"trace_id": true, "code": true, "meta": true,
+}
+
+// MarshalJSON implements json.Marshaler. Problems without extensions are serialized with their
+// struct tags, the members of the JSON object representation of Extensions are added after the
+// standard members in name order. Extension members named like a standard member are dropped so
+// that a typed extension cannot alter the problem, for example:
+//
+//	type outOfCredit struct {
+//		Balance  int      `json:"balance"`
+//		Accounts []string `json:"accounts"`
+//	}
+//	problem.Extensions = outOfCredit{Balance: 30, Accounts: []string{"/account/12345"}}
+func (r Rfc7807Response) MarshalJSON() ([]byte, error) {
+	type plain Rfc7807Response
+	b, err := json.Marshal(plain(r))
+	if err != nil || r.Extensions == nil {
+		return b, err
+	}
+	names, members, err := extensionMembers(r.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.Write(b[:len(b)-1])
+	for _, k := range names {
+		n, _ := json.Marshal(k)
+		buf.WriteByte(',')
+		buf.Write(n)
+		buf.WriteByte(':')
+		buf.Write(members[k])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// extensionMembers returns the sorted names and the JSON values of the members of the JSON object
+// representation of ext, the standard members are omitted.
+func extensionMembers(ext interface{}) ([]string, map[string]json.RawMessage, error) {
+	b, err := json.Marshal(ext)
+	if err != nil {
+		return nil, nil, err
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(b, &members); err != nil {
+		return nil, nil, fmt.Errorf("problem extensions must serialize as a JSON object: %s", err)
+	}
+	names := make([]string, 0, len(members))
+	for k := range members {
+		if standardMembers[k] {
+			delete(members, k)
+			continue
+		}
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names, members, nil
+}
+
+// extensionValues returns the members of ext decoded as generic JSON values for the
+// representations that do not use MarshalJSON.
+func extensionValues(ext interface{}) (map[string]interface{}, error) {
+	_, members, err := extensionMembers(ext)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{}, len(members))
+	for k, raw := range members {
+		var v interface{}
+		d := json.NewDecoder(bytes.NewReader(raw))
+		d.UseNumber()
+		if err := d.Decode(&v); err != nil {
+			return nil, err
+		}
+		values[k] = v
+	}
+	return values, nil
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+)
+
+// fastPathTemplates contains the pre-encoded leading members of the most common problems, the
+// untyped problems with the status text as title.
+var fastPathTemplates = map[int][]byte{
+	http.StatusUnauthorized:        fastPathTemplate(http.StatusUnauthorized),
+	http.StatusNotFound:            fastPathTemplate(http.StatusNotFound),
+	http.StatusInternalServerError: fastPathTemplate(http.StatusInternalServerError),
+}
+
+// WithFastPath enables the serialization of JSON problems without meta values nor extensions
+// without reflection into the pooled buffer, the type, title and status of the 401, 404 and 500
+// problems being pre-encoded. The output is identical to the one of encoding/json, the fast path
+// does not apply when a custom serializer, RFC 9457 mode or renamed JSON fields are in use.
+func WithFastPath(enabled bool) Option {
+	return func(o *options) {
+		o.fastPath = enabled
+	}
+}
+
+// fastPathApplies returns true if problem sent as mediaType may be serialized with encodeFastJSON.
+func (o *options) fastPathApplies(mediaType string, problem *Rfc7807Response) bool {
+	return o.fastPath && mediaType == Rfc7807JsonMediaIdentifier && problem.Meta == nil &&
+		problem.Extensions == nil
+}
+
+// encodeFastJSON writes the JSON representation of problem followed by a newline to buf like a
+// json.Encoder, problem must not have meta values nor extensions.
+func encodeFastJSON(buf *bytes.Buffer, problem *Rfc7807Response) {
+	if t, ok := fastPathTemplates[problem.Status]; ok && problem.Type == "" &&
+		problem.Title == http.StatusText(problem.Status) {
+		buf.Write(t)
+	} else {
+		buf.WriteString(`{"type":`)
+		writeJSONString(buf, problem.Type)
+		buf.WriteString(`,"title":`)
+		writeJSONString(buf, problem.Title)
+		buf.WriteString(`,"status":`)
+		var n [20]byte
+		buf.Write(strconv.AppendInt(n[:0], int64(problem.Status), 10))
+	}
+	buf.WriteString(`,"detail":`)
+	writeJSONString(buf, problem.Detail)
+	buf.WriteString(`,"instance":`)
+	writeJSONString(buf, problem.Instance)
+	buf.WriteString(`,"trace_id":`)
+	writeJSONString(buf, problem.TraceID)
+	if problem.Code != "" {
+		buf.WriteString(`,"code":`)
+		writeJSONString(buf, problem.Code)
+	}
+	buf.WriteString("}\n")
+}
+
+// fastPathTemplate returns the pre-encoded type, title and status members of the untyped problems
+// with status.
+func fastPathTemplate(status int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`{"type":"","title":`)
+	writeJSONString(&buf, http.StatusText(status))
+	buf.WriteString(`,"status":`)
+	buf.WriteString(strconv.Itoa(status))
+	return buf.Bytes()
+}
+
+// writeJSONString writes s as a JSON string to buf. Strings that encoding/json would escape are
+// delegated to it so that the output stays identical, the others are written as is.
+func writeJSONString(buf *bytes.Buffer, s string) {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c >= utf8.RuneSelf || c == '"' || c == '\\' || c == '<' ||
+			c == '>' || c == '&' {
+			b, _ := json.Marshal(s)
+			buf.Write(b)
+			return
+		}
+	}
+	buf.WriteByte('"')
+	buf.WriteString(s)
+	buf.WriteByte('"')
+}
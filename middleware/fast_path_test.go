@@ -0,0 +1,44 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goadesign/goa"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+func BenchmarkSendError(b *testing.B) {
+	errs := []struct {
+		name string
+		err  error
+	}{
+		{"not found", goa.ErrNotFound("no such order")},
+		{"internal", errors.New("connection refused")},
+	}
+	for _, fast := range []bool{false, true} {
+		handler := middleware.Rfc7807HandlerWithOptions(newTestService(), middleware.WithFastPath(fast))
+		for _, e := range errs {
+			name := e.name
+			if fast {
+				name += " fast path"
+			}
+			err := e.err
+			h := handler(func(context.Context, http.ResponseWriter, *http.Request) error { return err })
+			b.Run(name, func(b *testing.B) {
+				req := httptest.NewRequest("GET", "/orders/1", nil)
+				req.Header.Set("Accept", "application/json")
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					rw := httptest.NewRecorder()
+					ctx := goa.NewContext(context.Background(), rw, req, nil)
+					h(ctx, goa.ContextResponse(ctx), req)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// LegacyTypeFieldName is the name under which releases prior to the fix of the Type JSON tag
+// serialized the type of problems.
+const LegacyTypeFieldName = "tye"
+
+// WithLegacyFieldNames makes JSON problems carry their type under the "tye" member name used by
+// earlier releases instead of the RFC 7807 "type" member so that existing clients that adapted to
+// the typo keep working during their migration.
+func WithLegacyFieldNames(enabled bool) Option {
+	if !enabled {
+		return func(o *options) {
+			delete(o.jsonFieldNames, "type")
+		}
+	}
+	return WithJSONFieldNames(map[string]string{"type": LegacyTypeFieldName})
+}
+
+// WithJSONFieldNames renames the members of JSON problems, names maps the default member names
+// "type", "title", "status", "detail", "instance", "trace_id", "code" and "meta" to the names to
+// use, for example to follow the naming conventions of a team. Renamed problems are serialized with
+// encoding/json in place of the service encoder, register a serializer with WithSerializer for
+// complete control over the representation. The names do not apply in RFC 9457 mode.
+func WithJSONFieldNames(names map[string]string) Option {
+	return func(o *options) {
+		if o.jsonFieldNames == nil {
+			o.jsonFieldNames = make(map[string]string, len(names))
+		}
+		for k, v := range names {
+			o.jsonFieldNames[k] = v
+		}
+	}
+}
+
+// encodeRenamedJSON writes the JSON representation of problem to buf with the members renamed
+// according to names.
+func encodeRenamedJSON(buf *bytes.Buffer, problem *Rfc7807Response, names map[string]string) error {
+	members := []struct {
+		name  string
+		value interface{}
+	}{
+		{"type", problem.Type},
+		{"title", problem.Title},
+		{"status", problem.Status},
+		{"detail", problem.Detail},
+		{"instance", problem.Instance},
+		{"trace_id", problem.TraceID},
+	}
+	if problem.Code != "" {
+		members = append(members, struct {
+			name  string
+			value interface{}
+		}{"code", problem.Code})
+	}
+	if len(problem.Meta) > 0 {
+		members = append(members, struct {
+			name  string
+			value interface{}
+		}{"meta", problem.Meta})
+	}
+	buf.WriteByte('{')
+	for i, m := range members {
+		b, err := json.Marshal(m.value)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name := m.name
+		if n, ok := names[name]; ok && n != "" {
+			name = n
+		}
+		n, _ := json.Marshal(name)
+		buf.Write(n)
+		buf.WriteByte(':')
+		buf.Write(b)
+	}
+	if problem.Extensions != nil {
+		names, members, err := extensionMembers(problem.Extensions)
+		if err != nil {
+			return err
+		}
+		for _, k := range names {
+			n, _ := json.Marshal(k)
+			buf.WriteByte(',')
+			buf.Write(n)
+			buf.WriteByte(':')
+			buf.Write(members[k])
+		}
+	}
+	buf.WriteString("}\n")
+	return nil
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/goadesign/goa"
+)
+
+// FingerprintMetaKey is the meta key of the fingerprint of errors, see WithFingerprint.
+const FingerprintMetaKey = "fingerprint"
+
+// variableRegexp matches the parts of error messages that vary between occurrences of an error:
+// UUIDs, hexadecimal strings such as hashes and addresses, and numbers.
+var variableRegexp = regexp.MustCompile(`(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}|\b(0x)?[0-9a-f]*[0-9][0-9a-f]*\b`)
+
+// Fingerprint returns the signature of err, a hexadecimal hash of the type of the deepest error it
+// wraps, of its message with UUIDs, hexadecimal strings, numbers and the random IDs of goa errors
+// removed and of the top frame of its stack trace if any, see WithStackTrace. Occurrences of an
+// error that only differ by IDs share their fingerprint so that log aggregators can deduplicate
+// them and recurring incidents can be linked to a single signature. It returns an empty string if
+// err is nil.
+func Fingerprint(err error) string {
+	return fingerprint(err, unwrapCause)
+}
+
+// WithFingerprint sets whether the fingerprint of errors, see Fingerprint, is added to their log
+// entries and to problems in the fingerprint meta value, even in non verbose mode since it does
+// not reveal the error.
+func WithFingerprint(enabled bool) Option {
+	return func(o *options) {
+		o.fingerprint = enabled
+	}
+}
+
+// errorFingerprint returns the fingerprint of e if enabled, an empty string otherwise.
+func (o *options) errorFingerprint(e error) string {
+	if !o.fingerprint {
+		return ""
+	}
+	return fingerprint(e, o.unwrap())
+}
+
+// fingerprint returns the fingerprint of e, errors are unwrapped with unwrap.
+func fingerprint(e error, unwrap func(error) error) string {
+	if e == nil {
+		return ""
+	}
+	msg := e.Error()
+	root := e
+	for c := e; c != nil; c = unwrap(c) {
+		root = c
+		if resp, ok := c.(*goa.ErrorResponse); ok && resp.ID != "" {
+			msg = strings.Replace(msg, "["+resp.ID+"] ", "", -1)
+		}
+	}
+	var frame string
+	if frames := stackFrames(e, unwrap); len(frames) > 0 {
+		frame = frames[0].Func
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%T\n%s\n%s", root, variableRegexp.ReplaceAllString(msg, "#"), frame)))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// goaProblemTypes describes the problem types of the errors defined by goa indexed by error code.
+var goaProblemTypes = map[string]ProblemType{
+	"bad_request": {
+		Title:       "Bad Request",
+		Status:      http.StatusBadRequest,
+		Description: "The request is malformed.",
+	},
+	"unauthorized": {
+		Title:       "Unauthorized",
+		Status:      http.StatusUnauthorized,
+		Description: "The request lacks valid authentication credentials.",
+	},
+	"invalid_request": {
+		Title:       "Invalid Request",
+		Status:      http.StatusBadRequest,
+		Description: "The request parameters, headers or payload failed validation.",
+	},
+	"invalid_encoding": {
+		Title:       "Invalid Encoding",
+		Status:      http.StatusBadRequest,
+		Description: "The request body could not be decoded.",
+	},
+	"request_too_large": {
+		Title:       "Request Too Large",
+		Status:      http.StatusRequestEntityTooLarge,
+		Description: "The request body exceeds the maximum size accepted by the service.",
+	},
+	"no_auth_middleware": {
+		Title:       "Missing Auth Middleware",
+		Status:      http.StatusInternalServerError,
+		Description: "The service does not define the middleware of a security scheme.",
+	},
+	"invalid_file": {
+		Title:       "Invalid File",
+		Status:      http.StatusNotFound,
+		Description: "The requested file does not exist.",
+	},
+	"not_found": {
+		Title:       "Not Found",
+		Status:      http.StatusNotFound,
+		Description: "The requested resource does not exist.",
+	},
+	"method_not_allowed": {
+		Title:       "Method Not Allowed",
+		Status:      http.StatusMethodNotAllowed,
+		Description: "The resource does not support the request method.",
+	},
+	"internal": {
+		Title:       "Internal Server Error",
+		Status:      http.StatusInternalServerError,
+		Description: "The service failed to process the request.",
+	},
+}
+
+// RegisterGoaProblemTypes registers the problem types of the errors defined by goa, such as
+// goa.ErrBadRequest, goa.ErrNotFound and goa.ErrInternal, with r. The URI of each type is base
+// followed by the error code with dashes, e.g. "https://example.com/probs/not-found" for
+// goa.ErrNotFound with the base "https://example.com/probs/". An empty base produces relative
+// URIs resolved against the base set with WithTypeBaseURI.
+func RegisterGoaProblemTypes(r *ProblemTypeRegistry, base string) {
+	for code, t := range goaProblemTypes {
+		t.URI = base + strings.Replace(code, "_", "-", -1)
+		r.Register(code, t)
+	}
+}
+
+// WithGoaProblemTypes sets the type and title of the problems of goa errors whose code is not
+// registered with the problem type registry, see RegisterGoaProblemTypes for the URIs built from
+// base.
+func WithGoaProblemTypes(base string) Option {
+	return func(o *options) {
+		o.goaProblemTypes = NewProblemTypeRegistry()
+		RegisterGoaProblemTypes(o.goaProblemTypes, base)
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+// Hook is called at a well-defined point of the processing of an error response with the problem
+// and the error returned by the downstream handler, see WithBeforeSend and WithAfterSend.
+type Hook func(ctx context.Context, req *http.Request, problem *Rfc7807Response, err error)
+
+// WithBeforeSend appends hooks called in registration order right before problems are
+// serialized, after the problem type is resolved and the problem is localized. Hooks may modify
+// the problem, for example to add audit data. The status of the response cannot be changed,
+// use an interceptor for that.
+func WithBeforeSend(hooks ...Hook) Option {
+	return func(o *options) {
+		o.beforeSend = append(o.beforeSend, hooks...)
+	}
+}
+
+// WithAfterSend appends hooks called in registration order once problems have been written, for
+// example to emit audit events or record custom metrics. Hooks must not modify the problem.
+func WithAfterSend(hooks ...Hook) Option {
+	return func(o *options) {
+		o.afterSend = append(o.afterSend, hooks...)
+	}
+}
+
+// runHooks calls hooks with the given arguments.
+func runHooks(ctx context.Context, hooks []Hook, req *http.Request, problem *Rfc7807Response, err error) {
+	for _, h := range hooks {
+		h(ctx, req, problem, err)
+	}
+}
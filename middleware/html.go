@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"html/template"
+	"io"
+)
+
+// HTMLMediaIdentifier is the media type of HTML problem pages.
+const HTMLMediaIdentifier = "text/html"
+
+// DefaultHTMLTemplate is the template of the problem pages rendered by WithHTMLPages, it shows the
+// status, title, detail and trace ID of problems.
+var DefaultHTMLTemplate = template.Must(template.New("problem").Parse(`<!DOCTYPE html>
+<html lang="en"><head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Status}} {{.Title}}</title>
+<style>body{font-family:system-ui,sans-serif;max-width:40em;margin:4em auto;padding:0 1em;color:#222}
+h1{font-size:1.6em}code{background:#f3f3f3;padding:.1em .3em}footer{margin-top:2em;color:#777;font-size:.9em}</style>
+</head><body>
+<h1>{{.Status}} {{.Title}}</h1>
+{{if .Detail}}<p>{{.Detail}}</p>{{end}}
+{{if .TraceID}}<footer>Reference: <code>{{.TraceID}}</code></footer>{{end}}
+</body></html>
+`))
+
+// WithHTMLPages renders problems with DefaultHTMLTemplate for clients that prefer HTML, so that
+// users hitting the API from a browser see a readable page instead of a JSON document.
+func WithHTMLPages() Option {
+	return WithHTMLTemplate(DefaultHTMLTemplate)
+}
+
+// WithHTMLTemplate renders problems with t for clients that prefer HTML, such as browsers. The
+// template is executed with the *Rfc7807Response as data and html/template escapes all the
+// problem fields. Clients that prefer HTML get problems in the fallback format, problem+json by
+// default, when no template is configured, see WithFallbackFormat.
+func WithHTMLTemplate(t *template.Template) Option {
+	return WithSerializer(HTMLMediaIdentifier, func(w io.Writer, problem *Rfc7807Response) error {
+		return t.Execute(w, problem)
+	})
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// defaultProblemHandler is the handler used by WriteProblem outside of HTTPMiddleware.
+var defaultProblemHandler = NewProblemHandler(nil, false)
+
+// HTTPMiddleware returns a standard net/http middleware for services that do not use goa or for
+// handlers mounted directly on the goa mux. Downstream handlers send errors as problems with
+// WriteProblem, which uses the handler configuration, and panics are recovered and sent as 500
+// problems, or with the status of the problem or error given to panic, see PanicError, unless the
+// response is already committed, see WithCommittedResponse.
+func (p *ProblemHandler) HTTPMiddleware() func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			rw := &committedWriter{ResponseWriter: w}
+			ctx := context.WithValue(req.Context(), problemHandlerKey, p)
+			if _, ok := RequestStartTime(ctx); !ok {
+				ctx = WithRequestStartTime(ctx, time.Now())
+			}
+			ctx = withProblemEnrichment(ctx)
+			ctx = p.withSettings(ctx)
+			o := p.settings(ctx)
+			ctx = o.traceparentRequestID(ctx, req)
+			ctx = o.declareTraceIDTrailer(ctx, rw, req)
+			o.declareProblemTrailers(rw)
+			req = req.WithContext(ctx)
+			defer o.setTraceIDTrailer(ctx, rw)
+			encoding := captureEncoding(ctx, rw)
+			err := Recover()(func(_ context.Context, rw http.ResponseWriter, req *http.Request) error {
+				h.ServeHTTP(rw, req)
+				return nil
+			})(ctx, rw, req)
+			if err != nil {
+				encoding.restore(rw)
+				p.WriteProblem(rw, req, err)
+			}
+		})
+	}
+}
+
+// WriteProblem sends err as a problem in response to req like the Rfc7807Handler middleware does
+// for the errors returned by goa handlers. It returns an error if the problem cannot be sent.
+func (p *ProblemHandler) WriteProblem(rw http.ResponseWriter, req *http.Request, err error) error {
+	if rw == nil {
+		return ErrNilResponseWriter
+	}
+	return p.sendError(req.Context(), rw, req, err)
+}
+
+// WriteProblem sends err as a problem in response to req using the problem handler of the
+// HTTPMiddleware middleware that served req, or a non verbose handler with the default settings
+// if there is none.
+func WriteProblem(rw http.ResponseWriter, req *http.Request, err error) error {
+	p, ok := req.Context().Value(problemHandlerKey).(*ProblemHandler)
+	if !ok {
+		p = defaultProblemHandler
+	}
+	return p.WriteProblem(rw, req, err)
+}
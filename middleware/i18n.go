@@ -0,0 +1,326 @@
+package middleware
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type (
+	// Message is the localized title and detail of a problem type. Empty members leave the
+	// corresponding problem member unchanged.
+	Message struct {
+		// Title is the localized title.
+		Title string `json:"title" toml:"title"`
+		// Detail is the localized detail, it only replaces the default detail of the status.
+		Detail string `json:"detail" toml:"detail"`
+	}
+
+	// Catalog contains the localized messages of problem types. It is safe for concurrent use.
+	Catalog struct {
+		mu        sync.RWMutex
+		messages  map[string]map[string]localized
+		fallbacks map[string][]string
+	}
+
+	// localized is a message with the language tag it was registered with.
+	localized struct {
+		tag string
+		msg Message
+	}
+
+	// languageRange is a language range of an Accept-Language header with its weight.
+	languageRange struct {
+		tag string
+		q   float64
+	}
+)
+
+// NewCatalog returns an empty catalog.
+func NewCatalog() *Catalog {
+	return &Catalog{messages: make(map[string]map[string]localized)}
+}
+
+// Add sets the message of the problem type typ in the language lang, a BCP 47 language tag such as
+// "fr" or "pt-BR". typ is the type URI as sent in responses, problems without type and problems
+// whose type is "about:blank" are looked up by their status, e.g. "404".
+func (c *Catalog) Add(typ, lang string, m Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.messages[typ] == nil {
+		c.messages[typ] = make(map[string]localized)
+	}
+	c.messages[typ][strings.ToLower(lang)] = localized{tag: lang, msg: m}
+}
+
+// SetFallback sets the languages tried in order when no message is available in the language
+// lang, for example:
+//
+//	c.SetFallback("fr-CA", "fr-FR", "fr", "en")
+//
+// Fallback languages have their own fallback chain, so that the chain fr-CA → fr → en may also
+// be configured with c.SetFallback("fr", "en"). Languages without fallback chain fall back to
+// their base language, e.g. "fr-CA" to "fr". Calling SetFallback without fallbacks removes the
+// chain of lang.
+func (c *Catalog) SetFallback(lang string, fallbacks ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lang = strings.ToLower(lang)
+	if len(fallbacks) == 0 {
+		delete(c.fallbacks, lang)
+		return
+	}
+	if c.fallbacks == nil {
+		c.fallbacks = make(map[string][]string)
+	}
+	chain := make([]string, len(fallbacks))
+	for i, f := range fallbacks {
+		chain[i] = strings.ToLower(f)
+	}
+	c.fallbacks[lang] = chain
+}
+
+// AddMessages adds the messages in the language lang indexed by problem type.
+func (c *Catalog) AddMessages(lang string, messages map[string]Message) {
+	for typ, m := range messages {
+		c.Add(typ, lang, m)
+	}
+}
+
+// LoadFS adds the messages of the files of fsys matching pattern, for example an embed.FS or an
+// os.DirFS. Each file contains the messages of the language given by its base name without
+// extension, e.g. "fr.json", indexed by problem type:
+//
+//	{"https://example.com/probs/out-of-credit": {"title": "Crédit insuffisant"}}
+//
+// Files are decoded with the format registered for their extension, see RegisterCatalogFormat,
+// JSON is supported by default.
+func (c *Catalog) LoadFS(fsys fs.FS, pattern string) error {
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		ext := path.Ext(f)
+		unmarshal, ok := catalogFormat(ext)
+		if !ok {
+			return fmt.Errorf("catalog %s: unsupported format %q", f, ext)
+		}
+		b, err := fs.ReadFile(fsys, f)
+		if err != nil {
+			return err
+		}
+		var messages map[string]Message
+		if err := unmarshal(b, &messages); err != nil {
+			return fmt.Errorf("catalog %s: %s", f, err)
+		}
+		c.AddMessages(strings.TrimSuffix(path.Base(f), ext), messages)
+	}
+	return nil
+}
+
+// Lookup returns the message of the problem type typ in the language preferred by the given
+// Accept-Language header value and the language tag of the message. Language ranges match the
+// tags they are equal to or a prefix of, e.g. "fr" matches "fr-CA", and regional ranges fall
+// back to their fallback languages, see SetFallback.
+func (c *Catalog) Lookup(typ, acceptLanguage string) (Message, string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	langs := c.messages[typ]
+	if len(langs) == 0 {
+		return Message{}, "", false
+	}
+	for _, r := range parseAcceptLanguage(acceptLanguage) {
+		if l, ok := langs[r]; ok {
+			return l.msg, l.tag, true
+		}
+		var tags []string
+		for tag := range langs {
+			if r == "*" || strings.HasPrefix(tag, r+"-") {
+				tags = append(tags, tag)
+			}
+		}
+		if len(tags) > 0 {
+			sort.Strings(tags)
+			l := langs[tags[0]]
+			return l.msg, l.tag, true
+		}
+		for _, f := range c.fallbackChain(r) {
+			if l, ok := langs[f]; ok {
+				return l.msg, l.tag, true
+			}
+		}
+	}
+	return Message{}, "", false
+}
+
+// fallbackChain returns the fallback languages of the lowercase language tag lang in the order
+// they are tried, lang excluded.
+func (c *Catalog) fallbackChain(lang string) []string {
+	var chain []string
+	seen := map[string]bool{lang: true}
+	for queue := []string{lang}; len(queue) > 0; queue = queue[1:] {
+		next, ok := c.fallbacks[queue[0]]
+		if !ok {
+			if i := strings.LastIndex(queue[0], "-"); i > 0 {
+				next = []string{queue[0][:i]}
+			}
+		}
+		for _, f := range next {
+			if !seen[f] {
+				seen[f] = true
+				chain = append(chain, f)
+				queue = append(queue, f)
+			}
+		}
+	}
+	return chain
+}
+
+// WithCatalog localizes the title and detail of problems with the messages of c in the language
+// preferred by the Accept-Language request header. Details specific to the occurrence are kept,
+// only the default detail of the status is localized and the request ID of the masked details of
+// 500 problems is preserved. The Content-Language response header is set to the language of the
+// message and Accept-Language is added to the Vary header.
+func WithCatalog(c *Catalog) Option {
+	return func(o *options) {
+		o.catalog = c
+	}
+}
+
+// WithDefaultLanguage sets the language of the catalog messages used when none of the languages
+// accepted by the client is available, see WithCatalog.
+func WithDefaultLanguage(lang string) Option {
+	return func(o *options) {
+		o.defaultLanguage = lang
+	}
+}
+
+// WithPseudoLocalization sets whether the titles and details taken from the catalog are
+// pseudo-localized, i.e. their letters are replaced with accented lookalikes, they are padded
+// to simulate the expansion of translations and they are enclosed in brackets:
+//
+//	Not Found → [Ñöţ Ƒöûñð ~~~]
+//
+// Messages missing from the catalog, or hardcoded in the errors, stand out in the responses as
+// plain text, and truncated text is detected by the missing closing bracket. The mode is meant
+// for test environments, see WithCatalog.
+func WithPseudoLocalization(enabled bool) Option {
+	return func(o *options) {
+		o.pseudoLocalization = enabled
+	}
+}
+
+// localize replaces the title and the default detail of problem with the catalog message in the
+// language preferred by req.
+func (o *options) localize(h http.Header, req *http.Request, problem *Rfc7807Response) {
+	if o.catalog == nil {
+		return
+	}
+	h.Add("Vary", "Accept-Language")
+	typ := problem.Type
+	if typ == "" || typ == BlankProblemType {
+		typ = strconv.Itoa(problem.Status)
+	}
+	m, lang, ok := o.catalog.Lookup(typ, req.Header.Get("Accept-Language"))
+	if !ok && o.defaultLanguage != "" {
+		m, lang, ok = o.catalog.Lookup(typ, o.defaultLanguage)
+	}
+	if !ok {
+		return
+	}
+	if o.pseudoLocalization {
+		m = Message{Title: pseudoLocalize(m.Title), Detail: pseudoLocalize(m.Detail)}
+	}
+	if m.Title != "" {
+		problem.Title = m.Title
+	}
+	if m.Detail != "" {
+		problem.Detail = localizedDetail(problem.Detail, problem.Status, m.Detail)
+	}
+	h.Set("Content-Language", lang)
+}
+
+// localizedDetail returns the localized detail of a problem with the given status and detail. Only
+// empty details and the default detail of the status, followed or not by the request ID of masked
+// 500 details, are replaced.
+func localizedDetail(detail string, status int, localized string) string {
+	text := http.StatusText(status)
+	switch {
+	case detail == "" || detail == text:
+		return localized
+	case strings.HasPrefix(detail, text+" [") && strings.HasSuffix(detail, "]"):
+		return localized + detail[len(text):]
+	default:
+		return detail
+	}
+}
+
+// parseAcceptLanguage returns the lowercase language ranges of the Accept-Language header value
+// v sorted by decreasing weight, ranges with a zero weight are dropped.
+func parseAcceptLanguage(v string) []string {
+	var ranges []languageRange
+	for _, part := range strings.Split(v, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		for _, p := range fields[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if f, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		ranges = append(ranges, languageRange{tag: tag, q: q})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+	tags := make([]string, len(ranges))
+	for i, r := range ranges {
+		tags[i] = r.tag
+	}
+	return tags
+}
+
+// pseudoLetters maps ASCII letters to accented lookalikes.
+var pseudoLetters = map[rune]rune{
+	'a': 'å', 'b': 'ƀ', 'c': 'ç', 'd': 'ð', 'e': 'é', 'f': 'ƒ', 'g': 'ĝ', 'h': 'ĥ', 'i': 'î',
+	'j': 'ĵ', 'k': 'ķ', 'l': 'ļ', 'm': 'ɱ', 'n': 'ñ', 'o': 'ö', 'p': 'þ', 'q': 'ǫ', 'r': 'ŕ',
+	's': 'š', 't': 'ţ', 'u': 'û', 'v': 'ṽ', 'w': 'ŵ', 'x': 'ẋ', 'y': 'ý', 'z': 'ž',
+	'A': 'Å', 'B': 'Ɓ', 'C': 'Ç', 'D': 'Ð', 'E': 'É', 'F': 'Ƒ', 'G': 'Ĝ', 'H': 'Ĥ', 'I': 'Î',
+	'J': 'Ĵ', 'K': 'Ķ', 'L': 'Ļ', 'M': 'Ṁ', 'N': 'Ñ', 'O': 'Ö', 'P': 'Þ', 'Q': 'Ǫ', 'R': 'Ŕ',
+	'S': 'Š', 'T': 'Ţ', 'U': 'Û', 'V': 'Ṽ', 'W': 'Ŵ', 'X': 'Ẋ', 'Y': 'Ý', 'Z': 'Ž',
+}
+
+// pseudoLocalize returns the pseudo-localized s, see WithPseudoLocalization. The padding adds
+// about a third of the length of s.
+func pseudoLocalize(s string) string {
+	if s == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('[')
+	n := 0
+	for _, r := range s {
+		if p, ok := pseudoLetters[r]; ok {
+			r = p
+		}
+		b.WriteRune(r)
+		n++
+	}
+	b.WriteByte(' ')
+	b.WriteString(strings.Repeat("~", (n+2)/3))
+	b.WriteByte(']')
+	return b.String()
+}
@@ -0,0 +1,24 @@
+// Package toml adds support for TOML translation catalogs (github.com/pelletier/go-toml) to the
+// RFC 7807 middleware. Importing the package registers the ".toml" extension so that
+// Catalog.LoadFS decodes TOML files:
+//
+//	import _ "github.com/blueoceans/goans/middleware/i18n/toml"
+//
+// Each file contains a table per problem type:
+//
+//	["https://example.com/probs/out-of-credit"]
+//	title = "Crédit insuffisant"
+package toml
+
+import (
+	gotoml "github.com/pelletier/go-toml/v2"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+// Extension is the extension of TOML catalog files.
+const Extension = ".toml"
+
+func init() {
+	middleware.RegisterCatalogFormat(Extension, gotoml.Unmarshal)
+}
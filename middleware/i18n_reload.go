@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// catalogFormatsMu protects catalogFormats.
+	catalogFormatsMu sync.RWMutex
+	// catalogFormats maps the extensions of catalog files to the functions decoding them.
+	catalogFormats = map[string]func([]byte, interface{}) error{".json": json.Unmarshal}
+)
+
+// RegisterCatalogFormat registers the function decoding the catalog files with the extension ext,
+// e.g. ".toml", see the i18n/toml subpackage. Registering an extension again replaces its
+// function.
+func RegisterCatalogFormat(ext string, unmarshal func(data []byte, v interface{}) error) {
+	catalogFormatsMu.Lock()
+	defer catalogFormatsMu.Unlock()
+	catalogFormats[strings.ToLower(ext)] = unmarshal
+}
+
+// catalogFormat returns the function decoding the catalog files with the extension ext.
+func catalogFormat(ext string) (func([]byte, interface{}) error, bool) {
+	catalogFormatsMu.RLock()
+	defer catalogFormatsMu.RUnlock()
+	f, ok := catalogFormats[strings.ToLower(ext)]
+	return f, ok
+}
+
+// ReloadFS replaces the messages of c with the messages of the files of fsys matching pattern,
+// see LoadFS. The files are loaded before the messages are swapped so that lookups see either
+// the old or the new messages, c is left unchanged if a file fails to load. Messages added with
+// Add are discarded.
+func (c *Catalog) ReloadFS(fsys fs.FS, pattern string) error {
+	fresh := NewCatalog()
+	if err := fresh.LoadFS(fsys, pattern); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = fresh.messages
+	return nil
+}
+
+// WatchFS reloads the messages of c with ReloadFS whenever the files of fsys matching pattern
+// change, are added or are removed, so that copy edits to error messages do not require a
+// redeploy. The files are polled every interval, one second when zero, until ctx is done. Reload
+// failures are reported to onError if not nil and leave the messages unchanged. WatchFS does not
+// load the files initially, call LoadFS or ReloadFS first:
+//
+//	fsys := os.DirFS("/etc/service/i18n")
+//	if err := catalog.ReloadFS(fsys, "*.json"); err != nil {
+//		log.Fatal(err)
+//	}
+//	catalog.WatchFS(ctx, fsys, "*.json", 10*time.Second, func(err error) { log.Print(err) })
+func (c *Catalog) WatchFS(ctx context.Context, fsys fs.FS, pattern string, interval time.Duration, onError func(error)) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	last, _ := catalogSnapshot(fsys, pattern)
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+			snap, err := catalogSnapshot(fsys, pattern)
+			if err == nil && snap == last {
+				continue
+			}
+			if err == nil {
+				err = c.ReloadFS(fsys, pattern)
+			}
+			if err != nil {
+				if onError != nil {
+					onError(err)
+				}
+				continue
+			}
+			last = snap
+		}
+	}()
+}
+
+// catalogSnapshot returns a string identifying the names, sizes and modification times of the
+// files of fsys matching pattern.
+func catalogSnapshot(fsys fs.FS, pattern string) (string, error) {
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for _, f := range files {
+		fi, err := fs.Stat(fsys, f)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(f)
+		sb.WriteByte(0)
+		sb.WriteString(fi.ModTime().UTC().Format(time.RFC3339Nano))
+		sb.WriteByte(0)
+		sb.WriteString(strconv.FormatInt(fi.Size(), 10))
+		sb.WriteByte('\n')
+	}
+	return sb.String(), nil
+}
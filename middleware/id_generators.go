@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"time"
+)
+
+const (
+	// crockfordAlphabet is the Crockford base32 alphabet used by ULIDs.
+	crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	// base62Alphabet is the alphabet used by KSUIDs.
+	base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	// ksuidEpoch is the Unix time of the KSUID epoch.
+	ksuidEpoch = 1400000000
+)
+
+// UUIDGenerator returns a generator of random (version 4) UUIDs in their canonical textual form,
+// e.g. "1b4e28ba-2fa1-41d2-883f-0016d3cca427".
+func UUIDGenerator() IDGenerator {
+	return IDGeneratorFunc(newUUID)
+}
+
+// ULIDGenerator returns a generator of ULIDs, 26 characters IDs that sort by creation time, e.g.
+// "01ARZ3NDEKTSV4RRFFQ69G5FAV".
+func ULIDGenerator() IDGenerator {
+	return IDGeneratorFunc(func() string {
+		var b [16]byte
+		ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+		b[0], b[1], b[2] = byte(ms>>40), byte(ms>>32), byte(ms>>24)
+		b[3], b[4], b[5] = byte(ms>>16), byte(ms>>8), byte(ms)
+		io.ReadFull(rand.Reader, b[6:])
+		return encodeBase(new(big.Int).SetBytes(b[:]), crockfordAlphabet, 26)
+	})
+}
+
+// KSUIDGenerator returns a generator of KSUIDs, 27 characters IDs that sort by creation time with
+// a second precision, e.g. "0ujtsYcgvSTl8PAuAdqWYSMnLOv".
+func KSUIDGenerator() IDGenerator {
+	return IDGeneratorFunc(func() string {
+		var b [20]byte
+		binary.BigEndian.PutUint32(b[:4], uint32(time.Now().Unix()-ksuidEpoch))
+		io.ReadFull(rand.Reader, b[4:])
+		return encodeBase(new(big.Int).SetBytes(b[:]), base62Alphabet, 27)
+	})
+}
+
+// PrefixedGenerator returns a generator of the IDs of g prefixed with prefix, e.g. "req_" to make
+// the kind of IDs obvious in logs.
+func PrefixedGenerator(prefix string, g IDGenerator) IDGenerator {
+	return IDGeneratorFunc(func() string {
+		return prefix + g.NewID()
+	})
+}
+
+// encodeBase encodes n with the given alphabet, left padded with its first character to size.
+func encodeBase(n *big.Int, alphabet string, size int) string {
+	s := make([]byte, size)
+	base := big.NewInt(int64(len(alphabet)))
+	mod := new(big.Int)
+	for i := size - 1; i >= 0; i-- {
+		n.DivMod(n, base, mod)
+		s[i] = alphabet[mod.Int64()]
+	}
+	return string(s)
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sort"
+)
+
+// IdentityExtractor returns the values identifying the client of the request req with context
+// ctx, e.g. the authenticated principal, the tenant or the API key ID, by name.
+type IdentityExtractor func(ctx context.Context, req *http.Request) map[string]interface{}
+
+// WithIdentityExtractor sets the function extracting the identity of the clients of failed
+// requests, its values are appended to the error log entries sorted by name so that multi-tenant
+// operators can attribute error spikes, for example:
+//
+//	middleware.WithIdentityExtractor(func(ctx context.Context, _ *http.Request) map[string]interface{} {
+//		return map[string]interface{}{"tenant": auth.Tenant(ctx), "principal": auth.Subject(ctx)}
+//	})
+//
+// The values are also added to the meta values of problems with WithIdentityInMeta.
+func WithIdentityExtractor(f IdentityExtractor) Option {
+	return func(o *options) {
+		o.identityExtractor = f
+	}
+}
+
+// WithIdentityInMeta sets whether the values returned by the identity extractor are added to the
+// meta values of problems, regardless of the verbosity. Meta values of the error take precedence
+// and the values go through the meta redaction, see WithMetaRedactPaths.
+func WithIdentityInMeta(enabled bool) Option {
+	return func(o *options) {
+		o.identityInMeta = enabled
+	}
+}
+
+// identity returns the identity of the client of req.
+func (o *options) identity(ctx context.Context, req *http.Request) map[string]interface{} {
+	if o.identityExtractor == nil {
+		return nil
+	}
+	return o.identityExtractor(ctx, req)
+}
+
+// identityFields returns the identity values as log key/value pairs sorted by name.
+func identityFields(identity map[string]interface{}) []interface{} {
+	if len(identity) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(identity))
+	for k := range identity {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	keyvals := make([]interface{}, 0, 2*len(names))
+	for _, k := range names {
+		keyvals = append(keyvals, k, identity[k])
+	}
+	return keyvals
+}
+
+// setIdentity adds the identity values to the meta values of problem if enabled.
+func (o *options) setIdentity(problem *Rfc7807Response, identity map[string]interface{}) {
+	if !o.identityInMeta {
+		return
+	}
+	for k, v := range identity {
+		if _, ok := problem.Meta[k]; !ok {
+			problem.setMeta(k, v)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// InstanceFunc returns the instance URI of problem sent in response to req, an empty string leaves
+// the instance unset.
+type InstanceFunc func(ctx context.Context, req *http.Request, problem *Rfc7807Response) string
+
+// WithInstanceFunc sets the function computing the Instance of problems that do not have one, see
+// InstancePath, InstanceRequestIDURL and InstanceTemplate. It takes precedence over
+// WithInstanceUUID which applies when f returns an empty string.
+func WithInstanceFunc(f InstanceFunc) Option {
+	return func(o *options) {
+		o.instanceFunc = f
+	}
+}
+
+// InstancePath returns an InstanceFunc that uses the escaped path of the request as instance.
+func InstancePath() InstanceFunc {
+	return func(_ context.Context, req *http.Request, _ *Rfc7807Response) string {
+		return req.URL.EscapedPath()
+	}
+}
+
+// InstanceRequestIDURL returns an InstanceFunc that appends the escaped request ID to base, e.g.
+// "https://example.com/errors/" gives "https://example.com/errors/Ab12Cd34". The trace ID of the
+// problem is used when the context has no request ID.
+func InstanceRequestIDURL(base string) InstanceFunc {
+	return func(ctx context.Context, _ *http.Request, problem *Rfc7807Response) string {
+		id := problemRequestID(ctx, problem)
+		if id == "" {
+			return ""
+		}
+		return base + url.PathEscape(id)
+	}
+}
+
+// InstanceTemplate returns an InstanceFunc that expands the placeholders "{path}", "{request_id}",
+// "{trace_id}" and "{status}" of tmpl, e.g. "/errors/{status}/{request_id}". The values are
+// escaped as path segments except for the path.
+func InstanceTemplate(tmpl string) InstanceFunc {
+	return func(ctx context.Context, req *http.Request, problem *Rfc7807Response) string {
+		return strings.NewReplacer(
+			"{path}", req.URL.EscapedPath(),
+			"{request_id}", url.PathEscape(problemRequestID(ctx, problem)),
+			"{trace_id}", url.PathEscape(problem.TraceID),
+			"{status}", strconv.Itoa(problem.Status),
+		).Replace(tmpl)
+	}
+}
+
+// setInstance sets the Instance of problem with the instance function if problem does not have an
+// instance already.
+func (o *options) setInstance(ctx context.Context, req *http.Request, problem *Rfc7807Response) {
+	if o.instanceFunc == nil || problem.Instance != "" {
+		return
+	}
+	problem.Instance = o.instanceFunc(ctx, req, problem)
+}
+
+// problemRequestID returns the request ID stored in ctx or the trace ID of problem.
+func problemRequestID(ctx context.Context, problem *Rfc7807Response) string {
+	if id, ok := RequestID(ctx); ok && id != "" {
+		return id
+	}
+	return problem.TraceID
+}
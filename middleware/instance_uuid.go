@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"regexp"
+)
+
+// uuidRegexp matches the canonical textual representation of UUIDs.
+var uuidRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// WithInstanceUUID sets the Instance of problems that do not have one to a "urn:uuid:" URN
+// unique to the occurrence. The UUID is produced by the ID generator set with WithIDGenerator if it
+// generates UUIDs, e.g. UUIDGenerator, and is a random version 4 UUID otherwise.
+func WithInstanceUUID(enabled bool) Option {
+	return func(o *options) {
+		o.instanceUUID = enabled
+	}
+}
+
+// setInstanceUUID sets the Instance of problem to a new "urn:uuid:" URN if enabled and problem does
+// not have an instance already.
+func (o *options) setInstanceUUID(problem *Rfc7807Response) {
+	if !o.instanceUUID || problem.Instance != "" {
+		return
+	}
+	var id string
+	if o.idGenerator != nil {
+		id = o.idGenerator.NewID()
+	}
+	if !uuidRegexp.MatchString(id) {
+		id = newUUID()
+	}
+	problem.Instance = "urn:uuid:" + id
+}
+
+// newUUID returns a random (version 4) UUID in its canonical textual form.
+func newUUID() string {
+	var b [16]byte
+	io.ReadFull(rand.Reader, b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:])
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+type (
+	// Interceptor observes and may modify problem responses before they are sent.
+	Interceptor interface {
+		// Intercept is called with the problem about to be sent in response to req.
+		Intercept(ctx context.Context, req *http.Request, pc *ProblemContext)
+	}
+
+	// InterceptorFunc is an adapter that allows using ordinary functions as interceptors.
+	InterceptorFunc func(ctx context.Context, req *http.Request, pc *ProblemContext)
+
+	// ProblemContext holds the mutable state of a problem response given to interceptors.
+	ProblemContext struct {
+		// Status is the HTTP status of the response, it is not kept in sync with Response.Status
+		// so that interceptors may send a status that differs from the problem one.
+		Status int
+		// Response is the problem sent in the response body, it must not be nil.
+		Response *Rfc7807Response
+		// Header contains the response headers.
+		Header http.Header
+	}
+)
+
+// Intercept calls f(ctx, req, pc).
+func (f InterceptorFunc) Intercept(ctx context.Context, req *http.Request, pc *ProblemContext) {
+	f(ctx, req, pc)
+}
+
+// WithInterceptors appends interceptors to the list of interceptors called before problems are
+// sent. Interceptors are called in the order they are registered, across all uses of the option,
+// and each interceptor sees the changes made by the previous ones. They run after the built-in
+// processing of the problem so their changes are final.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(o *options) {
+		o.interceptors = append(o.interceptors, interceptors...)
+	}
+}
+
+// intercept runs the interceptors and returns the resulting status and problem.
+func (o *options) intercept(ctx context.Context, req *http.Request, h http.Header, status int, problem *Rfc7807Response) (int, *Rfc7807Response) {
+	if len(o.interceptors) == 0 {
+		return status, problem
+	}
+	pc := &ProblemContext{Status: status, Response: problem, Header: h}
+	for _, i := range o.interceptors {
+		i.Intercept(ctx, req, pc)
+	}
+	if pc.Response == nil {
+		pc.Response = problem
+	}
+	return pc.Status, pc.Response
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// JSONAPIMediaIdentifier is the media type of JSON:API documents.
+const JSONAPIMediaIdentifier = "application/vnd.api+json"
+
+type (
+	// jsonAPIDocument is a JSON:API error document.
+	jsonAPIDocument struct {
+		Errors []jsonAPIError `json:"errors"`
+	}
+
+	// jsonAPIError is a JSON:API error object.
+	jsonAPIError struct {
+		ID     string                 `json:"id,omitempty"`
+		Links  *jsonAPILinks          `json:"links,omitempty"`
+		Status string                 `json:"status"`
+		Code   string                 `json:"code,omitempty"`
+		Title  string                 `json:"title,omitempty"`
+		Detail string                 `json:"detail,omitempty"`
+		Source *jsonAPISource         `json:"source,omitempty"`
+		Meta   map[string]interface{} `json:"meta,omitempty"`
+	}
+
+	// jsonAPILinks contains the links of a JSON:API error object.
+	jsonAPILinks struct {
+		About string `json:"about,omitempty"`
+		Type  string `json:"type,omitempty"`
+	}
+
+	// jsonAPISource identifies the source of a JSON:API error.
+	jsonAPISource struct {
+		Pointer   string `json:"pointer,omitempty"`
+		Parameter string `json:"parameter,omitempty"`
+	}
+)
+
+// WithJSONAPI registers EncodeJSONAPI as the serializer of the application/vnd.api+json media
+// type so that clients of services mixing JSON:API resources with goa get JSON:API error
+// documents. The problems go through the same processing as the other representations.
+func WithJSONAPI() Option {
+	return WithSerializer(JSONAPIMediaIdentifier, EncodeJSONAPI)
+}
+
+// EncodeJSONAPI writes problem to w as a JSON:API error document. The problem is rendered as one
+// error object with the trace ID as id, the type as type link, the instance as about link and the
+// meta values as meta. Validation problems are rendered as one error object per field error with
+// the JSON pointer of the field as source and aggregated problems as one error object per
+// aggregated error.
+func EncodeJSONAPI(w io.Writer, problem *Rfc7807Response) error {
+	base := jsonAPIError{
+		ID:     problem.TraceID,
+		Status: strconv.Itoa(problem.Status),
+		Code:   problem.Code,
+		Title:  problem.Title,
+		Detail: problem.Detail,
+	}
+	if problem.Type != "" || problem.Instance != "" {
+		base.Links = &jsonAPILinks{About: problem.Instance, Type: problem.Type}
+	}
+	meta := make(map[string]interface{}, len(problem.Meta))
+	for k, v := range problem.Meta {
+		meta[k] = v
+	}
+	var doc jsonAPIDocument
+	switch errs := problem.Meta[ValidationErrorsMetaKey].(type) {
+	case []FieldError:
+		delete(meta, ValidationErrorsMetaKey)
+		for _, fe := range errs {
+			e := base
+			e.Code, e.Detail = fe.Code, fe.Message
+			if fe.Pointer != "" {
+				e.Source = &jsonAPISource{Pointer: fe.Pointer}
+			} else {
+				e.Source = &jsonAPISource{Parameter: fe.Field}
+			}
+			doc.Errors = append(doc.Errors, e)
+		}
+	case []ErrorEntry:
+		delete(meta, ErrorsMetaKey)
+		for _, entry := range errs {
+			e := base
+			e.Status, e.Code, e.Detail = strconv.Itoa(entry.Status), entry.Code, entry.Detail
+			doc.Errors = append(doc.Errors, e)
+		}
+	}
+	if len(doc.Errors) == 0 {
+		doc.Errors = []jsonAPIError{base}
+	}
+	if len(meta) > 0 {
+		for i := range doc.Errors {
+			doc.Errors[i].Meta = meta
+		}
+	}
+	return json.NewEncoder(w).Encode(doc)
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// WithLatencyObserver sets a function called with the status of each problem response and the
+// time elapsed since the request started, for example to record an error latency histogram. The
+// start time is read from the context (see WithRequestStartTime) and defaults to the time the
+// request reached the handler.
+func WithLatencyObserver(f func(status int, d time.Duration)) Option {
+	return func(o *options) {
+		o.latencyObserver = f
+	}
+}
+
+// StatusClass returns the class of status, e.g. "4xx" for 404, suitable as metric label.
+func StatusClass(status int) string {
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// observeLatency calls the latency observer with the time elapsed since the request started.
+func (o *options) observeLatency(ctx context.Context, status int) {
+	if o.latencyObserver == nil {
+		return
+	}
+	if started, ok := RequestStartTime(ctx); ok {
+		o.latencyObserver(status, time.Since(started))
+	}
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/goadesign/goa"
+)
+
+type (
+	// LoadShedConfig configures the LoadShed middleware.
+	LoadShedConfig struct {
+		// MaxInFlight is the maximum number of requests processed concurrently, requests are
+		// not limited when zero.
+		MaxInFlight int
+		// MaxQueue is the maximum number of requests waiting for one of the requests in flight
+		// to complete, requests are rejected as soon as MaxInFlight is reached when zero.
+		MaxQueue int
+		// QueueTimeout is the maximum time requests wait in the queue, it defaults to one
+		// second.
+		QueueTimeout time.Duration
+		// RetryAfter is the delay sent in the Retry-After header of rejected requests, it
+		// defaults to one second.
+		RetryAfter time.Duration
+	}
+
+	// loadShedder counts the requests in flight and waiting.
+	loadShedder struct {
+		slots    chan struct{}
+		inFlight int64
+		queued   int64
+	}
+)
+
+// LoadShed returns a middleware that limits the number of requests processed concurrently and
+// rejects excess requests with a 503 Service Unavailable problem so that saturated services
+// answer quickly with structured errors instead of timing out. Requests arriving when
+// MaxInFlight requests are in flight wait in a queue of at most MaxQueue requests for at most
+// QueueTimeout, requests that do not fit in the queue or time out are rejected. The problem
+// contains the in_flight, max_in_flight, queued and max_queue meta values and is sent with the
+// Retry-After header when the middleware is registered after the Rfc7807Handler middleware.
+// Requests whose context is done while waiting fail with the context error.
+func LoadShed(c LoadShedConfig) goa.Middleware {
+	if c.QueueTimeout <= 0 {
+		c.QueueTimeout = time.Second
+	}
+	if c.RetryAfter <= 0 {
+		c.RetryAfter = time.Second
+	}
+	var l loadShedder
+	if c.MaxInFlight > 0 {
+		l.slots = make(chan struct{}, c.MaxInFlight)
+	}
+	return func(h goa.Handler) goa.Handler {
+		if c.MaxInFlight <= 0 {
+			return h
+		}
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			ok, err := l.acquire(ctx, c)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return RetryAfter(NewProblem(http.StatusServiceUnavailable).
+					Detail("The service is overloaded, retry later.").
+					Meta("in_flight", atomic.LoadInt64(&l.inFlight)).
+					Meta("max_in_flight", c.MaxInFlight).
+					Meta("queued", atomic.LoadInt64(&l.queued)).
+					Meta("max_queue", c.MaxQueue).
+					Err(), c.RetryAfter)
+			}
+			defer l.release()
+			return h(ctx, rw, req)
+		}
+	}
+}
+
+// acquire takes a slot for a request, waiting in the queue if there is room in it. It returns
+// false if the request must be rejected and the context error if ctx is done while waiting.
+func (l *loadShedder) acquire(ctx context.Context, c LoadShedConfig) (bool, error) {
+	select {
+	case l.slots <- struct{}{}:
+		atomic.AddInt64(&l.inFlight, 1)
+		return true, nil
+	default:
+	}
+	if atomic.AddInt64(&l.queued, 1) > int64(c.MaxQueue) {
+		atomic.AddInt64(&l.queued, -1)
+		return false, nil
+	}
+	defer atomic.AddInt64(&l.queued, -1)
+	t := time.NewTimer(c.QueueTimeout)
+	defer t.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		atomic.AddInt64(&l.inFlight, 1)
+		return true, nil
+	case <-t.C:
+		return false, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// release frees the slot of a request.
+func (l *loadShedder) release() {
+	atomic.AddInt64(&l.inFlight, -1)
+	<-l.slots
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"time"
+)
+
+// ClientErrorLogging configures the logging of the responses to client errors, see
+// WithClientErrorLogging.
+type ClientErrorLogging struct {
+	// Level is the level of the log entries, LevelInfo when LevelNone.
+	Level Level
+	// Statuses selects the 4xx statuses whose responses are logged, all of them when nil.
+	Statuses func(status int) bool
+	// Limit is the maximum number of client error entries logged per client and Window, the
+	// entries are not rate limited when 0.
+	Limit int
+	// Window is the window of Limit, one minute when zero.
+	Window time.Duration
+}
+
+// WithClientErrorLogging logs the responses to client errors selected by c at the level of c so
+// that teams can spot clients hammering endpoints with bad requests. The entries of client errors
+// contain the method, path and client IP of the request, see WithTrustedProxies, in addition to the
+// keys of the error entries. When c.Limit is set they are rate limited per client by that limit
+// only, independently of WithPerClientLogRateLimit, so that a misbehaving client cannot evict the
+// entries of internal errors. The level of a log level function set with WithLogLevelFunc takes
+// precedence.
+func WithClientErrorLogging(c ClientErrorLogging) Option {
+	return func(o *options) {
+		if c.Level == LevelNone {
+			c.Level = LevelInfo
+		}
+		o.clientErrorLogging = &c
+		if c.Limit > 0 {
+			o.clientErrorLimiter = &clientLogLimiter{
+				limit:   c.Limit,
+				window:  orMinute(c.Window),
+				lru:     list.New(),
+				clients: make(map[string]*list.Element),
+			}
+		}
+	}
+}
+
+// clientErrorLevel returns the level used to log the response to the client error with the given
+// status and true if client error logging selects it.
+func (o *options) clientErrorLevel(status int) (Level, bool) {
+	c := o.clientErrorLogging
+	if c == nil || status < 400 || status >= 500 {
+		return LevelNone, false
+	}
+	if c.Statuses != nil && !c.Statuses(status) {
+		return LevelNone, true
+	}
+	return c.Level, true
+}
+
+// allowErrorLog returns true if the log entry of the response with the given status may be
+// emitted. The entries of client errors are only limited by the client error limiter when there
+// is one, the other entries by the per client log rate limit.
+func (o *options) allowErrorLog(ctx context.Context, req *http.Request, status int) bool {
+	if o.clientErrorLimiter != nil && status >= 400 && status < 500 {
+		return o.allowClientErrorLog(ctx, req)
+	}
+	return o.allowLog(ctx, req)
+}
+
+// allowClientErrorLog returns true if the log entry of the response to a client error may be
+// emitted according to the client error limiter.
+func (o *options) allowClientErrorLog(ctx context.Context, req *http.Request) bool {
+	client := o.clientIP(req)
+	ok, dropped := o.clientErrorLimiter.allow(client, time.Now())
+	if dropped > 0 {
+		o.log(ctx, LevelInfo, "dropped client error logs", "client", client, "count", dropped)
+	}
+	return ok
+}
+
+// requestLogFields returns the method, path and client IP of req for the log entries of client
+// errors.
+func (o *options) requestLogFields(req *http.Request, status int) []interface{} {
+	if status < 400 || status >= 500 {
+		return nil
+	}
+	return []interface{}{"method", req.Method, "path", req.URL.Path, "from", o.clientIP(req)}
+}
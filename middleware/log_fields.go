@@ -0,0 +1,20 @@
+package middleware
+
+import "context"
+
+// WithContextLogFields sets a function returning key/value pairs appended to the error log entries
+// of the handler, for example to include the structured fields stored in the context by logging
+// middleware.
+func WithContextLogFields(f func(context.Context) []interface{}) Option {
+	return func(o *options) {
+		o.contextLogFields = f
+	}
+}
+
+// logFields returns the key/value pairs to append to log entries for the request context ctx.
+func (o *options) logFields(ctx context.Context) []interface{} {
+	if o.contextLogFields == nil {
+		return nil
+	}
+	return o.contextLogFields(ctx)
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/goadesign/goa"
+)
+
+// Level is the severity of a log entry.
+type Level int
+
+const (
+	// LevelNone disables logging.
+	LevelNone Level = iota
+	// LevelDebug is the level of debugging entries.
+	LevelDebug
+	// LevelInfo is the level of informational entries.
+	LevelInfo
+	// LevelWarn is the level of entries that may require attention.
+	LevelWarn
+	// LevelError is the level of error entries.
+	LevelError
+)
+
+// String returns the lowercase name of the level.
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return "none"
+}
+
+// WithLogLevelFunc sets the function that returns the level used to log error responses given
+// their status and the error returned by the handler. Responses are not logged when it returns
+// LevelNone. By default only 500 responses are logged, at the error level, see also
+// WithLogClientErrors.
+func WithLogLevelFunc(f func(status int, err error) Level) Option {
+	return func(o *options) {
+		o.logLevelFunc = f
+	}
+}
+
+// WithLogClientErrors also logs the responses to client errors, i.e. with a 4xx status, at the
+// info level when no log level function is set, see also WithClientErrorLogging.
+func WithLogClientErrors(enabled bool) Option {
+	return func(o *options) {
+		o.logClientErrors = enabled
+	}
+}
+
+// WithLeveledLogger sets the function used to write the log entries of the handler. By default
+// entries are written with goa.LogInfo for the debug and info levels and with goa.LogError for the
+// warn and error levels.
+func WithLeveledLogger(f func(ctx context.Context, level Level, msg string, keyvals ...interface{})) Option {
+	return func(o *options) {
+		o.leveledLogger = f
+	}
+}
+
+// Logger writes the structured log entries of the handler. keyvals alternates string keys and
+// values, the error entries contain the err, id, problem_detail, status, type, trace_id and
+// duration keys, the entries of client errors also contain the method, path and from keys and the
+// entries of all errors the identity values, see WithIdentityExtractor.
+type Logger interface {
+	// Log writes an entry with the given level and message.
+	Log(ctx context.Context, level Level, msg string, keyvals ...interface{})
+}
+
+// WithLogger writes the log entries of the handler with l in place of goa.LogInfo and
+// goa.LogError, see the adapters of the logging subpackages for zap, zerolog and logrus.
+func WithLogger(l Logger) Option {
+	return WithLeveledLogger(l.Log)
+}
+
+// logLevel returns the level used to log the response with the given status for err.
+func (o *options) logLevel(status int, err error) Level {
+	if o.logLevelFunc != nil {
+		return o.logLevelFunc(status, err)
+	}
+	if status == http.StatusInternalServerError {
+		return LevelError
+	}
+	if level, ok := o.clientErrorLevel(status); ok {
+		return level
+	}
+	if o.logClientErrors && status >= 400 && status < 500 {
+		return LevelInfo
+	}
+	return LevelNone
+}
+
+// log writes a log entry with the configured leveled logger.
+func (o *options) log(ctx context.Context, level Level, msg string, keyvals ...interface{}) {
+	if o.leveledLogger != nil {
+		o.leveledLogger(ctx, level, msg, keyvals...)
+		return
+	}
+	switch level {
+	case LevelDebug, LevelInfo:
+		goa.LogInfo(ctx, msg, keyvals...)
+	case LevelWarn, LevelError:
+		goa.LogError(ctx, msg, keyvals...)
+	}
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxRateLimitedClients is the number of clients tracked by the per client log rate limiter, the
+// least recently seen clients are evicted first.
+const maxRateLimitedClients = 10000
+
+type (
+	// clientLogLimiter caps the number of error log entries emitted per client, or per error
+	// fingerprint, and time window.
+	clientLogLimiter struct {
+		limit  int
+		window time.Duration
+
+		mu      sync.Mutex
+		lru     *list.List // *clientLogWindow values, most recently seen first
+		clients map[string]*list.Element
+	}
+
+	// clientLogWindow records the log entries of a client during the current window.
+	clientLogWindow struct {
+		client  string
+		start   time.Time
+		count   int
+		dropped int
+	}
+)
+
+// WithPerClientLogRateLimit limits the number of error log entries to n per client and window. The
+// client is identified by the remote address of the connection, or by the X-Forwarded-For header
+// for the requests coming from trusted proxies, see WithTrustedProxies. The number of entries
+// dropped during a window is logged when the client logs again after the window elapsed. Only the
+// most recently seen clients are tracked so memory usage stays bounded.
+func WithPerClientLogRateLimit(n int, window time.Duration) Option {
+	return func(o *options) {
+		o.logLimiter = &clientLogLimiter{
+			limit:   n,
+			window:  window,
+			lru:     list.New(),
+			clients: make(map[string]*list.Element),
+		}
+	}
+}
+
+// allowLog returns true if the error log entry for req may be emitted.
+func (o *options) allowLog(ctx context.Context, req *http.Request) bool {
+	if o.logLimiter == nil {
+		return true
+	}
+	client := o.clientIP(req)
+	ok, dropped := o.logLimiter.allow(client, time.Now())
+	if dropped > 0 {
+		o.log(ctx, LevelInfo, "dropped error logs", "client", client, "count", dropped)
+	}
+	return ok
+}
+
+// allow records a log entry for client and returns whether it is within the limit as well as the
+// number of entries dropped during the previous window of the client if it just ended.
+func (l *clientLogLimiter) allow(client string, now time.Time) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var w *clientLogWindow
+	if elem, ok := l.clients[client]; ok {
+		l.lru.MoveToFront(elem)
+		w = elem.Value.(*clientLogWindow)
+	} else {
+		w = &clientLogWindow{client: client, start: now}
+		l.clients[client] = l.lru.PushFront(w)
+		if l.lru.Len() > maxRateLimitedClients {
+			oldest := l.lru.Back()
+			l.lru.Remove(oldest)
+			delete(l.clients, oldest.Value.(*clientLogWindow).client)
+		}
+	}
+	var dropped int
+	if now.Sub(w.start) >= l.window {
+		dropped = w.dropped
+		w.start, w.count, w.dropped = now, 0, 0
+	}
+	if w.count >= l.limit {
+		w.dropped++
+		return false, dropped
+	}
+	w.count++
+	return true, dropped
+}
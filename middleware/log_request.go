@@ -22,12 +22,14 @@ import (
 func LogRequest(verbose bool) goa.Middleware {
 	return func(h goa.Handler) goa.Handler {
 		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
-			reqID := ctx.Value(reqIDKey)
-			if reqID == nil {
+			reqID, ok := RequestID(ctx)
+			if !ok {
 				reqID = shortID()
+				ctx = WithRequestID(ctx, reqID)
 			}
 			ctx = goa.WithLogContext(ctx, "req_id", reqID)
 			startedAt := time.Now()
+			ctx = WithRequestStartTime(ctx, startedAt)
 			r := goa.ContextRequest(ctx)
 			goa.LogInfo(ctx, "started", r.Method, r.URL.String(), "from", from(req),
 				"ctrl", goa.ContextController(ctx), "action", goa.ContextAction(ctx))
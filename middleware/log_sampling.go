@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// digitsRegexp matches the numbers of error messages, they are replaced in the default
+// fingerprints so that errors that only differ by IDs or durations share their fingerprint.
+var digitsRegexp = regexp.MustCompile(`[0-9]+`)
+
+// WithLogSampling limits the number of log entries of 5xx problems to n per error fingerprint and
+// window so that a failing dependency does not flood the logging pipeline with identical stack
+// traces. The number of entries dropped during a window is logged with the fingerprint when the
+// error is logged again after the window elapsed. fingerprint computes the signature of errors
+// given the status and the cause of the error, when nil the fingerprint is made of the status,
+// the type of the cause and its message with numbers removed. Only the most recently seen
+// fingerprints are tracked so memory usage stays bounded.
+func WithLogSampling(n int, window time.Duration, fingerprint func(status int, err error) string) Option {
+	return func(o *options) {
+		if fingerprint == nil {
+			fingerprint = defaultFingerprint
+		}
+		o.logFingerprint = fingerprint
+		o.logSampler = &clientLogLimiter{
+			limit:   n,
+			window:  window,
+			lru:     list.New(),
+			clients: make(map[string]*list.Element),
+		}
+	}
+}
+
+// sampleLog returns true if the log entry of the problem with the given status and error cause
+// may be emitted.
+func (o *options) sampleLog(ctx context.Context, status int, cause error) bool {
+	if o.logSampler == nil || status < 500 {
+		return true
+	}
+	fp := o.logFingerprint(status, cause)
+	ok, dropped := o.logSampler.allow(fp, time.Now())
+	if dropped > 0 {
+		o.log(ctx, LevelWarn, "suppressed error logs", "fingerprint", fp, "count", dropped)
+	}
+	return ok
+}
+
+// defaultFingerprint returns the status, the type of err and its message without numbers.
+func defaultFingerprint(status int, err error) string {
+	return fmt.Sprintf("%d %T %s", status, err, digitsRegexp.ReplaceAllString(err.Error(), "#"))
+}
@@ -0,0 +1,46 @@
+// Package logrus writes the log entries of the RFC 7807 middleware with logrus
+// (github.com/sirupsen/logrus).
+package logrus
+
+import (
+	"context"
+	"fmt"
+
+	sirupsen "github.com/sirupsen/logrus"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+// logger adapts a logrus logger to middleware.Logger.
+type logger struct {
+	l sirupsen.FieldLogger
+}
+
+// Logger returns a middleware.Logger writing entries with l, for example a *logrus.Logger or a
+// *logrus.Entry, the key/value pairs are written as fields.
+func Logger(l sirupsen.FieldLogger) middleware.Logger {
+	return &logger{l: l}
+}
+
+// Log implements middleware.Logger.
+func (a *logger) Log(_ context.Context, level middleware.Level, msg string, keyvals ...interface{}) {
+	fields := make(sirupsen.Fields, (len(keyvals)+1)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		var v interface{}
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		fields[fmt.Sprint(keyvals[i])] = v
+	}
+	entry := a.l.WithFields(fields)
+	switch level {
+	case middleware.LevelDebug:
+		entry.Debug(msg)
+	case middleware.LevelInfo:
+		entry.Info(msg)
+	case middleware.LevelWarn:
+		entry.Warn(msg)
+	default:
+		entry.Error(msg)
+	}
+}
@@ -0,0 +1,53 @@
+// Package zap writes the log entries of the RFC 7807 middleware with zap (go.uber.org/zap).
+package zap
+
+import (
+	"context"
+	"fmt"
+
+	uberzap "go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+// logger adapts a zap logger to middleware.Logger.
+type logger struct {
+	l *uberzap.Logger
+}
+
+// Logger returns a middleware.Logger writing entries with l, the key/value pairs are written as
+// zap fields.
+func Logger(l *uberzap.Logger) middleware.Logger {
+	return &logger{l: l}
+}
+
+// Log implements middleware.Logger.
+func (a *logger) Log(_ context.Context, level middleware.Level, msg string, keyvals ...interface{}) {
+	ce := a.l.Check(zapLevel(level), msg)
+	if ce == nil {
+		return
+	}
+	fields := make([]zapcore.Field, 0, (len(keyvals)+1)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		var v interface{}
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		fields = append(fields, uberzap.Any(fmt.Sprint(keyvals[i]), v))
+	}
+	ce.Write(fields...)
+}
+
+// zapLevel returns the zap level corresponding to level.
+func zapLevel(level middleware.Level) zapcore.Level {
+	switch level {
+	case middleware.LevelDebug:
+		return zapcore.DebugLevel
+	case middleware.LevelInfo:
+		return zapcore.InfoLevel
+	case middleware.LevelWarn:
+		return zapcore.WarnLevel
+	}
+	return zapcore.ErrorLevel
+}
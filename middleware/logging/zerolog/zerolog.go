@@ -0,0 +1,40 @@
+// Package zerolog writes the log entries of the RFC 7807 middleware with zerolog
+// (github.com/rs/zerolog).
+package zerolog
+
+import (
+	"context"
+
+	rszerolog "github.com/rs/zerolog"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+// logger adapts a zerolog logger to middleware.Logger.
+type logger struct {
+	l rszerolog.Logger
+}
+
+// Logger returns a middleware.Logger writing entries with l, the key/value pairs are written as
+// event fields.
+func Logger(l rszerolog.Logger) middleware.Logger {
+	return &logger{l: l}
+}
+
+// Log implements middleware.Logger.
+func (a *logger) Log(_ context.Context, level middleware.Level, msg string, keyvals ...interface{}) {
+	a.l.WithLevel(zerologLevel(level)).Fields(keyvals).Msg(msg)
+}
+
+// zerologLevel returns the zerolog level corresponding to level.
+func zerologLevel(level middleware.Level) rszerolog.Level {
+	switch level {
+	case middleware.LevelDebug:
+		return rszerolog.DebugLevel
+	case middleware.LevelInfo:
+		return rszerolog.InfoLevel
+	case middleware.LevelWarn:
+		return rszerolog.WarnLevel
+	}
+	return rszerolog.ErrorLevel
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goadesign/goa"
+)
+
+// MaintenanceType is the type of the problems of the requests rejected during maintenance, a
+// relative reference resolved against the base set with WithTypeBaseURI.
+const MaintenanceType = "maintenance"
+
+// MaintenanceEndMetaKey is the meta key of the planned end of maintenance, an RFC 3339 time.
+const MaintenanceEndMetaKey = "maintenance_end"
+
+type (
+	// MaintenanceFunc returns whether req must be rejected because the service is in
+	// maintenance and the planned end of the maintenance, the zero time if unknown. Functions
+	// may let some requests through during maintenance, e.g. health checks or requests from
+	// operators.
+	MaintenanceFunc func(req *http.Request) (bool, time.Time)
+
+	// MaintenanceFlag is a maintenance switch toggled at runtime, e.g. by an admin endpoint or on
+	// a signal. The zero value is disabled, it is safe for concurrent use.
+	MaintenanceFlag struct {
+		mu      sync.RWMutex
+		enabled bool
+		end     time.Time
+	}
+)
+
+// Maintenance returns a middleware that rejects the requests for which check returns true with
+// a 503 Service Unavailable problem of type MaintenanceType so that operators can put services in
+// maintenance without a proxy rule. When the planned end is known and in the future, it is sent
+// in the maintenance_end meta value and, when the middleware is registered after the
+// Rfc7807Handler middleware, as the Retry-After header. For example:
+//
+//	var maintenance middleware.MaintenanceFlag
+//	service.Use(middleware.Rfc7807HandlerWithOptions(service))
+//	service.Use(middleware.Maintenance(maintenance.Check))
+//	...
+//	maintenance.Enable(time.Now().Add(30 * time.Minute))
+func Maintenance(check MaintenanceFunc) goa.Middleware {
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			on, end := check(req)
+			if !on {
+				return h(ctx, rw, req)
+			}
+			b := NewProblem(http.StatusServiceUnavailable).
+				Type(MaintenanceType).
+				Title("Service under maintenance").
+				Detail("The service is under maintenance, retry later.")
+			wait := time.Until(end)
+			if end.IsZero() || wait <= 0 {
+				return b.Err()
+			}
+			b.Meta(MaintenanceEndMetaKey, end.UTC().Format(time.RFC3339))
+			return RetryAfter(b.Err(), wait)
+		}
+	}
+}
+
+// Enable puts the service in maintenance until end, the zero time if unknown. The flag is not
+// disabled automatically once end is past.
+func (f *MaintenanceFlag) Enable(end time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.enabled = true
+	f.end = end
+}
+
+// Disable ends the maintenance.
+func (f *MaintenanceFlag) Disable() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.enabled = false
+	f.end = time.Time{}
+}
+
+// Check is a MaintenanceFunc rejecting all requests while the flag is enabled.
+func (f *MaintenanceFlag) Check(*http.Request) (bool, time.Time) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.enabled, f.end
+}
+
+// MaintenanceFile returns a MaintenanceFunc rejecting all requests while the file with the given
+// name exists, e.g. a file created by deployment scripts. The file may contain the planned end of
+// the maintenance as an RFC 3339 time, other contents are ignored. The file is checked at most
+// once per second.
+func MaintenanceFile(name string) MaintenanceFunc {
+	var (
+		mu      sync.Mutex
+		checked time.Time
+		on      bool
+		end     time.Time
+	)
+	return func(*http.Request) (bool, time.Time) {
+		mu.Lock()
+		defer mu.Unlock()
+		if now := time.Now(); now.Sub(checked) >= time.Second {
+			checked = now
+			b, err := ioutil.ReadFile(name)
+			on, end = err == nil, time.Time{}
+			if on {
+				end, _ = time.Parse(time.RFC3339, strings.TrimSpace(string(b)))
+			}
+		}
+		return on, end
+	}
+}
@@ -0,0 +1,112 @@
+// Package sqlerrors maps common database failures to problems. It recognizes sql.ErrNoRows, the
+// constraint violations and transient failures of PostgreSQL drivers exposing the SQLSTATE code of
+// errors with a SQLState() string method, such as github.com/jackc/pgx and github.com/lib/pq, and
+// the errors of the MySQL driver github.com/go-sql-driver/mysql.
+package sqlerrors
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+// Problem types relative to the base given to Mapper.
+const (
+	// TypeNotFound is the type of the problems of queries that returned no rows.
+	TypeNotFound = "not-found"
+	// TypeUniqueViolation is the type of the problems of unique constraint violations.
+	TypeUniqueViolation = "unique-violation"
+	// TypeForeignKeyViolation is the type of the problems of foreign key constraint violations.
+	TypeForeignKeyViolation = "foreign-key-violation"
+	// TypeSerializationFailure is the type of the problems of transactions aborted by
+	// serialization failures or deadlocks, they may be retried.
+	TypeSerializationFailure = "serialization-failure"
+	// TypeUnavailable is the type of the problems of database connection failures.
+	TypeUnavailable = "database-unavailable"
+)
+
+// failure describes a class of database failures.
+type failure struct {
+	typ    string
+	status int
+	detail string
+}
+
+var (
+	notFound             = failure{TypeNotFound, http.StatusNotFound, "The requested resource does not exist."}
+	uniqueViolation      = failure{TypeUniqueViolation, http.StatusConflict, "The resource conflicts with an existing one."}
+	foreignKeyViolation  = failure{TypeForeignKeyViolation, http.StatusConflict, "The resource references or is referenced by other resources."}
+	serializationFailure = failure{TypeSerializationFailure, http.StatusServiceUnavailable, "The request conflicted with a concurrent request, retry it."}
+	unavailable          = failure{TypeUnavailable, http.StatusServiceUnavailable, "The database is unavailable."}
+)
+
+// sqlStates maps SQLSTATE codes to failures.
+var sqlStates = map[string]failure{
+	"23505": uniqueViolation,
+	"23503": foreignKeyViolation,
+	"40001": serializationFailure,
+	"40P01": serializationFailure,
+	"08000": unavailable,
+	"08003": unavailable,
+	"08006": unavailable,
+	"53300": unavailable,
+	"57P03": unavailable,
+}
+
+// mysqlErrors maps MySQL error numbers to failures.
+var mysqlErrors = map[uint16]failure{
+	1062: uniqueViolation,
+	1451: foreignKeyViolation,
+	1452: foreignKeyViolation,
+	1205: serializationFailure,
+	1213: serializationFailure,
+	1040: unavailable,
+}
+
+// Mapper returns a middleware.ErrorMapper translating database failures into 404, 409 and 503
+// problems whose type is one of the types of this package resolved against base, e.g.
+// "https://example.com/probs/" produces "https://example.com/probs/unique-violation". The detail of
+// the problems is generic so that database messages, which may contain data and schema names, are
+// not sent to clients. Register it with middleware.RegisterErrorMapper or
+// middleware.WithErrorMappers.
+func Mapper(base string) middleware.ErrorMapper {
+	return func(_ context.Context, err error) (*middleware.Rfc7807Response, bool) {
+		f, ok := classify(err)
+		if !ok {
+			return nil, false
+		}
+		return &middleware.Rfc7807Response{
+			Type:   base + f.typ,
+			Title:  http.StatusText(f.status),
+			Status: f.status,
+			Detail: f.detail,
+		}, true
+	}
+}
+
+// classify returns the failure err belongs to.
+func classify(err error) (failure, bool) {
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound, true
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return unavailable, true
+	}
+	var se interface{ SQLState() string }
+	if errors.As(err, &se) {
+		f, ok := sqlStates[se.SQLState()]
+		return f, ok
+	}
+	var me *mysql.MySQLError
+	if errors.As(err, &me) {
+		f, ok := mysqlErrors[me.Number]
+		return f, ok
+	}
+	return failure{}, false
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import "net/http"
+
+// MaskProblem returns a copy of problem masked like the handler configured with opts masks the
+// problems it sends, for the transports rendering problems without the handler such as gRPC. The
+// PII scrubber and the redactions are applied and, unless verbose is true, the detail of internal
+// errors is replaced with the status text followed by the trace ID, their code is cleared and
+// their meta values that are not public are dropped, see WithMetaExposure. The meta values are
+// finally redacted, see WithMetaRedactPaths.
+func MaskProblem(problem *Rfc7807Response, verbose bool, opts ...Option) *Rfc7807Response {
+	o := newOptions(opts...)
+	masked := *problem
+	if problem.Meta != nil {
+		masked.Meta = make(map[string]interface{}, len(problem.Meta))
+		for k, v := range problem.Meta {
+			// The scrubber alters the elements of these slices in place.
+			switch actual := v.(type) {
+			case []FieldError:
+				v = append([]FieldError(nil), actual...)
+			case []ErrorEntry:
+				v = append([]ErrorEntry(nil), actual...)
+			}
+			masked.Meta[k] = v
+		}
+	}
+	o.scrubProblem(&masked)
+	if !verbose && masked.Status == http.StatusInternalServerError {
+		o.maskProblem(&masked, masked.Status, masked.TraceID)
+	}
+	o.redactMeta(&masked)
+	return &masked
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import "sort"
+
+// MetaExposure is the audience of a meta value, see WithMetaExposure.
+type MetaExposure int
+
+const (
+	// MetaInternal values are only sent when the details of the problem are, e.g. in verbose
+	// mode or to trusted callers, this is the default.
+	MetaInternal MetaExposure = iota
+	// MetaPublic values are safe for any client and are sent even when the details of the
+	// problem are not, e.g. hints on how to fix the request.
+	MetaPublic
+	// MetaLogOnly values are never sent, they are added to the log entry of the error as
+	// "meta.<key>" fields for operators.
+	MetaLogOnly
+)
+
+// WithMetaExposure sets the exposure of the meta values with the given keys so that a single meta
+// map can carry both client-safe hints and operator-only diagnostics. By default meta values are
+// MetaInternal: they are all dropped when the details of a problem are not sent. The meta values
+// added by the handler itself, such as support_code, are sent regardless of the verbosity unless
+// their key is MetaLogOnly.
+func WithMetaExposure(exposure MetaExposure, keys ...string) Option {
+	return func(o *options) {
+		if o.metaExposure == nil {
+			o.metaExposure = make(map[string]MetaExposure, len(keys))
+		}
+		for _, k := range keys {
+			o.metaExposure[k] = exposure
+		}
+	}
+}
+
+// publicMeta returns the public values of meta, nil if there is none.
+func (o *options) publicMeta(meta map[string]interface{}) map[string]interface{} {
+	var public map[string]interface{}
+	for k, v := range meta {
+		if o.metaExposure[k] != MetaPublic {
+			continue
+		}
+		if public == nil {
+			public = make(map[string]interface{})
+		}
+		public[k] = v
+	}
+	return public
+}
+
+// logOnlyMetaFields returns the log fields of the log-only meta values of problem sorted by key.
+func (o *options) logOnlyMetaFields(problem *Rfc7807Response) []interface{} {
+	if len(o.metaExposure) == 0 {
+		return nil
+	}
+	var keys []string
+	for k := range problem.Meta {
+		if o.metaExposure[k] == MetaLogOnly {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	keyvals := make([]interface{}, 0, 2*len(keys))
+	for _, k := range keys {
+		keyvals = append(keyvals, "meta."+k, problem.Meta[k])
+	}
+	return keyvals
+}
+
+// dropLogOnlyMeta removes the log-only meta values of problem. The meta map is copied so that the
+// meta values of the original error are left untouched.
+func (o *options) dropLogOnlyMeta(problem *Rfc7807Response) {
+	if len(o.metaExposure) == 0 || len(problem.Meta) == 0 {
+		return
+	}
+	meta := make(map[string]interface{}, len(problem.Meta))
+	for k, v := range problem.Meta {
+		if o.metaExposure[k] != MetaLogOnly {
+			meta[k] = v
+		}
+	}
+	if len(meta) == 0 {
+		meta = nil
+	}
+	problem.Meta = meta
+}
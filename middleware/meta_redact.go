@@ -0,0 +1,64 @@
+package middleware
+
+import "strings"
+
+// redactedValue replaces the redacted meta values.
+const redactedValue = "[REDACTED]"
+
+// WithMetaRedactPaths redacts the problem meta values found at the given dot separated paths, for
+// example "user.credentials.token" redacts the "token" value of the "credentials" map nested in
+// the "user" map. Paths that do not exist are ignored. The maps along redacted paths are copied so
+// that the meta values of the original error are left untouched.
+func WithMetaRedactPaths(paths ...string) Option {
+	return func(o *options) {
+		for _, p := range paths {
+			o.metaRedactPaths = append(o.metaRedactPaths, strings.Split(p, "."))
+		}
+	}
+}
+
+// redactMeta redacts the configured meta paths of problem.
+func (o *options) redactMeta(problem *Rfc7807Response) {
+	for _, path := range o.metaRedactPaths {
+		if m, ok := redactPath(problem.Meta, path); ok {
+			problem.Meta = m
+		}
+	}
+}
+
+// redactPath returns a copy of m where the value at path is replaced with redactedValue and true,
+// or false if path does not exist in m.
+func redactPath(m map[string]interface{}, path []string) (map[string]interface{}, bool) {
+	v, ok := m[path[0]]
+	if !ok {
+		return nil, false
+	}
+	if len(path) > 1 {
+		switch nested := v.(type) {
+		case map[string]interface{}:
+			if v, ok = redactPath(nested, path[1:]); !ok {
+				return nil, false
+			}
+		case map[string]string:
+			if _, ok := nested[path[1]]; !ok || len(path) > 2 {
+				return nil, false
+			}
+			c := make(map[string]string, len(nested))
+			for k, s := range nested {
+				c[k] = s
+			}
+			c[path[1]] = redactedValue
+			v = c
+		default:
+			return nil, false
+		}
+	} else {
+		v = redactedValue
+	}
+	c := make(map[string]interface{}, len(m))
+	for k, val := range m {
+		c[k] = val
+	}
+	c[path[0]] = v
+	return c, true
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// AllowedMethodsMetaKey is the meta key of the methods allowed by 405 problems.
+const AllowedMethodsMetaKey = "allowed_methods"
+
+// allowedMethodsError is an error wrapped with the methods allowed by the target resource.
+type allowedMethodsError struct {
+	wrappedError
+	methods []string
+}
+
+// AllowedMethods wraps err with the methods allowed by the target resource. The handler sends them
+// in the Allow header and in the allowed_methods meta value of 405 Method Not Allowed problems.
+// The methods of errors produced by the goa mux are read from their "allowed" meta value or from
+// the Allow header set by the mux.
+func AllowedMethods(err error, methods ...string) error {
+	return &allowedMethodsError{wrappedError: wrappedError{err}, methods: methods}
+}
+
+// AllowedMethods returns the allowed methods.
+func (e *allowedMethodsError) AllowedMethods() []string {
+	return e.methods
+}
+
+// WithMethodNotAllowedDetail makes 405 problems list the allowed methods in their detail and in
+// the Allow header. The allowed methods are those given to AllowedMethods or the "allowed" meta
+// value of the error, as set by goa.MethodNotAllowedError, which may be a comma separated string
+// or a slice of strings.
+func WithMethodNotAllowedDetail(enabled bool) Option {
+	return func(o *options) {
+		o.methodNotAllowedDetail = enabled
+	}
+}
+
+// describeMethodNotAllowed sets the Allow header and the detail of 405 problems.
+func (o *options) describeMethodNotAllowed(h http.Header, req *http.Request, e error, problem *Rfc7807Response) {
+	if !o.methodNotAllowedDetail || problem.Status != http.StatusMethodNotAllowed {
+		return
+	}
+	allowed := o.permittedMethods(h, e, problem)
+	if len(allowed) == 0 {
+		return
+	}
+	list := strings.Join(allowed, ", ")
+	h.Set("Allow", list)
+	problem.Detail = fmt.Sprintf("Method %s not allowed; allowed: %s", req.Method, list)
+}
+
+// setAllowedMethods sets the Allow header and the allowed_methods meta value of 405 problems.
+func (o *options) setAllowedMethods(h http.Header, e error, problem *Rfc7807Response) {
+	if problem.Status != http.StatusMethodNotAllowed {
+		return
+	}
+	allowed := o.permittedMethods(h, e, problem)
+	if len(allowed) == 0 {
+		return
+	}
+	h.Set("Allow", strings.Join(allowed, ", "))
+	problem.setMeta(AllowedMethodsMetaKey, allowed)
+}
+
+// permittedMethods returns the methods carried by the chain of errors wrapped by e, listed in the
+// "allowed" meta value of problem or in the Allow header h in this order of precedence.
+func (o *options) permittedMethods(h http.Header, e error, problem *Rfc7807Response) []string {
+	unwrap := o.unwrap()
+	for err := e; err != nil; err = unwrap(err) {
+		if a, ok := err.(interface{ AllowedMethods() []string }); ok {
+			return allowedMethods(a.AllowedMethods())
+		}
+	}
+	if allowed := allowedMethods(problem.Meta["allowed"]); len(allowed) > 0 {
+		return allowed
+	}
+	return allowedMethods(h.Get("Allow"))
+}
+
+// allowedMethods returns the methods listed in v.
+func allowedMethods(v interface{}) []string {
+	var methods []string
+	switch a := v.(type) {
+	case string:
+		methods = strings.Split(a, ",")
+	case []string:
+		methods = a
+	case []interface{}:
+		for _, m := range a {
+			methods = append(methods, fmt.Sprintf("%v", m))
+		}
+	}
+	res := make([]string, 0, len(methods))
+	for _, m := range methods {
+		if m = strings.ToUpper(strings.TrimSpace(m)); m != "" {
+			res = append(res, m)
+		}
+	}
+	return res
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/goadesign/goa"
+)
+
+// ProblemLabels describes a problem response for metrics.
+type ProblemLabels struct {
+	// Status is the HTTP status of the response.
+	Status int
+	// Type is the problem type, "about:blank" for problems without type.
+	Type string
+	// Controller is the name of the goa controller that handled the request, "<unknown>" outside
+	// of goa controllers.
+	Controller string
+	// Action is the name of the goa action that handled the request, "<unknown>" outside of goa
+	// actions.
+	Action string
+	// BodySize is the size in bytes of the serialized problem body, 0 when no body was written.
+	// It is not meant to be used as a label but as the value of a size histogram.
+	BodySize int
+	// BurnsBudget is true if the problem burns the error budget of the service according to the
+	// SLO reporter of the handler, false without reporter, see WithSLOReporter.
+	BurnsBudget bool
+}
+
+// WithMetricsHook sets a function called with the labels of each problem response sent, for
+// example to increment an error counter, see the prometheus subpackage for a ready-made
+// collector.
+func WithMetricsHook(f func(ctx context.Context, labels ProblemLabels)) Option {
+	return func(o *options) {
+		o.metricsHook = f
+	}
+}
+
+// recordMetrics records the problem sent with the given status and body size in the SLO reporter
+// and calls the metrics hook.
+func (o *options) recordMetrics(ctx context.Context, status int, problem *Rfc7807Response, size int) {
+	if o.metricsHook == nil && o.sloReporter == nil {
+		return
+	}
+	typ := problem.Type
+	if typ == "" {
+		typ = BlankProblemType
+	}
+	labels := ProblemLabels{
+		Status:     status,
+		Type:       typ,
+		Controller: goa.ContextController(ctx),
+		Action:     goa.ContextAction(ctx),
+		BodySize:   size,
+	}
+	if o.sloReporter != nil {
+		labels.BurnsBudget = o.sloReporter.record(labels, o.now())
+	}
+	if o.metricsHook != nil {
+		o.metricsHook(ctx, labels)
+	}
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/goadesign/goa"
+)
+
+// ErrorsMetaKey is the meta key of the constituent errors of aggregated problems, it is the same
+// key as ValidationErrorsMetaKey.
+const ErrorsMetaKey = ValidationErrorsMetaKey
+
+// ErrorEntry describes one of the errors aggregated into a problem, see WithMultiErrors.
+type ErrorEntry struct {
+	// Status is the HTTP status of the error.
+	Status int `json:"status" xml:"status" form:"status"`
+	// Code is the code of goa errors, the type of problems or the token of other service errors.
+	Code string `json:"code,omitempty" xml:"code,omitempty" form:"code,omitempty"`
+	// Detail describes the error.
+	Detail string `json:"detail,omitempty" xml:"detail,omitempty" form:"detail,omitempty"`
+}
+
+// WithMultiErrors makes the handler aggregate the errors bundled in multi-errors such as the
+// results of errors.Join or hashicorp/go-multierror errors, that is errors implementing
+// Unwrap() []error or WrappedErrors() []error. The problem gets the highest status of the
+// constituents and lists each of them in the "errors" meta value instead of the concatenation of
+// their messages in its detail. Errors that are neither goa.ServiceError errors nor mapped by
+// error mappers are reported as internal errors without detail, like in non verbose mode.
+func WithMultiErrors(enabled bool) Option {
+	return func(o *options) {
+		o.multiErrors = enabled
+	}
+}
+
+// aggregate returns the problem aggregating the errors bundled in the chain of errors wrapped by
+// e if multi-errors are enabled and e bundles more than one error.
+func (o *options) aggregate(ctx context.Context, e error) (*Rfc7807Response, bool) {
+	if !o.multiErrors {
+		return nil, false
+	}
+	errs := bundled(e, o.unwrap())
+	if len(errs) < 2 {
+		return nil, false
+	}
+	entries := make([]ErrorEntry, len(errs))
+	status := 0
+	for i, err := range errs {
+		entries[i] = o.errorEntry(ctx, err)
+		if entries[i].Status > status {
+			status = entries[i].Status
+		}
+	}
+	problem := &Rfc7807Response{
+		Status: status,
+		Detail: fmt.Sprintf("%d errors occurred", len(errs)),
+	}
+	problem.setMeta(ErrorsMetaKey, entries)
+	return problem, true
+}
+
+// errorEntry describes err.
+func (o *options) errorEntry(ctx context.Context, err error) ErrorEntry {
+	if se, ok := cause(err, o.unwrap()).(goa.ServiceError); ok {
+		entry := ErrorEntry{Status: se.ResponseStatus(), Code: errorCode(se), Detail: se.Error()}
+		if resp, ok := se.(*goa.ErrorResponse); ok {
+			entry.Detail = resp.Detail
+		}
+		return entry
+	}
+	if mapped, ok := o.mapError(ctx, err); ok {
+		return ErrorEntry{Status: mapped.Status, Code: mapped.Type, Detail: mapped.Detail}
+	}
+	return ErrorEntry{
+		Status: http.StatusInternalServerError,
+		Detail: http.StatusText(http.StatusInternalServerError),
+	}
+}
+
+// bundled returns the errors bundled by the first multi-error of the chain of errors wrapped by e.
+// The chain is not searched past goa.ServiceError errors.
+func bundled(e error, unwrap func(error) error) []error {
+	for ; e != nil; e = unwrap(e) {
+		switch m := e.(type) {
+		case goa.ServiceError:
+			return nil
+		case interface{ WrappedErrors() []error }:
+			return m.WrappedErrors()
+		case interface{ Unwrap() []error }:
+			return m.Unwrap()
+		}
+	}
+	return nil
+}
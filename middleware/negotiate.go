@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// problemMediaTypes lists the canonical media types of the problem representations produced by
+// the handler, the first one is used when the request does not accept any of them.
+var problemMediaTypes = []string{Rfc7807JsonMediaIdentifier, Rfc7807XmlMediaIdentifier}
+
+// WithSerializer registers a serializer for problems of the given media type. The media type is
+// added to the set negotiated with the Accept request header after the built-in problem media
+// types. Registering a serializer for a built-in media type overrides the default serialization.
+func WithSerializer(mediaType string, f func(w io.Writer, problem *Rfc7807Response) error) Option {
+	return func(o *options) {
+		if mt, _, err := mime.ParseMediaType(mediaType); err == nil {
+			mediaType = mt
+		}
+		if o.serializers == nil {
+			o.serializers = make(map[string]func(io.Writer, *Rfc7807Response) error)
+		}
+		if _, ok := o.serializers[mediaType]; !ok && !isProblemMediaType(mediaType) {
+			o.customMediaTypes = append(o.customMediaTypes, mediaType)
+		}
+		o.serializers[mediaType] = f
+	}
+}
+
+// WithDefaultFormat sets the media type of the problems sent in response to requests without an
+// Accept header, it defaults to the first supported media type (problem+json).
+func WithDefaultFormat(mediaType string) Option {
+	return func(o *options) {
+		o.defaultFormat = strings.ToLower(mediaType)
+	}
+}
+
+// WithFallbackFormat sets the media type of the problems sent in response to requests whose Accept
+// header does not match any supported media type, it defaults to the first supported media type
+// (problem+json). This is distinct from the default format used when there is no Accept header at
+// all.
+func WithFallbackFormat(mediaType string) Option {
+	return func(o *options) {
+		o.fallbackFormat = strings.ToLower(mediaType)
+	}
+}
+
+// mediaTypes returns the media types the handler can produce, the first one is the default.
+func (o *options) mediaTypes() []string {
+	return append(append([]string{}, problemMediaTypes...), o.customMediaTypes...)
+}
+
+// acceptRange is a media range of an Accept header with its weight.
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// negotiateMediaType returns the media type to use for a request with the given Accept header
+// value. Each supported media type gets the weight of the most specific media range matching it,
+// an exact match being more specific than a structured syntax suffix match, e.g.
+// "application/json" for "application/problem+json", which is more specific than a subtype
+// wildcard and than "*/*". The media type with the highest weight is returned, ties are broken by
+// specificity and then by the order of mediaTypes, and media types whose weight is 0 are never
+// returned. Media types and their parameters are compared case-insensitively so that mangled
+// headers such as "Application/Problem+JSON" are honored, the returned value is always one of
+// the canonical lowercase media types returned by mediaTypes, the vendor media types configured
+// with WithVendorMediaType match their canonical media type. The default format is returned when
+// accept is empty and the fallback format when no media type matches or when HTML is preferred to
+// the matching media types but no HTML template is configured, so that browsers, which accept
+// application/xml with a lower weight, do not get XML problems, see WithHTMLTemplate.
+func (o *options) negotiateMediaType(accept string) string {
+	types := o.mediaTypes()
+	if strings.TrimSpace(accept) == "" {
+		return configuredMediaType(o.defaultFormat, types)
+	}
+	ranges := parseAccept(accept)
+	var (
+		best            string
+		bestQ, bestSpec float64
+	)
+	for _, t := range types {
+		q, spec := -1.0, 0
+		for _, r := range ranges {
+			s := mediaTypeSpecificity(r.mediaType, t)
+			if vt, ok := o.vendorMediaTypes[t]; ok {
+				if vs := mediaTypeSpecificity(r.mediaType, vt); vs > s {
+					s = vs
+				}
+			}
+			if s > spec {
+				q, spec = r.q, s
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		if q > bestQ || (q == bestQ && float64(spec) > bestSpec) {
+			best, bestQ, bestSpec = t, q, float64(spec)
+		}
+	}
+	if best == "" || (!o.hasMediaType(HTMLMediaIdentifier) && htmlWeight(ranges) > bestQ) {
+		return configuredMediaType(o.fallbackFormat, types)
+	}
+	return best
+}
+
+// hasMediaType returns true if the handler can produce problems of the media type t.
+func (o *options) hasMediaType(t string) bool {
+	for _, mt := range o.mediaTypes() {
+		if mt == t {
+			return true
+		}
+	}
+	return false
+}
+
+// htmlWeight returns the weight of the media ranges naming HTML explicitly, 0 if none does.
+func htmlWeight(ranges []acceptRange) float64 {
+	var q float64
+	for _, r := range ranges {
+		if r.mediaType == HTMLMediaIdentifier && r.q > q {
+			q = r.q
+		}
+	}
+	return q
+}
+
+// parseAccept returns the media ranges of the Accept header value accept, the ranges that do not
+// parse are ignored and the weight defaults to 1.
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, r := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(r))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f < 0 || f > 1 {
+				continue
+			}
+			q = f
+		}
+		ranges = append(ranges, acceptRange{mediaType: mt, q: q})
+	}
+	return ranges
+}
+
+// configuredMediaType returns t if it is one of types and the first element of types otherwise.
+func configuredMediaType(t string, types []string) string {
+	for _, mt := range types {
+		if mt == t {
+			return t
+		}
+	}
+	return types[0]
+}
+
+// isProblemMediaType returns true if t is one of the built-in problem media types.
+func isProblemMediaType(t string) bool {
+	for _, pt := range problemMediaTypes {
+		if pt == t {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaTypeMatches returns true if the media range r accepts the media type t. r must already be
+// lowercase as returned by mime.ParseMediaType. A range naming the generic format of a structured
+// syntax suffix accepts the media types using the suffix, e.g. "application/xml" accepts
+// "application/problem+xml".
+func mediaTypeMatches(r, t string) bool {
+	return mediaTypeSpecificity(r, t) > 0
+}
+
+// mediaTypeSpecificity returns how specifically the media range r accepts the media type t: 4
+// for an exact match, 3 for a structured syntax suffix match, 2 for a subtype wildcard such as
+// "application/*", 1 for "*/*" and 0 if r does not accept t.
+func mediaTypeSpecificity(r, t string) int {
+	switch {
+	case r == t:
+		return 4
+	case r == "*/*":
+		return 1
+	case strings.HasSuffix(r, "/*"):
+		if strings.HasPrefix(t, strings.TrimSuffix(r, "*")) {
+			return 2
+		}
+		return 0
+	}
+	i := strings.Index(r, "/")
+	if i < 0 || !strings.HasPrefix(t, r[:i+1]) || !strings.HasSuffix(t, "+"+r[i+1:]) {
+		return 0
+	}
+	return 3
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// WithFollowNegotiatedType makes the handler send problems in the flavor of the media type the
+// goa controller already negotiated for the response, i.e. the Content-Type header set on the
+// response when the error is returned, instead of negotiating with the Accept header. Registered
+// serializers are used for their media type, e.g. JSON:API documents for
+// application/vnd.api+json, XML and +xml media types get problem+xml and JSON and the other +json
+// vendor media types get problem+json. The Accept header is used when no Content-Type is set or
+// when it has no problem flavor.
+func WithFollowNegotiatedType(enabled bool) Option {
+	return func(o *options) {
+		o.followNegotiatedType = enabled
+	}
+}
+
+// problemMediaType returns the media type of the problem sent in response to req, h is the header
+// of the response.
+func (o *options) problemMediaType(h http.Header, req *http.Request) string {
+	if o.followNegotiatedType {
+		if mt, ok := o.negotiatedMediaType(h.Get("Content-Type")); ok {
+			return mt
+		}
+	}
+	return o.negotiateMediaType(req.Header.Get("Accept"))
+}
+
+// negotiatedMediaType returns the problem media type matching the response media type
+// contentType.
+func (o *options) negotiatedMediaType(contentType string) (string, bool) {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", false
+	}
+	for _, t := range o.mediaTypes() {
+		if t == mt {
+			return t, true
+		}
+	}
+	switch {
+	case mt == "application/xml" || mt == "text/xml" || strings.HasSuffix(mt, "+xml"):
+		return Rfc7807XmlMediaIdentifier, true
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		return Rfc7807JsonMediaIdentifier, true
+	}
+	return "", false
+}
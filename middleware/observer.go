@@ -0,0 +1,20 @@
+package middleware
+
+import "context"
+
+// WithErrorObserver registers a function called with the problem about to be sent for each error
+// response together with the error returned by the handler. Observers are called in registration
+// order after all other processing and must not modify the problem. They are the extension point
+// used by integrations such as tracing or metrics.
+func WithErrorObserver(f func(ctx context.Context, problem *Rfc7807Response, err error)) Option {
+	return func(o *options) {
+		o.errorObservers = append(o.errorObservers, f)
+	}
+}
+
+// observe calls the registered error observers.
+func (o *options) observe(ctx context.Context, problem *Rfc7807Response, err error) {
+	for _, f := range o.errorObservers {
+		f(ctx, problem, err)
+	}
+}
@@ -0,0 +1,250 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+type (
+	// Option configures the Rfc7807Handler middleware.
+	Option func(*options)
+
+	// options holds the settings applied by the Rfc7807Handler middleware.
+	options struct {
+		// authFailureDelay is the minimum delay applied before sending 401 and 403 responses.
+		authFailureDelay time.Duration
+		// authFailureJitter is the upper bound of the random delay added to authFailureDelay.
+		authFailureJitter time.Duration
+		// traceIDComposer generates the trace IDs of errors, shortID is used when nil.
+		traceIDComposer func(context.Context, *http.Request) string
+		// verboseFromContext decides the verbosity of each request when not nil.
+		verboseFromContext func(context.Context) bool
+		// supportCodeGenerator mints the support code of each error response when not nil.
+		supportCodeGenerator func() string
+		// statusAliases maps the statuses of errors to the statuses sent to clients.
+		statusAliases map[int]int
+		// extraUnwrapMethods enables the Reason and Underlying methods when unwrapping errors.
+		extraUnwrapMethods bool
+		// responseSizeObserver is called with the size of each serialized problem when not nil.
+		responseSizeObserver func(status int, bytes int)
+		// xmlDeclaration prepends the XML declaration to XML problems.
+		xmlDeclaration bool
+		// preferServiceErrorDetail uses the detail of wrapped service errors in verbose mode.
+		preferServiceErrorDetail bool
+		// logLimiter caps the number of error logs per client when not nil.
+		logLimiter *clientLogLimiter
+		// instanceUUID sets the instance of problems to a "urn:uuid:" URN.
+		instanceUUID bool
+		// metaRedactPaths lists the paths of the meta values to redact.
+		metaRedactPaths [][]string
+		// errorObservers are called with each problem before it is sent.
+		errorObservers []func(ctx context.Context, problem *Rfc7807Response, err error)
+		// deadLetterSink receives the dead letter entries when not nil.
+		deadLetterSink func(DeadLetterEntry)
+		// deadLetterDispatcher runs the calls to deadLetterSink.
+		deadLetterDispatcher *Dispatcher
+		// deadLetterHeaders lists the request headers recorded in dead letter entries.
+		deadLetterHeaders []string
+		// deadLetterStatus selects the statuses recorded in the dead letter sink.
+		deadLetterStatus func(status int) bool
+		// serializers contains the custom problem serializers indexed by media type.
+		serializers map[string]func(io.Writer, *Rfc7807Response) error
+		// customMediaTypes lists the media types of custom serializers in registration order.
+		customMediaTypes []string
+		// contextLogFields returns key/value pairs appended to error log entries when not nil.
+		contextLogFields func(context.Context) []interface{}
+		// securityHeaders are set on all problem responses.
+		securityHeaders map[string]string
+		// piiScrubber removes personal data from problems and logs when not nil.
+		piiScrubber func(string) string
+		// redactions are applied after the PII scrubber, see WithRedaction.
+		redactions []Redaction
+		// methodNotAllowedDetail lists the allowed methods in 405 problems.
+		methodNotAllowedDetail bool
+		// interceptors are called in order before problems are sent.
+		interceptors []Interceptor
+		// detailCharset filters the characters of problem details when not nil.
+		detailCharset func(rune) bool
+		// defaultFormat is the media type used for requests without Accept header.
+		defaultFormat string
+		// fallbackFormat is the media type used when the Accept header matches no media type.
+		fallbackFormat string
+		// tokenInBody decides whether error tokens are sent to clients when not nil.
+		tokenInBody func(context.Context) bool
+		// detailFromMeta synthesizes problem details from meta values when not nil.
+		detailFromMeta func(meta map[string]interface{}) (string, bool)
+		// latencyObserver is called with the latency of each problem response when not nil.
+		latencyObserver func(status int, d time.Duration)
+		// traceIDTrailer sends the request trace ID in a trailer.
+		traceIDTrailer bool
+		// logLevelFunc selects the level of error response logs when not nil.
+		logLevelFunc func(status int, err error) Level
+		// leveledLogger writes the handler log entries when not nil.
+		leveledLogger func(ctx context.Context, level Level, msg string, keyvals ...interface{})
+		// conflictDetail exposes the resource versions of 409 problems.
+		conflictDetail bool
+		// verboseTokens contains the tokens of internal errors whose details are always sent.
+		verboseTokens map[string]bool
+		// verbose includes the details of internal errors in responses.
+		verbose bool
+		// typeBaseURI is the URI relative problem types are resolved against.
+		typeBaseURI string
+		// idGenerator produces the IDs of internal errors unless a trace ID composer is set.
+		idGenerator IDGenerator
+		// logClientErrors logs 4xx errors at the info level.
+		logClientErrors bool
+		// title is the title of problems that have none instead of their status text.
+		title string
+		// problemTypes is the registry of problem types used in place of ProblemTypes when not nil.
+		problemTypes *ProblemTypeRegistry
+		// errorMappers translate errors before the registered mappers.
+		errorMappers []ErrorMapper
+		// rfc9457 follows RFC 9457 rather than RFC 7807.
+		rfc9457 bool
+		// catalog localizes the title and detail of problems when not nil.
+		catalog *Catalog
+		// instanceFunc computes the instance of problems when not nil.
+		instanceFunc InstanceFunc
+		// metricsHook records the labels of problem responses when not nil.
+		metricsHook func(context.Context, ProblemLabels)
+		// verbosityFunc decides per problem whether its details are sent when not nil.
+		verbosityFunc VerbosityFunc
+		// authChallenges are the default WWW-Authenticate challenges of 401 problems.
+		authChallenges []string
+		// requestIDTraceID makes the request ID the trace ID of all problems.
+		requestIDTraceID bool
+		// traceparent makes the trace-id of the traceparent header the default request ID.
+		traceparent bool
+		// multiErrors aggregates the errors bundled in multi-errors.
+		multiErrors bool
+		// jsonFieldNames renames the members of JSON problems.
+		jsonFieldNames map[string]string
+		// committedAction is the action taken when the response is already committed.
+		committedAction CommittedAction
+		// cors configures the CORS headers of problem responses when not nil.
+		cors *CORSConfig
+		// goaProblemTypes contains the problem types of goa errors consulted after the registry.
+		goaProblemTypes *ProblemTypeRegistry
+		// statusOverrides overrides the status of service errors indexed by error code.
+		statusOverrides map[string]int
+		// beforeSend are the hooks called before problems are serialized.
+		beforeSend []Hook
+		// afterSend are the hooks called after problems are written.
+		afterSend []Hook
+		// errorReporter reports the errors of 5xx problems when not nil.
+		errorReporter ErrorReporter
+		// logSampler caps the 5xx log entries per fingerprint when not nil.
+		logSampler *clientLogLimiter
+		// logFingerprint computes the signature of logged errors.
+		logFingerprint func(status int, err error) string
+		// contextErrors maps context errors to 504 and canceled problems.
+		contextErrors bool
+		// canceledStatus is the status of the problems of canceled requests.
+		canceledStatus int
+		// noStore prevents the caching of problem responses.
+		noStore bool
+		// fastPath enables the reflection free serialization of simple JSON problems.
+		fastPath bool
+		// debugTokenHeader is the header carrying the debug token.
+		debugTokenHeader string
+		// debugToken is the token making requests verbose.
+		debugToken string
+		// debugNetworks are the networks whose requests are verbose.
+		debugNetworks []*net.IPNet
+		// defaultLanguage is the language of the messages used when no accepted language matches.
+		defaultLanguage string
+		// clientErrorLogging configures the logging of client errors.
+		clientErrorLogging *ClientErrorLogging
+		// clientErrorLimiter rate limits the log entries of client errors.
+		clientErrorLimiter *clientLogLimiter
+		// auditSinks receive the audit records of problem responses.
+		auditSinks []AuditSink
+		// auditPrincipal returns the principal recorded in audit records.
+		auditPrincipal func(context.Context) string
+		// quietPaths are the paths whose errors are not logged nor recorded in metrics.
+		quietPaths []string
+		// quietUserAgents are the user agents whose errors are not logged nor recorded in metrics.
+		quietUserAgents []string
+		// shadow enables the shadow mode.
+		shadow bool
+		// problemTrailers enables the Problem-Type and Problem-Detail trailers.
+		problemTrailers bool
+		// maxDetailLength is the maximum length of problem details.
+		maxDetailLength int
+		// maxMetaSize is the maximum size of the JSON representation of problem meta values.
+		maxMetaSize int
+		// maxLogErrorLength is the maximum length of logged errors.
+		maxLogErrorLength int
+		// timestamp is true if problems carry the time they were sent in the timestamp meta value.
+		timestamp bool
+		// clock tells the time of problems, audit records and dead letter entries, the system time if nil.
+		clock Clock
+		// followNegotiatedType is true if problems follow the media type negotiated by the controller.
+		followNegotiatedType bool
+		// stackTrace is true if the problems sent in verbose mode carry the stack trace of the error.
+		stackTrace bool
+		// routes contains the settings overridden for the actions of controllers.
+		routes []routeOptions
+		// transformers are applied in order to the problems right before they are sent.
+		transformers []Transformer
+		// causeChain is true if the problems sent in verbose mode carry the chain of causes of the error.
+		causeChain bool
+		// identityExtractor returns the principal, tenant or API key identifying the client of requests.
+		identityExtractor IdentityExtractor
+		// identityInMeta is true if the identity of the client is added to the meta values of problems.
+		identityInMeta bool
+		// pseudoLocalization enables the pseudo-localization of catalog messages.
+		pseudoLocalization bool
+		// fingerprint adds the fingerprint of errors to log entries and problems.
+		fingerprint bool
+		// metaExposure contains the exposure of meta values by key.
+		metaExposure map[string]MetaExposure
+		// maxBodySize is the maximum size of serialized problems, 0 for no limit.
+		maxBodySize int
+		// problemTypeHeader sends the type of the problems sent without body in the Problem-Type header.
+		problemTypeHeader bool
+		// problemStore records the problem responses with their details when not nil.
+		problemStore *ProblemStore
+		// vendorMediaTypes maps the problem media types to the vendor media types sent in their place.
+		vendorMediaTypes map[string]string
+		// sloReporter records the problem responses for SLO reporting.
+		sloReporter *SLOReporter
+		// trustedProxies are the networks of the trusted reverse proxies.
+		trustedProxies []*net.IPNet
+		// contextTraceIDFunc returns the trace ID of all the problems of a request.
+		contextTraceIDFunc func(context.Context) string
+	}
+)
+
+// newOptions returns the handler settings resulting from applying opts in order.
+func newOptions(opts ...Option) *options {
+	o := applyOptions(opts)
+	for i := range o.routes {
+		o.routes[i].resolved = applyOptions(append(append([]Option{}, opts...), o.routes[i].opts...))
+	}
+	return o
+}
+
+// applyOptions returns the default settings modified by applying opts in order.
+func applyOptions(opts []Option) *options {
+	o := &options{
+		xmlDeclaration: true,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Combine returns an option that applies opts in order, for example so that integrations can
+// configure the handler with a single option.
+func Combine(opts ...Option) Option {
+	return func(o *options) {
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
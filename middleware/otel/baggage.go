@@ -0,0 +1,29 @@
+package otel
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/baggage"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+// WithBaggageMeta returns a middleware option that copies the values of the given OpenTelemetry
+// baggage members of the request context into the problem meta values, for example to include the
+// tenant in problems for support triage. Members absent from the baggage are ignored.
+func WithBaggageMeta(keys ...string) middleware.Option {
+	return middleware.WithInterceptors(middleware.InterceptorFunc(func(ctx context.Context, _ *http.Request, pc *middleware.ProblemContext) {
+		b := baggage.FromContext(ctx)
+		for _, k := range keys {
+			m := b.Member(k)
+			if m.Key() == "" {
+				continue
+			}
+			if pc.Response.Meta == nil {
+				pc.Response.Meta = make(map[string]interface{})
+			}
+			pc.Response.Meta[k] = m.Value()
+		}
+	}))
+}
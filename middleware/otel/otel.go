@@ -0,0 +1,45 @@
+// Package otel integrates the RFC 7807 middleware with OpenTelemetry. It provides middleware
+// options that record problem responses on the span active in the request context and correlate
+// them with the trace of the request.
+package otel
+
+import (
+	"context"
+
+	"github.com/goadesign/goa"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+// Attribute keys set on spans by WithSpanAttributes.
+const (
+	// ErrorTokenKey is the attribute key of the goa error token.
+	ErrorTokenKey = attribute.Key("error.token")
+	// StatusCodeKey is the attribute key of the problem HTTP status.
+	StatusCodeKey = attribute.Key("http.status_code")
+	// ProblemTypeKey is the attribute key of the problem type URI.
+	ProblemTypeKey = attribute.Key("problem.type")
+)
+
+// WithSpanAttributes returns a middleware option that sets the goa error token, the HTTP status
+// and the problem type as attributes of the span active in the request context whenever an error
+// response is sent, so that tracing backends can facet errors by these values.
+func WithSpanAttributes() middleware.Option {
+	return middleware.WithErrorObserver(func(ctx context.Context, problem *middleware.Rfc7807Response, _ error) {
+		span := trace.SpanFromContext(ctx)
+		if !span.IsRecording() {
+			return
+		}
+		typ := problem.Type
+		if typ == "" {
+			typ = "about:blank"
+		}
+		attrs := []attribute.KeyValue{StatusCodeKey.Int(problem.Status), ProblemTypeKey.String(typ)}
+		if resp := goa.ContextResponse(ctx); resp != nil && resp.ErrorCode != "" {
+			attrs = append(attrs, ErrorTokenKey.String(resp.ErrorCode))
+		}
+		span.SetAttributes(attrs...)
+	})
+}
@@ -0,0 +1,61 @@
+package otel
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+// SpanIDMetaKey is the meta key of the span ID set by WithTraceCorrelation.
+const SpanIDMetaKey = "span_id"
+
+// WithTraceCorrelation returns a middleware option that correlates problem responses with the
+// distributed trace of the request: the trace ID of problems is set to the ID of the trace of the
+// span active in the request context before they are logged, see middleware.WithContextTraceID,
+// and, if withSpanID is true, the span ID is added to the problem meta values. The trace ID is
+// also used as the ID of internal errors that do not have a request ID so that it appears in
+// their detail. The error is recorded on the
+// span whose status is set to error for 5xx responses, client errors do not fail server spans.
+// Problems are left unchanged when the context has no valid span context.
+func WithTraceCorrelation(withSpanID bool) middleware.Option {
+	return middleware.Combine(
+		middleware.WithTraceIDComposer(func(ctx context.Context, _ *http.Request) string {
+			return spanTraceID(ctx)
+		}),
+		middleware.WithContextTraceID(spanTraceID),
+		middleware.WithInterceptors(middleware.InterceptorFunc(func(ctx context.Context, _ *http.Request, pc *middleware.ProblemContext) {
+			sc := trace.SpanContextFromContext(ctx)
+			if !sc.IsValid() {
+				return
+			}
+			if withSpanID {
+				if pc.Response.Meta == nil {
+					pc.Response.Meta = make(map[string]interface{})
+				}
+				pc.Response.Meta[SpanIDMetaKey] = sc.SpanID().String()
+			}
+		})),
+		middleware.WithErrorObserver(func(ctx context.Context, problem *middleware.Rfc7807Response, err error) {
+			span := trace.SpanFromContext(ctx)
+			if !span.IsRecording() {
+				return
+			}
+			span.RecordError(err)
+			if problem.Status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, problem.Title)
+			}
+		}),
+	)
+}
+
+// spanTraceID returns the ID of the trace of the span active in ctx or "" if there is none.
+func spanTraceID(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
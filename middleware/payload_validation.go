@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/goadesign/goa"
+)
+
+var (
+	// loadErrorRegexp matches the details of the goa.ErrBadRequest errors goa records in the
+	// context when the validation of a payload fails, the submatch is the message of the
+	// validation error followed by its meta values.
+	loadErrorRegexp = regexp.MustCompile(`(?s)^\[[^\]]*\] \d{3} invalid_request: (.*)$`)
+	// validationMetaKeys are the meta keys of the goa validation errors.
+	validationMetaKeys = []string{"attribute", "comp", "error", "expected", "len", "name", "param", "parent", "regexp", "value"}
+)
+
+// PayloadValidator validates the decoded payload of a request and returns the failed validations,
+// for example by validating the payload against a JSON schema. payload is the value set by goa in
+// the Payload field of the request data, it is nil when the request has no body.
+type PayloadValidator func(ctx context.Context, req *http.Request, payload interface{}) []FieldError
+
+// ValidatePayload returns a middleware that rejects the requests whose payload is invalid before
+// the controller runs with a 422 Unprocessable Entity problem listing the failed validations in
+// the errors meta value. The payloads that fail the validations of the goa design are rejected
+// as well as the payloads for which one of the validators returns errors, goa errors that are
+// not validation errors, e.g. malformed bodies, are left for the controller to return. The
+// middleware must be registered after the Rfc7807Handler middleware.
+func ValidatePayload(validators ...PayloadValidator) goa.Middleware {
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			if resp, ok := goa.ContextError(ctx).(*goa.ErrorResponse); ok {
+				if errs := payloadValidationErrors(resp); errs != nil {
+					return invalidPayload(errs)
+				}
+			}
+			var payload interface{}
+			if r := goa.ContextRequest(ctx); r != nil {
+				payload = r.Payload
+			}
+			var errs []FieldError
+			for _, v := range validators {
+				errs = append(errs, v(ctx, req, payload)...)
+			}
+			if len(errs) > 0 {
+				return invalidPayload(errs)
+			}
+			return h(ctx, rw, req)
+		}
+	}
+}
+
+// invalidPayload returns the 422 problem listing errs.
+func invalidPayload(errs []FieldError) error {
+	return NewProblem(http.StatusUnprocessableEntity).
+		Detail("The request payload is invalid.").
+		Meta(ValidationErrorsMetaKey, errs).
+		Err()
+}
+
+// payloadValidationErrors returns the per field errors of the goa validation error resp or of the
+// validation error wrapped by resp when goa failed to load the payload.
+func payloadValidationErrors(resp *goa.ErrorResponse) []FieldError {
+	if errs := validationErrors(resp); errs != nil {
+		return errs
+	}
+	m := loadErrorRegexp.FindStringSubmatch(resp.Detail)
+	if m == nil {
+		return nil
+	}
+	detail := m[1]
+	for _, k := range validationMetaKeys {
+		if i := strings.Index(detail, ", "+k+": "); i >= 0 {
+			detail = detail[:i]
+		}
+	}
+	return validationErrors(&goa.ErrorResponse{Code: "invalid_request", Detail: detail})
+}
@@ -0,0 +1,45 @@
+package middleware
+
+// WithPIIScrubber sets a function applied to the title, the detail and the top level string meta
+// values, field error messages and error entry details of problems as well as to the logged error
+// messages. The scrubber runs before anything is logged or sent so that personal data never
+// reaches logs nor clients. The redactions set with WithRedaction run after it.
+func WithPIIScrubber(f func(string) string) Option {
+	return func(o *options) {
+		o.piiScrubber = f
+	}
+}
+
+// scrub returns s scrubbed by the PII scrubber if any and the redactions, see WithRedaction.
+func (o *options) scrub(s string) string {
+	if o.piiScrubber != nil {
+		s = o.piiScrubber(s)
+	}
+	for _, r := range o.redactions {
+		s = r(s)
+	}
+	return s
+}
+
+// scrubProblem applies the PII scrubber and the redactions to the strings of problem.
+func (o *options) scrubProblem(problem *Rfc7807Response) {
+	if o.piiScrubber == nil && len(o.redactions) == 0 {
+		return
+	}
+	problem.Title = o.scrub(problem.Title)
+	problem.Detail = o.scrub(problem.Detail)
+	for k, v := range problem.Meta {
+		switch actual := v.(type) {
+		case string:
+			problem.Meta[k] = o.scrub(actual)
+		case []FieldError:
+			for i := range actual {
+				actual[i].Message = o.scrub(actual[i].Message)
+			}
+		case []ErrorEntry:
+			for i := range actual {
+				actual[i].Detail = o.scrub(actual[i].Detail)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// PlainTextMediaIdentifier is the media type of plain text problems.
+const PlainTextMediaIdentifier = "text/plain"
+
+// WithPlainText renders problems as plain text for clients that prefer it, such as curl users
+// sending "Accept: text/plain". The status and title are on the first line, followed by the
+// detail, the meta values sorted by key, the code and the trace ID:
+//
+//	403 Forbidden
+//	Your current balance is 30, but that costs 50.
+//	balance: 30
+//	trace_id: 5Yy8gxKq
+func WithPlainText() Option {
+	return WithSerializer(PlainTextMediaIdentifier, EncodePlainText)
+}
+
+// EncodePlainText writes problem to w as plain text, see WithPlainText.
+func EncodePlainText(w io.Writer, problem *Rfc7807Response) error {
+	if _, err := fmt.Fprintf(w, "%d %s\n", problem.Status, problem.Title); err != nil {
+		return err
+	}
+	if problem.Detail != "" {
+		if _, err := fmt.Fprintln(w, problem.Detail); err != nil {
+			return err
+		}
+	}
+	keys := make([]string, 0, len(problem.Meta))
+	for k := range problem.Meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, err := fmt.Fprintf(w, "%s: %v\n", k, problem.Meta[k]); err != nil {
+			return err
+		}
+	}
+	if problem.Code != "" {
+		if _, err := fmt.Fprintf(w, "code: %s\n", problem.Code); err != nil {
+			return err
+		}
+	}
+	if problem.TraceID != "" {
+		if _, err := fmt.Fprintf(w, "trace_id: %s\n", problem.TraceID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// ProblemBuilder builds problems with chainable methods, see NewProblem.
+type ProblemBuilder struct {
+	problem Rfc7807Response
+	err     error
+}
+
+// NewProblem returns a builder of problems with the given status, for example:
+//
+//	return middleware.NewProblem(http.StatusForbidden).
+//		Type("https://example.com/probs/out-of-credit").
+//		Detail("Your current balance is 30, but that costs 50.").
+//		Meta("balance", 30).
+//		Err()
+//
+// The status must be a 4xx or 5xx status, invalid arguments are reported by Build and Err.
+func NewProblem(status int) *ProblemBuilder {
+	b := &ProblemBuilder{problem: Rfc7807Response{Status: status}}
+	if status < 400 || status > 599 {
+		b.err = fmt.Errorf("invalid problem status %d, must be a 4xx or 5xx status", status)
+	}
+	return b
+}
+
+// Type sets the type of the problem, uri must be a valid URI reference.
+func (b *ProblemBuilder) Type(uri string) *ProblemBuilder {
+	if _, err := url.Parse(uri); err != nil && b.err == nil {
+		b.err = fmt.Errorf("invalid problem type: %s", err)
+	}
+	b.problem.Type = uri
+	return b
+}
+
+// Title sets the title of the problem.
+func (b *ProblemBuilder) Title(title string) *ProblemBuilder {
+	b.problem.Title = title
+	return b
+}
+
+// Detail sets the detail of the problem.
+func (b *ProblemBuilder) Detail(detail string) *ProblemBuilder {
+	b.problem.Detail = detail
+	return b
+}
+
+// Instance sets the instance of the problem, uri must be a valid URI reference.
+func (b *ProblemBuilder) Instance(uri string) *ProblemBuilder {
+	if _, err := url.Parse(uri); err != nil && b.err == nil {
+		b.err = fmt.Errorf("invalid problem instance: %s", err)
+	}
+	b.problem.Instance = uri
+	return b
+}
+
+// Code sets the stable machine-readable code of the problem.
+func (b *ProblemBuilder) Code(code string) *ProblemBuilder {
+	b.problem.Code = code
+	return b
+}
+
+// Meta sets the meta value with key k.
+func (b *ProblemBuilder) Meta(k string, v interface{}) *ProblemBuilder {
+	b.problem.setMeta(k, v)
+	return b
+}
+
+// Extensions sets the extensions of the problem, a struct or map serialized as top level
+// members, see Rfc7807Response.MarshalJSON.
+func (b *ProblemBuilder) Extensions(ext interface{}) *ProblemBuilder {
+	b.problem.Extensions = ext
+	return b
+}
+
+// Build returns a new problem or the first validation error. The title defaults to the status
+// text and each problem gets a new random trace ID like goa errors.
+func (b *ProblemBuilder) Build() (*Rfc7807Response, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	problem := b.problem
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	problem.TraceID = shortID()
+	if problem.Meta != nil {
+		meta := make(map[string]interface{}, len(problem.Meta))
+		for k, v := range problem.Meta {
+			meta[k] = v
+		}
+		problem.Meta = meta
+	}
+	return &problem, nil
+}
+
+// Err returns the problem as an error that handlers may return, or the validation error.
+func (b *ProblemBuilder) Err() error {
+	problem, err := b.Build()
+	if err != nil {
+		return err
+	}
+	return problem
+}
+
+// Error returns the detail of the problem or its title if there is no detail, it makes problems
+// usable as errors.
+func (r *Rfc7807Response) Error() string {
+	if r.Detail != "" {
+		return r.Detail
+	}
+	return r.Title
+}
+
+// ResponseStatus returns the status of the problem, it makes problems goa.ServiceError errors
+// that the handler sends unchanged.
+func (r *Rfc7807Response) ResponseStatus() int {
+	return r.Status
+}
+
+// Token returns the trace ID of the problem.
+func (r *Rfc7807Response) Token() string {
+	return r.TraceID
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+)
+
+// problemEnrichment contains the detail and meta values recorded by WithProblemDetail and
+// WithProblemMeta and the verbosity forced by ForceVerbose and ForceTerse during a request.
+type problemEnrichment struct {
+	mu        sync.Mutex
+	detail    string
+	meta      map[string]interface{}
+	verbosity int
+}
+
+// WithProblemMeta records the meta value v with key k in ctx, the handler adds it to the problem
+// sent if the request fails. It lets handler code attach extension members without access to the
+// response writer, ctx must derive from the context given to the handler by the Rfc7807Handler
+// middleware or HTTPMiddleware, the value is dropped otherwise. Values of the error take
+// precedence.
+func WithProblemMeta(ctx context.Context, k string, v interface{}) {
+	pe, ok := ctx.Value(problemEnrichmentKey).(*problemEnrichment)
+	if !ok {
+		return
+	}
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	if pe.meta == nil {
+		pe.meta = make(map[string]interface{})
+	}
+	pe.meta[k] = v
+}
+
+// WithProblemDetail records detail in ctx, the handler uses it in place of the detail of the
+// error as the detail of the problem sent if the request fails, see WithProblemMeta. The detail of
+// internal errors is still only sent in verbose mode.
+func WithProblemDetail(ctx context.Context, detail string) {
+	pe, ok := ctx.Value(problemEnrichmentKey).(*problemEnrichment)
+	if !ok {
+		return
+	}
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pe.detail = detail
+}
+
+// withProblemEnrichment returns a copy of ctx in which WithProblemDetail and WithProblemMeta
+// record values.
+func withProblemEnrichment(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(problemEnrichmentKey).(*problemEnrichment); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, problemEnrichmentKey, &problemEnrichment{})
+}
+
+// enrich merges the detail and meta values recorded in ctx into problem.
+func enrich(ctx context.Context, problem *Rfc7807Response) {
+	pe, ok := ctx.Value(problemEnrichmentKey).(*problemEnrichment)
+	if !ok {
+		return
+	}
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	if pe.detail != "" {
+		problem.Detail = pe.detail
+	}
+	for k, v := range pe.meta {
+		if _, ok := problem.Meta[k]; !ok {
+			problem.setMeta(k, v)
+		}
+	}
+}
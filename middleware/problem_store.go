@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// StoredProblem is a problem response recorded by a ProblemStore.
+	StoredProblem struct {
+		// Time is the time the problem was sent.
+		Time time.Time `json:"time"`
+		// Method is the request HTTP method.
+		Method string `json:"method"`
+		// Path is the request URL path.
+		Path string `json:"path"`
+		// Status is the status of the response.
+		Status int `json:"status"`
+		// TraceID is the ID logged with the problem, the request ID or the generated ID of
+		// internal errors, it is set even when the problem has no trace ID, e.g. for client
+		// errors or when WithTokenInBody hides it.
+		TraceID string `json:"trace_id,omitempty"`
+		// Error is the error returned by the handler formatted with %+v, it goes through the PII
+		// scrubber and the maximum log error length like in log entries.
+		Error string `json:"error"`
+		// Problem is the problem with its details, as sent in verbose mode.
+		Problem *Rfc7807Response `json:"problem"`
+	}
+
+	// ProblemStore keeps the last problem responses in memory with their details regardless of
+	// the verbosity of the responses, so that on-call engineers can retrieve the details of the
+	// error a client got from its trace ID. It is safe for concurrent use.
+	ProblemStore struct {
+		mu      sync.RWMutex
+		entries []StoredProblem
+		next    int
+		full    bool
+	}
+)
+
+// NewProblemStore returns a store of the last n problem responses, n defaults to 100.
+func NewProblemStore(n int) *ProblemStore {
+	if n <= 0 {
+		n = 100
+	}
+	return &ProblemStore{entries: make([]StoredProblem, n)}
+}
+
+// WithProblemStore records the problem responses in s, see ProblemStore. The problems sent with a
+// status for which the handler is quiet are recorded as well.
+func WithProblemStore(s *ProblemStore) Option {
+	return func(o *options) {
+		o.problemStore = s
+	}
+}
+
+// Add records p, the oldest problem is dropped when the store is full.
+func (s *ProblemStore) Add(p StoredProblem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[s.next] = p
+	s.next = (s.next + 1) % len(s.entries)
+	if s.next == 0 {
+		s.full = true
+	}
+}
+
+// Get returns the last problem recorded with the given trace ID, which is either the ID logged
+// with the problem or the trace ID of the problem.
+func (s *ProblemStore) Get(traceID string) (StoredProblem, bool) {
+	if traceID == "" {
+		return StoredProblem{}, false
+	}
+	for _, p := range s.Problems() {
+		if p.TraceID == traceID || (p.Problem != nil && p.Problem.TraceID == traceID) {
+			return p, true
+		}
+	}
+	return StoredProblem{}, false
+}
+
+// Problems returns the recorded problems, the most recent first.
+func (s *ProblemStore) Problems() []StoredProblem {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n := s.next
+	if s.full {
+		n = len(s.entries)
+	}
+	problems := make([]StoredProblem, 0, n)
+	for i := 1; i <= n; i++ {
+		problems = append(problems, s.entries[(s.next-i+len(s.entries))%len(s.entries)])
+	}
+	return problems
+}
+
+// Handler returns an HTTP handler serving the recorded problems as JSON mounted under prefix,
+// e.g. "/debug/problems": GET prefix lists the problems, the most recent first, and GET
+// prefix/{trace_id} or GET prefix?trace_id={trace_id} returns the last problem recorded with the
+// trace ID, the query form suits trace IDs containing slashes. The details of the problems are
+// not meant for clients, mount the handler on an admin server or behind authentication.
+func (s *ProblemStore) Handler(prefix string) http.Handler {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			rw.Header().Set("Allow", "GET, HEAD")
+			http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		id := req.URL.Query().Get("trace_id")
+		if id == "" {
+			id = strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, prefix), "/")
+		}
+		var v interface{}
+		if id == "" {
+			v = s.Problems()
+		} else {
+			p, ok := s.Get(id)
+			if !ok {
+				http.NotFound(rw, req)
+				return
+			}
+			v = p
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Header().Set("Cache-Control", "no-store")
+		rw.Write(append(b, '\n'))
+	})
+}
+
+// snapshotProblem returns a copy of problem with its details to record in the problem store, nil
+// if there is no store.
+func (o *options) snapshotProblem(problem *Rfc7807Response, e error) *Rfc7807Response {
+	if o.problemStore == nil {
+		return nil
+	}
+	snapshot := *problem
+	snapshot.Meta = make(map[string]interface{}, len(problem.Meta))
+	for k, v := range problem.Meta {
+		snapshot.Meta[k] = v
+	}
+	o.setStackTrace(&snapshot, e)
+	o.setCauses(&snapshot, e)
+	return &snapshot
+}
+
+// storeProblem records snapshot, the details of problem sent to req with status and logged with
+// id, or recorded with the request ID of ctx if id is empty, in the problem store. The members and
+// the meta values set after the snapshot was taken, e.g. the report ID, are copied from problem.
+// The snapshot strings are scrubbed before it is taken and its meta values are redacted like the
+// ones of problem, see WithMetaRedactPaths, so that the store never holds more than the logs.
+func (o *options) storeProblem(ctx context.Context, req *http.Request, status int, id string, snapshot, problem *Rfc7807Response, e error) {
+	if snapshot == nil {
+		return
+	}
+	if id == "" {
+		id, _ = RequestID(ctx)
+	}
+	snapshot.Type = problem.Type
+	snapshot.Title = problem.Title
+	snapshot.Status = problem.Status
+	snapshot.Instance = problem.Instance
+	snapshot.TraceID = problem.TraceID
+	snapshot.Code = problem.Code
+	for k, v := range problem.Meta {
+		if _, ok := snapshot.Meta[k]; !ok {
+			snapshot.Meta[k] = v
+		}
+	}
+	o.redactMeta(snapshot)
+	if len(snapshot.Meta) == 0 {
+		snapshot.Meta = nil
+	}
+	o.problemStore.Add(StoredProblem{
+		Time:    o.now().UTC(),
+		Method:  req.Method,
+		Path:    req.URL.Path,
+		Status:  status,
+		TraceID: id,
+		Error:   o.truncateLogError(o.scrub(fmt.Sprintf("%+v", e))),
+		Problem: snapshot,
+	})
+}
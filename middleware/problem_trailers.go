@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/goadesign/goa"
+)
+
+const (
+	// ProblemTypeTrailer is the name of the trailer that carries the type of the problems of
+	// committed responses, see WithProblemTrailers.
+	ProblemTypeTrailer = "Problem-Type"
+	// ProblemDetailTrailer is the name of the trailer that carries the detail of the problems of
+	// committed responses, see WithProblemTrailers.
+	ProblemDetailTrailer = "Problem-Detail"
+)
+
+// WithProblemTrailers declares the Problem-Type and Problem-Detail trailers on all responses and
+// sets them to the type and detail of the problem when a downstream handler fails after the
+// response status line has been written, e.g. while streaming a chunked response, so that
+// clients can detect that the stream terminated due to an error. Problems without type are
+// reported with the "about:blank" type and the detail of internal errors is only sent in verbose
+// mode like in problem responses. The trailers are not sent when the handler succeeds.
+func WithProblemTrailers(enabled bool) Option {
+	return func(o *options) {
+		o.problemTrailers = enabled
+	}
+}
+
+// declareProblemTrailers declares the problem trailers.
+func (o *options) declareProblemTrailers(rw http.ResponseWriter) {
+	if !o.problemTrailers {
+		return
+	}
+	rw.Header().Add("Trailer", ProblemTypeTrailer)
+	rw.Header().Add("Trailer", ProblemDetailTrailer)
+}
+
+// setProblemTrailers sets the problem trailers declared by declareProblemTrailers to the type and
+// detail of the problem corresponding to e sent with status.
+func (o *options) setProblemTrailers(ctx context.Context, rw http.ResponseWriter, req *http.Request, e error, status int) {
+	if !o.problemTrailers {
+		return
+	}
+	c := cause(e, o.unwrap())
+	problem := &Rfc7807Response{Status: status, Detail: e.Error()}
+	if se, ok := c.(goa.ServiceError); ok {
+		problem = newRfc7807Response(se)
+		o.applyProblemType(se, problem)
+	}
+	o.resolveType(problem)
+	typ := problem.Type
+	if typ == "" {
+		typ = BlankProblemType
+	}
+	detail := o.scrub(problem.Detail)
+	if !o.isVerboseFor(ctx, req, c, status) {
+		detail = http.StatusText(status)
+	}
+	rw.Header().Set(ProblemTypeTrailer, trailerValue(typ))
+	rw.Header().Set(ProblemDetailTrailer, trailerValue(detail))
+}
+
+// trailerValue returns s with the control characters that are not allowed in header values
+// replaced with spaces.
+func trailerValue(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return ' '
+		}
+		return r
+	}, s)
+}
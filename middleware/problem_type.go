@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/goadesign/goa"
+)
+
+// WithTypeBaseURI sets the absolute URI that relative problem type references are resolved
+// against, for example a type "out-of-credit" set by an interceptor becomes
+// "https://example.com/probs/out-of-credit" with the base "https://example.com/probs/".
+func WithTypeBaseURI(base string) Option {
+	return func(o *options) {
+		o.typeBaseURI = base
+	}
+}
+
+// resolveType resolves the type of problem against the type base URI. Types that are absolute or
+// that do not parse are left unchanged.
+func (o *options) resolveType(problem *Rfc7807Response) {
+	if o.typeBaseURI == "" || problem.Type == "" {
+		return
+	}
+	ref, err := url.Parse(problem.Type)
+	if err != nil || ref.IsAbs() {
+		return
+	}
+	base, err := url.Parse(o.typeBaseURI)
+	if err != nil {
+		return
+	}
+	problem.Type = base.ResolveReference(ref).String()
+}
+
+// ProblemType describes a problem type emitted for a class of errors.
+type ProblemType struct {
+	// URI is the URI reference identifying the problem type, relative references are resolved
+	// against the base set with WithTypeBaseURI.
+	URI string
+	// Title is the title of the problem type, the status text is used when empty.
+	Title string
+	// Status is the HTTP status of the problems of this type, it is only used for documentation.
+	Status int
+	// Description explains the problem type and how to resolve it, it is only used for
+	// documentation.
+	Description string
+	// Deprecated is the date the problem type was deprecated at, problems of deprecated types
+	// are sent with a Deprecation header.
+	Deprecated time.Time
+	// Sunset is the date after which the problem type will no longer be sent, it is sent in the
+	// Sunset header.
+	Sunset time.Time
+	// SupersededBy is the URI of the problem type replacing the deprecated type, it is sent in
+	// the Link header and in the superseded_by meta value.
+	SupersededBy string
+	// Version is the version of the problem type, versions from 2 are rendered in the type URI
+	// and the previous version is sent in the Link header, see WithVersion.
+	Version int
+}
+
+// ProblemTypeRegistry maps error codes to problem types. It is safe for concurrent use.
+type ProblemTypeRegistry struct {
+	mu    sync.RWMutex
+	types map[string]ProblemType
+}
+
+// ProblemTypes is the registry consulted by the handler unless WithProblemTypes is used.
+var ProblemTypes = NewProblemTypeRegistry()
+
+// NewProblemTypeRegistry returns an empty registry.
+func NewProblemTypeRegistry() *ProblemTypeRegistry {
+	return &ProblemTypeRegistry{types: make(map[string]ProblemType)}
+}
+
+// Register associates the problem type t with code. The code of goa.ErrorResponse errors is their
+// Code field, e.g. "not_found", the code of other goa.ServiceError errors is the value returned by
+// their Token method.
+func (r *ProblemTypeRegistry) Register(code string, t ProblemType) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.types[code] = t
+}
+
+// Lookup returns the problem type registered for code.
+func (r *ProblemTypeRegistry) Lookup(code string) (ProblemType, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.types[code]
+	return t, ok
+}
+
+// find returns a problem type of r for which match returns true.
+func (r *ProblemTypeRegistry) find(match func(ProblemType) bool) (ProblemType, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, t := range r.types {
+		if match(t) {
+			return t, true
+		}
+	}
+	return ProblemType{}, false
+}
+
+// WithProblemTypes sets the registry the handler consults in place of ProblemTypes.
+func WithProblemTypes(r *ProblemTypeRegistry) Option {
+	return func(o *options) {
+		o.problemTypes = r
+	}
+}
+
+// applyProblemType sets the type and title of problem to the ones registered for the code of err,
+// or to the ones of the goa error types, unless the problem already has a type. It returns the
+// problem type applied if any, or the registered type of problems that already have a type so
+// that its version and deprecation apply, e.g. to the problems of goansgen constructors.
+func (o *options) applyProblemType(err goa.ServiceError, problem *Rfc7807Response) (ProblemType, bool) {
+	if problem.Type != "" {
+		return o.registeredType(problem)
+	}
+	t, ok := o.lookupProblemType(errorCode(err))
+	if !ok {
+		return ProblemType{}, false
+	}
+	problem.Type = t.VersionedURI()
+	problem.Title = t.Title
+	return t, true
+}
+
+// registeredType returns the problem type registered for the code of problem if its URI is the
+// type of problem, or else the registered problem type whose URI is the type of problem. URIs are
+// compared once resolved against the type base URI.
+func (o *options) registeredType(problem *Rfc7807Response) (ProblemType, bool) {
+	typ := resolveTypeURI(o.typeBaseURI, problem.Type)
+	matches := func(t ProblemType) bool {
+		return resolveTypeURI(o.typeBaseURI, t.VersionedURI()) == typ
+	}
+	if problem.Code != "" {
+		if t, ok := o.lookupProblemType(problem.Code); ok && matches(t) {
+			return t, true
+		}
+	}
+	r := o.problemTypes
+	if r == nil {
+		r = ProblemTypes
+	}
+	if t, ok := r.find(matches); ok {
+		return t, true
+	}
+	if o.goaProblemTypes != nil {
+		return o.goaProblemTypes.find(matches)
+	}
+	return ProblemType{}, false
+}
+
+// lookupProblemType returns the problem type registered for code with the problem type registry
+// or with the goa problem types, see WithGoaProblemTypes.
+func (o *options) lookupProblemType(code string) (ProblemType, bool) {
+	r := o.problemTypes
+	if r == nil {
+		r = ProblemTypes
+	}
+	t, ok := r.Lookup(code)
+	if !ok && o.goaProblemTypes != nil {
+		t, ok = o.goaProblemTypes.Lookup(code)
+	}
+	return t, ok
+}
+
+// errorCode returns the code classifying err: the Code of goa.ErrorResponse errors and the type of
+// problems as their token is a unique ID, the token of other service errors.
+func errorCode(err goa.ServiceError) string {
+	switch actual := err.(type) {
+	case *goa.ErrorResponse:
+		return actual.Code
+	case *Rfc7807Response:
+		return actual.Type
+	}
+	return err.Token()
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"encoding/json"
+	"html/template"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// problemTypeDoc is the documentation of a registered problem type.
+type problemTypeDoc struct {
+	Code        string `json:"code"`
+	Type        string `json:"type"`
+	Title       string `json:"title"`
+	Status      int    `json:"status,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// problemTypeDocsTemplate renders the documentation of problem types as HTML.
+var problemTypeDocsTemplate = template.Must(template.New("problems").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Problem types</title></head><body>
+{{range .}}<section id="{{.Code}}"><h2>{{.Title}}</h2>
+<dl><dt>Type</dt><dd><code>{{.Type}}</code></dd>{{if .Status}}<dt>Status</dt><dd>{{.Status}}</dd>{{end}}</dl>
+{{if .Description}}<p>{{.Description}}</p>{{end}}</section>
+{{end}}</body></html>
+`))
+
+// Codes returns the registered codes in lexical order.
+func (r *ProblemTypeRegistry) Codes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	codes := make([]string, 0, len(r.types))
+	for c := range r.types {
+		codes = append(codes, c)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// DocsHandler returns an HTTP handler serving the documentation of the registered problem types
+// mounted under prefix, e.g. "/problems": GET prefix lists all the types and GET prefix/{code}
+// describes the type registered with code. The documentation is sent as JSON to clients that
+// prefer application/json and as HTML otherwise. Registering types with the relative URIs of
+// their documentation, e.g. "/problems/not_found", makes the type of problems dereferenceable.
+func (r *ProblemTypeRegistry) DocsHandler(prefix string) http.Handler {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			rw.Header().Set("Allow", "GET, HEAD")
+			http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		rest := strings.Trim(strings.TrimPrefix(req.URL.Path, prefix), "/")
+		var docs []problemTypeDoc
+		if rest == "" {
+			for _, c := range r.Codes() {
+				docs = append(docs, r.doc(c))
+			}
+		} else {
+			if _, ok := r.Lookup(rest); !ok {
+				http.NotFound(rw, req)
+				return
+			}
+			docs = []problemTypeDoc{r.doc(rest)}
+		}
+		rw.Header().Add("Vary", "Accept")
+		if prefersJSON(req.Header.Get("Accept")) {
+			rw.Header().Set("Content-Type", "application/json")
+			var v interface{} = docs
+			if rest != "" {
+				v = docs[0]
+			}
+			json.NewEncoder(rw).Encode(v)
+			return
+		}
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		problemTypeDocsTemplate.Execute(rw, docs)
+	})
+}
+
+// doc returns the documentation of the problem type registered with code.
+func (r *ProblemTypeRegistry) doc(code string) problemTypeDoc {
+	t, _ := r.Lookup(code)
+	d := problemTypeDoc{Code: code, Type: t.VersionedURI(), Title: t.Title, Status: t.Status, Description: t.Description}
+	if d.Title == "" {
+		d.Title = http.StatusText(t.Status)
+	}
+	if d.Title == "" {
+		d.Title = code
+	}
+	return d
+}
+
+// prefersJSON returns true if the first media range of accept that names JSON or HTML names JSON.
+func prefersJSON(accept string) bool {
+	for _, r := range strings.Split(accept, ",") {
+		mt, _, err := mime.ParseMediaType(strings.TrimSpace(r))
+		if err != nil {
+			continue
+		}
+		switch {
+		case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+			return true
+		case mt == "text/html" || mt == "application/xhtml+xml":
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"regexp"
+)
+
+// ProblemSchemaName is the name of the schema of problems in the generated OpenAPI documents.
+const ProblemSchemaName = "Problem"
+
+// componentNameRegexp matches the characters that are not allowed in OpenAPI component names.
+var componentNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// OpenAPI3Components returns the OpenAPI 3 components object describing the registered problem
+// types: the "schemas" member contains the ProblemSchemaName schema and one schema per code
+// constraining its type and status, the "responses" member contains one response per code using
+// that schema. The components are named after the codes and base resolves the relative types like
+// WithTypeBaseURI. The result can be marshaled to JSON or YAML and merged into the components of
+// the specification of the service so that the documentation stays in sync with the responses.
+func (r *ProblemTypeRegistry) OpenAPI3Components(base string) map[string]interface{} {
+	schemas := map[string]interface{}{ProblemSchemaName: problemSchema()}
+	responses := make(map[string]interface{})
+	for _, c := range r.Codes() {
+		name, doc := componentName(c), r.doc(c)
+		schemas[name] = problemTypeSchema("#/components/schemas/", doc, base)
+		responses[name] = map[string]interface{}{
+			"description": responseDescription(doc),
+			"content": map[string]interface{}{
+				Rfc7807JsonMediaIdentifier: map[string]interface{}{
+					"schema": map[string]interface{}{"$ref": "#/components/schemas/" + name},
+				},
+			},
+		}
+	}
+	return map[string]interface{}{"schemas": schemas, "responses": responses}
+}
+
+// Swagger2Components returns the OpenAPI 2 (Swagger) "definitions" and "responses" members
+// describing the registered problem types, see OpenAPI3Components. The members are returned in a
+// map so that they can be merged into the generated swagger.json of the service.
+func (r *ProblemTypeRegistry) Swagger2Components(base string) map[string]interface{} {
+	definitions := map[string]interface{}{ProblemSchemaName: problemSchema()}
+	responses := make(map[string]interface{})
+	for _, c := range r.Codes() {
+		name, doc := componentName(c), r.doc(c)
+		definitions[name] = problemTypeSchema("#/definitions/", doc, base)
+		responses[name] = map[string]interface{}{
+			"description": responseDescription(doc),
+			"schema":      map[string]interface{}{"$ref": "#/definitions/" + name},
+		}
+	}
+	return map[string]interface{}{"definitions": definitions, "responses": responses}
+}
+
+// problemSchema returns the JSON schema of the problems serialized by the handler.
+func problemSchema() map[string]interface{} {
+	str := func(description string) map[string]interface{} {
+		return map[string]interface{}{"type": "string", "description": description}
+	}
+	return map[string]interface{}{
+		"type":        "object",
+		"description": "Problem details for HTTP APIs (RFC 7807)",
+		"properties": map[string]interface{}{
+			"type":  str("URI reference that identifies the problem type"),
+			"title": str("Short, human-readable summary of the problem type"),
+			"status": map[string]interface{}{
+				"type": "integer", "minimum": 400, "maximum": 599,
+				"description": "HTTP status code",
+			},
+			"detail":   str("Human-readable explanation specific to this occurrence of the problem"),
+			"instance": str("URI reference that identifies the specific occurrence of the problem"),
+			"trace_id": str("Unique error instance identifier"),
+			"code":     str("Stable machine-readable code of the error"),
+			"meta": map[string]interface{}{
+				"type": "object", "additionalProperties": true,
+				"description": "Additional key/value pairs useful to clients",
+			},
+		},
+		"required": []string{"type", "title", "status", "detail", "instance", "trace_id"},
+	}
+}
+
+// problemTypeSchema returns the schema of the problems of the type documented by doc, it extends
+// the problem schema referenced with prefix.
+func problemTypeSchema(prefix string, doc problemTypeDoc, base string) map[string]interface{} {
+	props := map[string]interface{}{
+		"type": map[string]interface{}{"type": "string", "enum": []string{resolveTypeURI(base, doc.Type)}},
+	}
+	if doc.Status != 0 {
+		props["status"] = map[string]interface{}{"type": "integer", "enum": []int{doc.Status}}
+	}
+	schema := map[string]interface{}{
+		"allOf": []interface{}{
+			map[string]interface{}{"$ref": prefix + ProblemSchemaName},
+			map[string]interface{}{"type": "object", "properties": props},
+		},
+		"title": doc.Title,
+	}
+	if doc.Description != "" {
+		schema["description"] = doc.Description
+	}
+	return schema
+}
+
+// responseDescription returns the description of the response of the problem type documented by
+// doc, responses require a description.
+func responseDescription(doc problemTypeDoc) string {
+	if doc.Description != "" {
+		return doc.Description
+	}
+	return doc.Title
+}
+
+// componentName returns code with the characters not allowed in component names replaced with
+// "_".
+func componentName(code string) string {
+	return componentNameRegexp.ReplaceAllString(code, "_")
+}
+
+// resolveTypeURI resolves typ against base like resolveType.
+func resolveTypeURI(base, typ string) string {
+	o := options{typeBaseURI: base}
+	problem := Rfc7807Response{Type: typ}
+	o.resolveType(&problem)
+	return problem.Type
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ProblemTypeOption configures the problem types registered with RegisterProblemType.
+type ProblemTypeOption func(*ProblemType)
+
+// WithVersion sets the version of the problem type so that error contracts can evolve without
+// breaking the clients of the previous version: the URI of versions from 2 is the URI of the
+// problem type followed by "/v" and the version, e.g. "order-not-found/v2", and the problems of
+// these versions are sent with a Link header with the "predecessor-version" relation pointing to
+// the previous version, the unversioned URI for version 2.
+func WithVersion(v int) ProblemTypeOption {
+	return func(t *ProblemType) {
+		t.Version = v
+	}
+}
+
+// WithTypeURI sets the URI of the problem type, the code with dashes by default.
+func WithTypeURI(uri string) ProblemTypeOption {
+	return func(t *ProblemType) {
+		t.URI = uri
+	}
+}
+
+// WithTypeTitle sets the title of the problem type.
+func WithTypeTitle(title string) ProblemTypeOption {
+	return func(t *ProblemType) {
+		t.Title = title
+	}
+}
+
+// RegisterProblemType registers the problem type configured with opts for code with ProblemTypes,
+// for example:
+//
+//	middleware.RegisterProblemType("order_not_found", middleware.WithVersion(2))
+//
+// makes the goa errors with code "order_not_found" problems of type "order-not-found/v2", see
+// ProblemTypeRegistry.RegisterType.
+func RegisterProblemType(code string, opts ...ProblemTypeOption) {
+	ProblemTypes.RegisterType(code, opts...)
+}
+
+// RegisterType registers the problem type configured with opts for code. The URI of the type is
+// the code with dashes unless set with WithTypeURI, relative URIs are resolved against the base
+// set with WithTypeBaseURI.
+func (r *ProblemTypeRegistry) RegisterType(code string, opts ...ProblemTypeOption) {
+	t := ProblemType{URI: strings.Replace(code, "_", "-", -1)}
+	for _, opt := range opts {
+		opt(&t)
+	}
+	r.Register(code, t)
+}
+
+// VersionedURI returns the URI of the problems of type t, the URI followed by the version for
+// versions from 2.
+func (t ProblemType) VersionedURI() string {
+	return versionedURI(t.URI, t.Version)
+}
+
+// versionedURI returns uri with version v.
+func versionedURI(uri string, v int) string {
+	if v < 2 {
+		return uri
+	}
+	return strings.TrimSuffix(uri, "/") + "/v" + strconv.Itoa(v)
+}
+
+// setPredecessorVersion advertises the previous version of the problem type t in a Link header
+// with the "predecessor-version" relation (RFC 5829).
+func (o *options) setPredecessorVersion(h http.Header, t ProblemType) {
+	if t.Version < 2 {
+		return
+	}
+	uri := resolveTypeURI(o.typeBaseURI, versionedURI(t.URI, t.Version-1))
+	h.Add("Link", "<"+uri+`>; rel="predecessor-version"`)
+}
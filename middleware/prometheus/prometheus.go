@@ -0,0 +1,81 @@
+// Package prometheus exposes the problem responses of the RFC 7807 middleware as Prometheus
+// metrics.
+package prometheus
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+// Collector counts problem responses by status, problem type, goa controller and goa action,
+// counts the ones burning the error budget and records the size of their bodies by status and
+// problem type, the bodiless responses such as the responses to HEAD requests excluded. It
+// implements prometheus.Collector.
+type Collector struct {
+	responses *prometheus.CounterVec
+	burning   *prometheus.CounterVec
+	sizes     *prometheus.HistogramVec
+}
+
+// NewCollector returns a collector of the "<namespace>_problem_responses_total" and
+// "<namespace>_problem_budget_burning_responses_total" counters and of the
+// "<namespace>_problem_response_size_bytes" histogram, register it with a Prometheus registry and
+// configure the handler with its Option method:
+//
+//	c := prometheus.NewCollector("api")
+//	registry.MustRegister(c)
+//	service.Use(middleware.Rfc7807HandlerWithOptions(service, c.Option()))
+//
+// The budget burning responses are only counted when the handler has an SLO reporter, see
+// middleware.WithSLOReporter.
+func NewCollector(namespace string) *Collector {
+	return &Collector{
+		responses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "problem_responses_total",
+			Help:      "Number of problem responses sent by status, problem type, controller and action.",
+		}, []string{"status", "type", "controller", "action"}),
+		burning: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "problem_budget_burning_responses_total",
+			Help:      "Number of problem responses burning the error budget by status, problem type, controller and action.",
+		}, []string{"status", "type", "controller", "action"}),
+		sizes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "problem_response_size_bytes",
+			Help:      "Size of the bodies of the problem responses sent by status and problem type.",
+			Buckets:   prometheus.ExponentialBuckets(128, 4, 6),
+		}, []string{"status", "type"}),
+	}
+}
+
+// Option returns the middleware option that records problem responses in c.
+func (c *Collector) Option() middleware.Option {
+	return middleware.WithMetricsHook(func(_ context.Context, l middleware.ProblemLabels) {
+		c.responses.WithLabelValues(strconv.Itoa(l.Status), l.Type, l.Controller, l.Action).Inc()
+		if l.BurnsBudget {
+			c.burning.WithLabelValues(strconv.Itoa(l.Status), l.Type, l.Controller, l.Action).Inc()
+		}
+		if l.BodySize > 0 {
+			c.sizes.WithLabelValues(strconv.Itoa(l.Status), l.Type).Observe(float64(l.BodySize))
+		}
+	})
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.responses.Describe(ch)
+	c.burning.Describe(ch)
+	c.sizes.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.responses.Collect(ch)
+	c.burning.Collect(ch)
+	c.sizes.Collect(ch)
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// WithQuietPaths disables the logging and the metrics of the errors of the requests to the given
+// URL paths, for example health and readiness probes failing during startup. Paths ending with
+// "*" match the paths they are a prefix of, e.g. "/healthz/*". The problem responses are sent as
+// usual.
+func WithQuietPaths(paths ...string) Option {
+	return func(o *options) {
+		o.quietPaths = append(o.quietPaths, paths...)
+	}
+}
+
+// WithQuietUserAgents disables the logging and the metrics of the errors of the requests whose
+// User-Agent header contains one of the given strings, e.g. "kube-probe", see WithQuietPaths.
+func WithQuietUserAgents(agents ...string) Option {
+	return func(o *options) {
+		o.quietUserAgents = append(o.quietUserAgents, agents...)
+	}
+}
+
+// isQuiet returns true if the errors of req must not be logged nor recorded in metrics.
+func (o *options) isQuiet(req *http.Request) bool {
+	for _, p := range o.quietPaths {
+		if prefix := strings.TrimSuffix(p, "*"); prefix != p {
+			if strings.HasPrefix(req.URL.Path, prefix) {
+				return true
+			}
+		} else if req.URL.Path == p {
+			return true
+		}
+	}
+	if len(o.quietUserAgents) == 0 {
+		return false
+	}
+	ua := req.UserAgent()
+	for _, a := range o.quietUserAgents {
+		if a != "" && strings.Contains(ua, a) {
+			return true
+		}
+	}
+	return false
+}
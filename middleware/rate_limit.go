@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/goadesign/goa"
+)
+
+type (
+	// RateLimitConfig configures the RateLimit middleware.
+	RateLimitConfig struct {
+		// Rate is the number of requests per second a client may sustain.
+		Rate float64
+		// Burst is the maximum number of requests a client may send at once, it is the limit
+		// sent in the X-RateLimit-Limit header. It defaults to 1.
+		Burst int
+		// Key returns the key identifying the client of a request, the client IP by default:
+		// the remote address of the connection, or the right-most X-Forwarded-For hop that is
+		// not a trusted proxy for the requests coming from TrustedProxies. Requests whose key is
+		// empty are not limited.
+		Key func(*http.Request) string
+		// TrustedProxies lists the CIDRs of the trusted reverse proxies used by the default Key,
+		// see WithTrustedProxies. RateLimit panics if one is invalid.
+		TrustedProxies []string
+	}
+
+	// rateLimiter maintains the token buckets of the most recently seen clients.
+	rateLimiter struct {
+		rate  float64
+		burst float64
+
+		mu      sync.Mutex
+		lru     *list.List // *tokenBucket values, most recently seen first
+		buckets map[string]*list.Element
+	}
+
+	// tokenBucket contains the tokens of a client.
+	tokenBucket struct {
+		key    string
+		tokens float64
+		last   time.Time
+	}
+)
+
+// RateLimit returns a middleware that limits the rate of the requests of each client with a token
+// bucket and rejects excess requests with a 429 Too Many Requests problem. The problem contains
+// the limit, remaining and reset meta values and is sent with the Retry-After header when the
+// middleware is registered after the Rfc7807Handler middleware. All responses carry the
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers, the reset being the
+// number of seconds until the bucket of the client is full again. Only the most recently seen
+// clients are tracked so memory usage stays bounded.
+func RateLimit(c RateLimitConfig) goa.Middleware {
+	if c.Burst <= 0 {
+		c.Burst = 1
+	}
+	if c.Key == nil {
+		trusted := mustParseNetworks(c.TrustedProxies)
+		c.Key = func(req *http.Request) string {
+			return clientIP(req, trusted)
+		}
+	}
+	l := &rateLimiter{
+		rate:    c.Rate,
+		burst:   float64(c.Burst),
+		lru:     list.New(),
+		buckets: make(map[string]*list.Element),
+	}
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			key := c.Key(req)
+			if key == "" {
+				return h(ctx, rw, req)
+			}
+			ok, remaining, wait, reset := l.take(key, time.Now())
+			resetSecs := int64(math.Ceil(reset.Seconds()))
+			rw.Header().Set("X-RateLimit-Limit", strconv.Itoa(c.Burst))
+			rw.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			rw.Header().Set("X-RateLimit-Reset", strconv.FormatInt(resetSecs, 10))
+			if !ok {
+				return RetryAfter(NewProblem(http.StatusTooManyRequests).
+					Detail("Rate limit exceeded, retry later.").
+					Meta("limit", c.Burst).
+					Meta("remaining", 0).
+					Meta("reset", resetSecs).
+					Err(), wait)
+			}
+			return h(ctx, rw, req)
+		}
+	}
+}
+
+// take takes a token from the bucket of the client identified by key. It returns whether a token
+// was available, the number of remaining tokens, the delay until the next token and the delay
+// until the bucket is full.
+func (l *rateLimiter) take(key string, now time.Time) (bool, int, time.Duration, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var b *tokenBucket
+	if elem, ok := l.buckets[key]; ok {
+		l.lru.MoveToFront(elem)
+		b = elem.Value.(*tokenBucket)
+		b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+		b.last = now
+	} else {
+		b = &tokenBucket{key: key, tokens: l.burst, last: now}
+		l.buckets[key] = l.lru.PushFront(b)
+		if l.lru.Len() > maxRateLimitedClients {
+			oldest := l.lru.Back()
+			l.lru.Remove(oldest)
+			delete(l.buckets, oldest.Value.(*tokenBucket).key)
+		}
+	}
+	ok := b.tokens >= 1
+	if ok {
+		b.tokens--
+	}
+	var wait, reset time.Duration
+	if l.rate > 0 {
+		if !ok {
+			wait = time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		}
+		reset = time.Duration((l.burst - b.tokens) / l.rate * float64(time.Second))
+	}
+	return ok, int(b.tokens), wait, reset
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import "context"
+
+// Update replaces the settings of the handler with the ones of the options it was created with
+// followed by c.Options() and opts, so that feature flag systems can change the error behavior,
+// e.g. the verbosity, the error mappers, the problem type registry or the message catalog, without
+// restarting the service. The settings that c leaves unset, except the verbose flag that c always
+// sets, keep the values of the creation options and each call replaces the changes of the
+// previous one. The settings are swapped atomically: requests in flight complete with the
+// settings they started with and new requests use the new settings. The state kept by the
+// settings, e.g. the log rate limits and samples, is reset. Handlers created with
+// Rfc7807HandlerWithOptions cannot be updated, create them with NewProblemHandler. Like
+// c.Options(), Update panics if c contains invalid networks, load c with LoadFromEnv to get an
+// error instead.
+func (p *ProblemHandler) Update(c Config, opts ...Option) {
+	all := append(append(append([]Option{}, p.base...), c.Options()...), opts...)
+	p.opts.Store(newOptions(all...))
+}
+
+// current returns the current settings of the handler.
+func (p *ProblemHandler) current() *options {
+	return p.opts.Load().(*options)
+}
+
+// handlerSettings are the settings of a handler recorded in the context of a request.
+type handlerSettings struct {
+	handler *ProblemHandler
+	opts    *options
+}
+
+// withSettings returns a copy of ctx recording the current settings of the handler so that the
+// request is handled with the same settings throughout, ctx is returned unchanged if it already
+// records the settings of the handler.
+func (p *ProblemHandler) withSettings(ctx context.Context) context.Context {
+	if s, ok := ctx.Value(settingsKey).(handlerSettings); ok && s.handler == p {
+		return ctx
+	}
+	return context.WithValue(ctx, settingsKey, handlerSettings{handler: p, opts: p.current()})
+}
+
+// settings returns the settings recorded in ctx by withSettings or the current settings.
+func (p *ProblemHandler) settings(ctx context.Context) *options {
+	if s, ok := ctx.Value(settingsKey).(handlerSettings); ok && s.handler == p {
+		return s.opts
+	}
+	return p.current()
+}
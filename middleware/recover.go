@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/goadesign/goa"
+)
+
+// PanicError is the error returned by the Recover middleware when a downstream handler panics. It
+// wraps the panic value when it is an error, a *ProblemBuilder or a Rfc7807Response so that code
+// deep in a handler may abort the request by panicking with e.g. a 403 problem, which is then sent
+// with its status and type instead of a generic 500 problem.
+type PanicError struct {
+	// Value is the value given to panic.
+	Value interface{}
+	// Stack is the stack trace of the goroutine at the time of the panic.
+	Stack []byte
+	// Callers are the program counters of the stack of the goroutine at the time of the panic as
+	// returned by runtime.Callers, see WithStackTrace.
+	Callers []uintptr
+
+	// err is the error wrapped by the panic value, if any.
+	err error
+}
+
+// newPanicError returns the error of a panic with value r.
+func newPanicError(r interface{}, stack []byte, callers []uintptr) *PanicError {
+	return &PanicError{Value: r, Stack: stack, Callers: callers, err: panicValueError(r)}
+}
+
+// panicValueError returns the error wrapped by the panic value r: r itself if it is an error, the
+// problem built by a *ProblemBuilder or a pointer to a Rfc7807Response, and nil otherwise.
+func panicValueError(r interface{}) error {
+	switch v := r.(type) {
+	case *ProblemBuilder:
+		if v != nil {
+			return v.Err()
+		}
+	case Rfc7807Response:
+		return &v
+	case error:
+		return v
+	}
+	return nil
+}
+
+// Error returns the panic value.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Format implements fmt.Formatter, the %+v verb includes the stack trace so that it is logged by
+// the Rfc7807Handler middleware.
+func (e *PanicError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		fmt.Fprintf(s, "%s\n%s", e.Error(), e.Stack)
+		return
+	}
+	fmt.Fprint(s, e.Error())
+}
+
+// Unwrap returns the error wrapped by the panic value, if any, so that the panics with a
+// goa.ServiceError or a problem are sent with their status and the panics with other errors are
+// mapped like any returned error.
+func (e *PanicError) Unwrap() error {
+	if e.err == nil {
+		return panicValueError(e.Value)
+	}
+	return e.err
+}
+
+const (
+	// maxPanicCallers is the maximum number of frames of the Callers of PanicError errors.
+	maxPanicCallers = 64
+	// panicCallersSkip is the number of frames skipped in the Callers of PanicError errors: the
+	// frames of runtime.Callers, of the deferred function and of runtime.gopanic.
+	panicCallersSkip = 3
+)
+
+// Recover returns a middleware that recovers from panics in downstream handlers and returns them as
+// a *PanicError. Placed below the Rfc7807Handler middleware in the middleware chain the panics are
+// logged with their stack trace and sent as 500 problems like any other internal error so that
+// their detail is only included in verbose mode, unless the panic value is a problem or an error
+// that the handler maps to another status, see PanicError. Panics with http.ErrAbortHandler are not
+// recovered so that net/http aborts the response.
+func Recover() goa.Middleware {
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					if r == http.ErrAbortHandler {
+						panic(r)
+					}
+					pcs := make([]uintptr, maxPanicCallers)
+					n := runtime.Callers(panicCallersSkip, pcs)
+					err = newPanicError(r, debug.Stack(), pcs[:n])
+				}
+			}()
+			return h(ctx, rw, req)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Redacted is the text that replaces the data matched by redactions.
+const Redacted = "[REDACTED]"
+
+// Redaction replaces the sensitive data of a string, see WithRedaction.
+type Redaction func(string) string
+
+var (
+	// emailPattern matches email addresses.
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// bearerPattern matches bearer credentials as sent in Authorization headers.
+	bearerPattern = regexp.MustCompile(`(?i)\b(bearer)\s+[A-Za-z0-9\-._~+/]+=*`)
+	// cardPattern matches sequences of 13 to 19 digits optionally separated by spaces or dashes.
+	cardPattern = regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`)
+)
+
+// RedactEmails returns a redaction of email addresses.
+func RedactEmails() Redaction {
+	return RedactPattern(emailPattern)
+}
+
+// RedactBearerTokens returns a redaction of bearer tokens, "Bearer abc.def" becomes
+// "Bearer [REDACTED]".
+func RedactBearerTokens() Redaction {
+	return func(s string) string {
+		return bearerPattern.ReplaceAllString(s, "$1 "+Redacted)
+	}
+}
+
+// RedactCardNumbers returns a redaction of payment card numbers, only digit sequences passing the
+// Luhn check are redacted so that most identifiers and timestamps are left unchanged.
+func RedactCardNumbers() Redaction {
+	return func(s string) string {
+		return cardPattern.ReplaceAllStringFunc(s, func(m string) string {
+			if !luhn(m) {
+				return m
+			}
+			return Redacted
+		})
+	}
+}
+
+// RedactPattern returns a redaction of the matches of re.
+func RedactPattern(re *regexp.Regexp) Redaction {
+	return func(s string) string {
+		return re.ReplaceAllLiteralString(s, Redacted)
+	}
+}
+
+// WithRedaction applies the redactions rs in order to the strings scrubbed by the PII scrubber:
+// the title, the detail and the string meta values of problems and the logged error messages. The
+// redactions run after the scrubber set with WithPIIScrubber if any, whatever the order of the
+// options, and in verbose mode too, so that internal errors do not leak secrets, for example:
+//
+//	middleware.WithRedaction(
+//		middleware.RedactEmails(),
+//		middleware.RedactBearerTokens(),
+//		middleware.RedactCardNumbers(),
+//		middleware.RedactPattern(regexp.MustCompile(`password=\S+`)),
+//	)
+func WithRedaction(rs ...Redaction) Option {
+	return func(o *options) {
+		o.redactions = append(o.redactions, rs...)
+	}
+}
+
+// luhn returns true if the digits of s pass the Luhn check, spaces and dashes are ignored.
+func luhn(s string) bool {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(s)
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/goadesign/goa"
+)
+
+// RequestIDHeaders are the request headers read by RequestIDFromHeader by default.
+var RequestIDHeaders = []string{"X-Request-ID", "Request-Id"}
+
+// maxRequestIDLength is the maximum length of inbound request IDs, longer IDs are truncated.
+const maxRequestIDLength = 128
+
+// RequestIDFromHeader returns a middleware that records the request ID read from the first
+// non-empty of the given request headers, RequestIDHeaders by default, in the request context.
+// The ID recorded by a previous request ID middleware, see RequestID, is used if the request carries
+// none, a random short ID is generated otherwise. The ID is echoed in the first header of the
+// response. Registered before the Rfc7807Handler middleware the ID is used for log
+// correlation and as the trace ID of internal errors, see also WithRequestIDTraceID.
+func RequestIDFromHeader(headers ...string) goa.Middleware {
+	if len(headers) == 0 {
+		headers = RequestIDHeaders
+	}
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			var id string
+			for _, name := range headers {
+				if id = headerSafe(req.Header.Get(name)); id != "" {
+					break
+				}
+			}
+			if len(id) > maxRequestIDLength {
+				id = id[:maxRequestIDLength]
+			}
+			if id == "" {
+				id, _ = RequestID(ctx)
+			}
+			if id == "" {
+				id = shortID()
+			}
+			rw.Header().Set(headers[0], id)
+			return h(WithRequestID(ctx, id), rw, req)
+		}
+	}
+}
+
+// WithRequestIDTraceID makes the request ID recorded in the context, e.g. by RequestIDFromHeader,
+// the trace ID of all problems and not only of the internal errors that carry no ID.
+func WithRequestIDTraceID(enabled bool) Option {
+	return func(o *options) {
+		o.requestIDTraceID = enabled
+	}
+}
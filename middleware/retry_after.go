@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryAfterMetaKey is the meta key of the number of seconds after which a request may be retried.
+const RetryAfterMetaKey = "retry_after"
+
+// retryAfterError is an error wrapped with a retry delay.
+type retryAfterError struct {
+	wrappedError
+	delay time.Duration
+}
+
+// RetryAfter wraps err with the delay after which the failed request may be retried. The handler
+// sends the delay in the Retry-After header and in the retry_after meta value of 429 Too Many
+// Requests and 503 Service Unavailable problems. goa errors may also carry the delay in seconds as
+// their retry_after meta value.
+func RetryAfter(err error, d time.Duration) error {
+	return &retryAfterError{wrappedError: wrappedError{err}, delay: d}
+}
+
+// RetryAfter returns the retry delay.
+func (e *retryAfterError) RetryAfter() time.Duration {
+	return e.delay
+}
+
+// setRetryAfter sets the Retry-After header and the retry_after meta value of 429 and 503
+// problems to the retry delay carried by the chain of errors wrapped by e or by the meta
+// values of problem.
+func (o *options) setRetryAfter(h http.Header, e error, problem *Rfc7807Response) {
+	if problem.Status != http.StatusTooManyRequests && problem.Status != http.StatusServiceUnavailable {
+		return
+	}
+	d, ok := retryDelay(e, o.unwrap())
+	if !ok {
+		d, ok = metaRetryDelay(problem.Meta[RetryAfterMetaKey])
+	}
+	if !ok || d < 0 {
+		return
+	}
+	secs := int64(math.Ceil(d.Seconds()))
+	h.Set("Retry-After", strconv.FormatInt(secs, 10))
+	problem.setMeta(RetryAfterMetaKey, secs)
+}
+
+// retryDelay returns the delay of the first error in the chain of errors wrapped by e that has a
+// RetryAfter method.
+func retryDelay(e error, unwrap func(error) error) (time.Duration, bool) {
+	for ; e != nil; e = unwrap(e) {
+		if r, ok := e.(interface{ RetryAfter() time.Duration }); ok {
+			return r.RetryAfter(), true
+		}
+	}
+	return 0, false
+}
+
+// metaRetryDelay converts the retry_after meta value v, a number of seconds, into a delay.
+func metaRetryDelay(v interface{}) (time.Duration, bool) {
+	var secs float64
+	switch actual := v.(type) {
+	case int:
+		secs = float64(actual)
+	case int64:
+		secs = float64(actual)
+	case float64:
+		secs = actual
+	case time.Duration:
+		return actual, true
+	case string:
+		f, err := strconv.ParseFloat(actual, 64)
+		if err != nil {
+			return 0, false
+		}
+		secs = f
+	default:
+		return 0, false
+	}
+	return time.Duration(secs * float64(time.Second)), true
+}
@@ -1,14 +1,21 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	"context"
 
 	"github.com/goadesign/goa"
 )
 
+// ErrNilResponseWriter is returned by the Rfc7807Handler middleware when the downstream handler
+// fails and the response writer is nil so that no problem can be sent.
+var ErrNilResponseWriter = errors.New("Rfc7807Handler: nil ResponseWriter")
+
 const (
 	Rfc7807JsonMediaIdentifier = "application/problem+json"
 	Rfc7807XmlMediaIdentifier  = "application/problem+xml"
@@ -17,7 +24,7 @@ const (
 type (
 	Rfc7807Response struct {
 		// Type is a URI reference [RFC3986] that identifies the problem type.
-		Type string `json:"tye" xml:"type" form:"type"`
+		Type string `json:"type" xml:"type" form:"type"`
 		// Title is a short, human-readable summary of the problem type.
 		Title string `json:"title" xml:"title" form:"title"`
 		// Status is the HTTP status code ([RFC7231], Section 6).
@@ -29,80 +36,383 @@ type (
 
 		// TraceID is the unique error instance identifier.
 		TraceID string `json:"trace_id" xml:"trace_id" form:"trace_id"`
+		// Code is the stable machine-readable code of the error, e.g. the Code of goa errors,
+		// clients may switch on it independently of the type.
+		Code string `json:"code,omitempty" xml:"code,omitempty" form:"code,omitempty"`
 		// Meta contains additional key/value pairs useful to clients.
 		Meta map[string]interface{} `json:"meta,omitempty" xml:"meta,omitempty" form:"meta,omitempty"`
+		// Extensions is a struct or map whose members are serialized as extension members at
+		// the top level of the problem as defined in RFC 7807 Section 3.2, see MarshalJSON.
+		Extensions interface{} `json:"-" xml:"-" form:"-"`
+	}
+
+	// ProblemHandler converts the errors returned by goa handlers into RFC 7807 problem
+	// responses.
+	ProblemHandler struct {
+		service *goa.Service
+		// base are the options the handler was created with.
+		base []Option
+		// opts contains the current *options, see Update.
+		opts atomic.Value
 	}
 )
 
 // RFC7807Handler turns a Go error into an HTTP response. It should be placed in the middleware chain
 // below the logger middleware so the logger properly logs the HTTP response. ErrorHandler
-// understands instances of goa.ServiceError and returns the status and response body embodied in
-// them, it turns other Go error types into a 500 internal error response.
+// understands instances of goa.ServiceError and returns the status and details embodied in them
+// as a problem, it turns other Go error types into the problem returned by the first error mapper
+// handling them or into a 500 internal error problem, see RegisterErrorMapper.
 // If verbose is false the details of internal errors is not included in HTTP responses.
 // If you use github.com/pkg/errors then wrapping the error will allow a trace to be printed to the logs
-func Rfc7807Handler(service *goa.Service, verbose bool) goa.Middleware {
+// The behavior of the middleware can be further tuned with opts.
+func Rfc7807Handler(service *goa.Service, verbose bool, opts ...Option) goa.Middleware {
+	return NewProblemHandler(service, verbose, opts...).Middleware()
+}
+
+// Rfc7807HandlerWithOptions is Rfc7807Handler configured with options only, the verbosity
+// defaults to false and is set with WithVerbose.
+func Rfc7807HandlerWithOptions(service *goa.Service, opts ...Option) goa.Middleware {
+	return newProblemHandler(service, opts).Middleware()
+}
+
+// NewProblemHandler creates a problem handler configured with opts, see Rfc7807Handler for a
+// description of the arguments. service may be nil for handlers that are only used with
+// HTTPMiddleware, JSON problems are then serialized with encoding/json.
+func NewProblemHandler(service *goa.Service, verbose bool, opts ...Option) *ProblemHandler {
+	return newProblemHandler(service, append([]Option{WithVerbose(verbose)}, opts...))
+}
+
+// newProblemHandler creates a problem handler configured with opts.
+func newProblemHandler(service *goa.Service, opts []Option) *ProblemHandler {
+	p := &ProblemHandler{service: service, base: opts}
+	p.opts.Store(newOptions(opts...))
+	return p
+}
+
+// Middleware returns the goa middleware that sends the errors returned by downstream handlers as
+// problem responses.
+func (p *ProblemHandler) Middleware() goa.Middleware {
 	return func(h goa.Handler) goa.Handler {
 		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
-			e := h(ctx, rw, req)
-			if e == nil {
+			if rw == nil {
+				if e := h(ctx, rw, req); e != nil {
+					return ErrNilResponseWriter
+				}
 				return nil
 			}
-			cause := cause(e)
-			status := http.StatusInternalServerError
-			var respBody interface{}
-			if err, ok := cause.(goa.ServiceError); ok {
-				status = err.ResponseStatus()
-				respBody = err
-				goa.ContextResponse(ctx).ErrorCode = err.Token()
-				rw.Header().Set("Content-Type", Rfc7807JsonMediaIdentifier)
-			} else {
-				respBody = e.Error()
-				rw.Header().Set("Content-Type", "text/plain")
+			if _, ok := RequestStartTime(ctx); !ok {
+				ctx = WithRequestStartTime(ctx, time.Now())
 			}
-			if status == http.StatusInternalServerError {
-				reqID := ctx.Value(reqIDKey)
-				if reqID == nil {
-					reqID = shortID()
-					ctx = context.WithValue(ctx, reqIDKey, reqID)
-				}
-				goa.LogError(ctx, "uncaught error", "err", fmt.Sprintf("%+v", e), "id", reqID, "msg", respBody)
-				if !verbose {
-					rw.Header().Set("Content-Type", Rfc7807JsonMediaIdentifier)
-					msg := fmt.Sprintf("%s [%s]", http.StatusText(http.StatusInternalServerError), reqID)
-					respBody = goa.ErrInternal(msg)
-					// Preserve the ID of the original error as that's what gets logged, the client
-					// received error ID must match the original
-					if origErrID := goa.ContextResponse(ctx).ErrorCode; origErrID != "" {
-						respBody.(*Rfc7807Response).TraceID = origErrID
-					}
-				}
+			ctx = withProblemEnrichment(ctx)
+			ctx = p.withSettings(ctx)
+			o := p.settings(ctx).forRoute(ctx)
+			ctx = o.traceparentRequestID(ctx, req)
+			ctx = o.declareTraceIDTrailer(ctx, rw, req)
+			o.declareProblemTrailers(rw)
+			encoding := captureEncoding(ctx, rw)
+			e := h(ctx, rw, req)
+			if e != nil && o.shadow {
+				p.sendError(ctx, &shadowWriter{}, req, e)
+			} else if e != nil {
+				encoding.restore(rw)
+				e = p.sendError(ctx, rw, req, e)
+			}
+			o.setTraceIDTrailer(ctx, rw)
+			return e
+		}
+	}
+}
+
+// SupportedMediaTypes returns the media types of the problem representations the handler can
+// produce, including the media types of custom serializers, as sent in the Content-Type header.
+func (p *ProblemHandler) SupportedMediaTypes() []string {
+	o := p.current()
+	types := o.mediaTypes()
+	for i, t := range types {
+		types[i] = o.contentType(t)
+	}
+	return types
+}
+
+// sendError sends the problem response corresponding to e. The problems rendered with a
+// captureWriter, see renderJSON, are only rendered: they are not logged, reported, counted, audited
+// or stored, the hooks run after sending and the authentication failure delay do not apply.
+func (p *ProblemHandler) sendError(ctx context.Context, rw http.ResponseWriter, req *http.Request, e error) error {
+	o, service := p.settings(ctx).forRoute(ctx), p.service
+	_, render := rw.(*captureWriter)
+	ctx = o.traceparentRequestID(ctx, req)
+	if isCommitted(rw) {
+		if isEventStream(rw) {
+			return p.sendProblemEvent(ctx, rw, req, e)
+		}
+		o.sendCommitted(ctx, rw, req, e)
+		return nil
+	}
+	cause := cause(e, o.unwrap())
+	status := http.StatusInternalServerError
+	var problem *Rfc7807Response
+	var ptype ProblemType
+	if aggregated, ok := o.aggregate(ctx, e); ok {
+		status = aggregated.Status
+		problem = aggregated
+	} else if err, ok := cause.(goa.ServiceError); ok {
+		status = err.ResponseStatus()
+		problem = newRfc7807Response(err)
+		if s, ok := o.overrideStatus(errorCode(err)); ok {
+			status = s
+			problem.Status = s
+		}
+		ptype, _ = o.applyProblemType(err, problem)
+		if resp := goa.ContextResponse(ctx); resp != nil && !render {
+			resp.ErrorCode = err.Token()
+		}
+	} else if mapped, ok := o.mapError(ctx, e); ok {
+		status = mapped.Status
+		problem = mapped
+	} else if mapped, ok := mapTimeoutError(e); ok {
+		status = mapped.Status
+		problem = mapped
+	} else if mapped, ok := o.mapContextError(e); ok {
+		status = mapped.Status
+		problem = mapped
+	} else if mapped, ok := mapBreakerError(e); ok {
+		status = mapped.Status
+		problem = mapped
+	} else {
+		problem = &Rfc7807Response{
+			Status: http.StatusInternalServerError,
+			Detail: e.Error(),
+		}
+	}
+	enrich(ctx, problem)
+	o.synthesizeDetail(problem)
+	o.scrubProblem(problem)
+	o.truncateDetail(problem)
+	mediaType := o.problemMediaType(rw.Header(), req)
+	rw.Header().Set("Content-Type", o.contentType(mediaType))
+	o.setSecurityHeaders(rw.Header())
+	o.setCORS(rw.Header(), req)
+	o.describeMethodNotAllowed(rw.Header(), req, e, problem)
+	var supportCode string
+	if o.supportCodeGenerator != nil {
+		supportCode = o.supportCodeGenerator()
+	}
+	reqID := problem.TraceID
+	if status == http.StatusInternalServerError {
+		var ok bool
+		if reqID, ok = RequestID(ctx); !ok {
+			reqID = o.newTraceID(ctx, req)
+			ctx = WithRequestID(ctx, reqID)
+		}
+		// Preserve the ID of the original error as that's what gets logged, the client
+		// received error ID must match the original
+		if problem.TraceID == "" {
+			problem.TraceID = reqID
+		}
+	}
+	if id, ok := RequestID(ctx); ok && id != "" && o.requestIDTraceID {
+		reqID = id
+		problem.TraceID = id
+	}
+	if id := o.contextTraceID(ctx); id != "" {
+		reqID = id
+		problem.TraceID = id
+	}
+	quiet := o.isQuiet(req)
+	identity := o.identity(ctx, req)
+	fp := o.errorFingerprint(e)
+	// The error entry is built before the problem is altered for the response and written once
+	// it is sent so that it includes the body size.
+	var logEntry func(size int)
+	if level := o.logLevel(status, e); level != LevelNone && !quiet && !render && o.allowErrorLog(ctx, req, status) && o.sampleLog(ctx, status, cause) {
+		msg := "error response"
+		if status == http.StatusInternalServerError {
+			msg = "uncaught error"
+		}
+		keyvals := []interface{}{"err", o.truncateLogError(o.scrub(fmt.Sprintf("%+v", e))), "id", reqID, "problem_detail", problem.Detail, "status", status}
+		if problem.Type != "" {
+			keyvals = append(keyvals, "type", problem.Type)
+		}
+		if problem.TraceID != "" {
+			keyvals = append(keyvals, "trace_id", problem.TraceID)
+		}
+		if started, ok := RequestStartTime(ctx); ok {
+			keyvals = append(keyvals, "duration", time.Since(started))
+		}
+		if supportCode != "" {
+			keyvals = append(keyvals, "support_code", supportCode)
+		}
+		if fp != "" {
+			keyvals = append(keyvals, "fingerprint", fp)
+		}
+		if o.shadow {
+			keyvals = append(keyvals, "shadow", true)
+		}
+		keyvals = append(keyvals, o.requestLogFields(req, status)...)
+		keyvals = append(keyvals, identityFields(identity)...)
+		keyvals = append(keyvals, o.logOnlyMetaFields(problem)...)
+		keyvals = append(keyvals, o.logFields(ctx)...)
+		logEntry = func(size int) {
+			o.log(ctx, level, msg, append(keyvals, "body_size", size)...)
+		}
+	}
+	snapshot := o.snapshotProblem(problem, e)
+	if !o.isVerboseFor(ctx, req, cause, status) {
+		o.maskProblem(problem, status, reqID)
+	} else {
+		if status == http.StatusInternalServerError && o.preferServiceErrorDetail {
+			if detail, ok := serviceErrorDetail(e, o.unwrap()); ok {
+				problem.Detail = o.scrub(detail)
 			}
-			return service.Send(ctx, status, respBody)
 		}
+		o.setStackTrace(problem, e)
+		o.setCauses(problem, e)
+	}
+	if !render {
+		o.report(ctx, req, e, problem)
+	}
+	unaliased := status
+	status, ptype = o.aliasStatus(rw.Header(), problem, ptype)
+	o.setDeprecation(rw.Header(), ptype, problem)
+	o.setPredecessorVersion(rw.Header(), ptype)
+	o.setRetryAfter(rw.Header(), e, problem)
+	o.setAllowedMethods(rw.Header(), e, problem)
+	o.setConflictDetail(rw.Header(), problem)
+	setConditionalHeaders(rw.Header(), problem)
+	o.setInstance(ctx, req, problem)
+	o.setInstanceUUID(problem)
+	o.setIdentity(problem, identity)
+	o.redactMeta(problem)
+	o.hideToken(ctx, problem)
+	o.defaultTitle(problem)
+	if supportCode != "" {
+		problem.setMeta("support_code", supportCode)
+		rw.Header().Set("X-Support-Code", supportCode)
+	}
+	if fp != "" {
+		problem.setMeta(FingerprintMetaKey, fp)
+	}
+	o.setTimestamp(problem)
+	status, problem = o.intercept(ctx, req, rw.Header(), status, problem)
+	o.defaultTitle(problem)
+	o.resolveType(problem)
+	o.applyRFC9457(req, problem)
+	o.localize(rw.Header(), req, problem)
+	runHooks(ctx, o.beforeSend, req, problem, e)
+	o.filterDetail(problem)
+	o.truncateDetail(problem)
+	o.dropLogOnlyMeta(problem)
+	o.limitMeta(problem)
+	o.transform(ctx, problem)
+	o.setAuthChallenge(rw.Header(), e, status)
+	if !render {
+		o.observe(ctx, problem, e)
+		o.delayAuthFailure(ctx, unaliased, status)
 	}
+	var err error
+	var size int
+	if isBodiless(req, status) {
+		o.sendBodiless(ctx, rw, status, problem)
+	} else if !o.skipBody(ctx, rw, status) {
+		size, err = o.sendProblem(ctx, service, rw, req, status, mediaType, problem)
+	}
+	if logEntry != nil {
+		logEntry(size)
+	}
+	if render {
+		return err
+	}
+	if !quiet {
+		o.observeLatency(ctx, status)
+		o.recordMetrics(ctx, status, problem, size)
+	}
+	o.audit(ctx, req, status, problem)
+	o.storeProblem(ctx, req, status, reqID, snapshot, problem, e)
+	runHooks(ctx, o.afterSend, req, problem, e)
+	return err
+}
+
+// maskProblem replaces the detail of problem sent with status with the status text, followed by
+// the request ID reqID for internal errors, and drops the meta values that are not public, see
+// WithMetaExposure. The code of server errors is cleared as it may identify internal errors.
+func (o *options) maskProblem(problem *Rfc7807Response, status int, reqID string) {
+	if status == http.StatusInternalServerError {
+		problem.Detail = http.StatusText(http.StatusInternalServerError) + " [" + reqID + "]"
+	} else {
+		problem.Detail = http.StatusText(status)
+	}
+	if status >= 500 {
+		problem.Code = ""
+	}
+	problem.Meta = o.publicMeta(problem.Meta)
 }
 
-// Cause returns the underlying cause of the error, if possible.
-// An error value has a cause if it implements the following
-// interface:
+// newRfc7807Response creates a problem from a goa service error. The meta values of goa error
+// responses and problems are copied so that the problem can be modified without altering the
+// error.
+func newRfc7807Response(err goa.ServiceError) *Rfc7807Response {
+	status := err.ResponseStatus()
+	problem := &Rfc7807Response{
+		Status:  status,
+		Detail:  err.Error(),
+		TraceID: err.Token(),
+	}
+	switch actual := err.(type) {
+	case *goa.ErrorResponse:
+		problem.Detail = actual.Detail
+		problem.Code = actual.Code
+		for k, v := range actual.Meta {
+			problem.setMeta(k, v)
+		}
+		if errs := validationErrors(actual); errs != nil {
+			problem.setMeta(ValidationErrorsMetaKey, errs)
+		}
+	case *Rfc7807Response:
+		problem.Type, problem.Title, problem.Detail, problem.Instance = actual.Type, actual.Title, actual.Detail, actual.Instance
+		problem.Code, problem.Extensions = actual.Code, actual.Extensions
+		for k, v := range actual.Meta {
+			problem.setMeta(k, v)
+		}
+	case *BatchError:
+		problem.Detail = actual.detail()
+		problem.setMeta("items", actual.Items)
+	}
+	return problem
+}
+
+// setMeta sets the meta value with key k, creating the meta map if needed.
+func (r *Rfc7807Response) setMeta(k string, v interface{}) {
+	if r.Meta == nil {
+		r.Meta = make(map[string]interface{})
+	}
+	r.Meta[k] = v
+}
+
+// cause returns the underlying cause of the error, if possible.
+// An error value has a cause if unwrap returns a non nil error for it.
 //
-//     type causer interface {
-//            Cause() error
-//     }
+// The first goa.ServiceError found in the chain is returned so that service errors wrapped with
+// fmt.Errorf("%w", ...) keep their status even when they wrap other errors themselves. The
+// errors of multi-errors such as the ones returned by errors.Join are searched in order for a
+// service error, the multi-error itself is returned if none of them contains one.
 //
-// If the error does not implement Cause, the original error will
+// If the error does not have a cause, the original error will
 // be returned. If the error is nil, nil will be returned without further
 // investigation.
-func cause(e error) error {
-	type causer interface {
-		Cause() error
-	}
-	for {
-		cause, ok := e.(causer)
-		if !ok {
-			break
+func cause(e error, unwrap func(error) error) error {
+	for e != nil {
+		if _, ok := e.(goa.ServiceError); ok {
+			return e
 		}
-		c := cause.Cause()
+		if m, ok := e.(interface{ Unwrap() []error }); ok {
+			for _, err := range m.Unwrap() {
+				if c, ok := cause(err, unwrap).(goa.ServiceError); ok {
+					return c
+				}
+			}
+			return e
+		}
+		c := unwrap(e)
 		if c == nil {
 			break
 		}
@@ -110,3 +420,24 @@ func cause(e error) error {
 	}
 	return e
 }
+
+// unwrapCause returns the error wrapped by e if e implements one of the following interfaces:
+//
+//	type causer interface {
+//		Cause() error
+//	}
+//
+//	type wrapper interface {
+//		Unwrap() error
+//	}
+//
+// It returns nil otherwise.
+func unwrapCause(e error) error {
+	switch w := e.(type) {
+	case interface{ Cause() error }:
+		return w.Cause()
+	case interface{ Unwrap() error }:
+		return w.Unwrap()
+	}
+	return nil
+}
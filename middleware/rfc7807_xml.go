@@ -0,0 +1,247 @@
+package middleware
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Rfc7807XmlNamespace is the XML namespace of problem documents as defined in RFC 7807 Appendix A.
+const Rfc7807XmlNamespace = "urn:ietf:rfc:7807"
+
+// MarshalXML implements xml.Marshaler. It produces a "problem" root element in the RFC 7807
+// namespace. The extension members are encoded as children of the root element sorted by name and
+// the meta values as children of a "meta" element sorted by key so that the output is
+// deterministic. Arrays are encoded as a sequence of "i" elements as shown in RFC 7807 Appendix A
+// and keys that are not valid XML names are encoded as an "entry" element with a "key" attribute.
+func (r Rfc7807Response) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	root := xml.StartElement{Name: xml.Name{Local: "problem"}, Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: Rfc7807XmlNamespace}}}
+	if err := e.EncodeToken(root); err != nil {
+		return err
+	}
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"type", r.Type},
+		{"title", r.Title},
+		{"status", strconv.Itoa(r.Status)},
+		{"detail", r.Detail},
+		{"instance", r.Instance},
+		{"trace_id", r.TraceID},
+		{"code", r.Code},
+	}
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		if err := e.EncodeElement(f.value, xml.StartElement{Name: xml.Name{Local: f.name}}); err != nil {
+			return err
+		}
+	}
+	if r.Extensions != nil {
+		values, err := extensionValues(r.Extensions)
+		if err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := xml.StartElement{Name: xml.Name{Local: k}}
+			if !isXMLName(k) {
+				child = xml.StartElement{Name: xml.Name{Local: "entry"}, Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: k}}}
+			}
+			if err := encodeXMLValue(e, child, values[k]); err != nil {
+				return err
+			}
+		}
+	}
+	if len(r.Meta) > 0 {
+		if err := encodeXMLValue(e, xml.StartElement{Name: xml.Name{Local: "meta"}}, r.Meta); err != nil {
+			return err
+		}
+	}
+	if err := e.EncodeToken(root.End()); err != nil {
+		return err
+	}
+	return e.Flush()
+}
+
+// encodeXMLValue encodes v as the element start. Maps are encoded with one child element per key
+// in sorted key order, slices with one "i" child element per item and structs using their XML
+// struct tags.
+func encodeXMLValue(e *xml.Encoder, start xml.StartElement, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			break
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() || (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) {
+		return e.EncodeElement("", start)
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		keys := make([]string, 0, rv.Len())
+		vals := make(map[string]reflect.Value, rv.Len())
+		for _, k := range rv.MapKeys() {
+			ks := fmt.Sprintf("%v", k.Interface())
+			keys = append(keys, ks)
+			vals[ks] = rv.MapIndex(k)
+		}
+		sort.Strings(keys)
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, k := range keys {
+			child := xml.StartElement{Name: xml.Name{Local: k}}
+			if !isXMLName(k) {
+				child = xml.StartElement{Name: xml.Name{Local: "entry"}, Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: k}}}
+			}
+			if err := encodeXMLValue(e, child, vals[k].Interface()); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			return e.EncodeElement(string(rv.Bytes()), start)
+		}
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if err := encodeXMLValue(e, xml.StartElement{Name: xml.Name{Local: "i"}}, rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case reflect.Struct:
+		return e.EncodeElement(rv.Interface(), start)
+	}
+	return e.EncodeElement(fmt.Sprintf("%v", rv.Interface()), start)
+}
+
+// isXMLName returns true if s can be used as the local name of an XML element.
+func isXMLName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if unicode.IsLetter(c) || c == '_' {
+			continue
+		}
+		if i > 0 && (unicode.IsDigit(c) || c == '-' || c == '.') {
+			continue
+		}
+		return false
+	}
+	// Names starting with "xml" are reserved.
+	return !strings.HasPrefix(strings.ToLower(s), "xml")
+}
+
+// UnmarshalXML implements xml.Unmarshaler. It decodes documents produced by MarshalXML, the
+// children of the "meta" element are decoded as strings, nested maps or slices of "i" elements.
+func (r *Rfc7807Response) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "meta" {
+				v, err := decodeXMLValue(d)
+				if err != nil {
+					return err
+				}
+				if m, ok := v.(map[string]interface{}); ok {
+					r.Meta = m
+				}
+				continue
+			}
+			var s string
+			if err := d.DecodeElement(&s, &t); err != nil {
+				return err
+			}
+			switch t.Name.Local {
+			case "type":
+				r.Type = s
+			case "title":
+				r.Title = s
+			case "status":
+				if r.Status, err = strconv.Atoi(strings.TrimSpace(s)); err != nil {
+					return fmt.Errorf("invalid problem status %q", s)
+				}
+			case "detail":
+				r.Detail = s
+			case "instance":
+				r.Instance = s
+			case "trace_id":
+				r.TraceID = s
+			case "code":
+				r.Code = s
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// decodeXMLValue decodes the content of the element whose start was just read from d. Elements
+// with children are decoded as maps, or as slices if all children are "i" elements, and elements
+// without children as strings.
+func decodeXMLValue(d *xml.Decoder) (interface{}, error) {
+	var (
+		text     strings.Builder
+		keys     []string
+		vals     []interface{}
+		allItems = true
+	)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.StartElement:
+			key := t.Name.Local
+			if key == "entry" {
+				for _, a := range t.Attr {
+					if a.Name.Local == "key" {
+						key = a.Value
+					}
+				}
+			}
+			v, err := decodeXMLValue(d)
+			if err != nil {
+				return nil, err
+			}
+			allItems = allItems && t.Name.Local == "i"
+			keys = append(keys, key)
+			vals = append(vals, v)
+		case xml.EndElement:
+			if len(keys) == 0 {
+				return text.String(), nil
+			}
+			if allItems {
+				return vals, nil
+			}
+			m := make(map[string]interface{}, len(keys))
+			for i, k := range keys {
+				m[k] = vals[i]
+			}
+			return m, nil
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// BlankProblemType is the problem type of problems that convey no semantics beyond their status.
+const BlankProblemType = "about:blank"
+
+// rfc9457Error is the RFC 9457 representation of a field error as an item of the "errors"
+// extension member.
+type rfc9457Error struct {
+	Detail  string `json:"detail" xml:"detail"`
+	Pointer string `json:"pointer,omitempty" xml:"pointer,omitempty"`
+	Field   string `json:"field,omitempty" xml:"field,omitempty"`
+	Code    string `json:"code,omitempty" xml:"code,omitempty"`
+}
+
+// WithRFC9457 makes the handler follow RFC 9457, which obsoletes RFC 7807, when enabled:
+//
+//   - problems without type have the type "about:blank" and the status text as title
+//   - relative types are resolved against the type base URI or the request URI
+//   - the meta values are extension members at the root of the problem rather than children of a
+//     "meta" member and the field errors of validation problems are items of an "errors" member
+//     with "detail" and "pointer" members
+//   - the members are serialized in the order of the RFC followed by the extension members sorted
+//     by name and empty members are omitted
+//
+// It is disabled by default for compatibility with the RFC 7807 representation.
+func WithRFC9457(enabled bool) Option {
+	return func(o *options) {
+		o.rfc9457 = enabled
+	}
+}
+
+// applyRFC9457 applies the type rules of RFC 9457 to problem.
+func (o *options) applyRFC9457(req *http.Request, problem *Rfc7807Response) {
+	if !o.rfc9457 {
+		return
+	}
+	if problem.Type == "" || problem.Type == BlankProblemType {
+		problem.Type = BlankProblemType
+		problem.Title = http.StatusText(problem.Status)
+		return
+	}
+	ref, err := url.Parse(problem.Type)
+	if err != nil || ref.IsAbs() || req.URL == nil {
+		return
+	}
+	base := *req.URL
+	if base.Host == "" {
+		base.Host = req.Host
+	}
+	if base.Scheme == "" {
+		base.Scheme = "http"
+		if req.TLS != nil {
+			base.Scheme = "https"
+		}
+	}
+	problem.Type = base.ResolveReference(ref).String()
+}
+
+// rfc9457Members returns the extension members of problem sorted by name.
+func rfc9457Members(problem *Rfc7807Response) ([]string, map[string]interface{}) {
+	members := make(map[string]interface{}, len(problem.Meta)+1)
+	for k, v := range problem.Meta {
+		switch k {
+		case "type", "title", "status", "detail", "instance":
+			continue
+		}
+		if errs, ok := v.([]FieldError); ok && k == ValidationErrorsMetaKey {
+			items := make([]rfc9457Error, len(errs))
+			for i, fe := range errs {
+				items[i] = rfc9457Error{Detail: fe.Message, Pointer: fe.Pointer, Field: fe.Field, Code: fe.Code}
+			}
+			v = items
+		}
+		members[k] = v
+	}
+	if problem.Extensions != nil {
+		if values, err := extensionValues(problem.Extensions); err == nil {
+			for k, v := range values {
+				members[k] = v
+			}
+		}
+	}
+	if problem.TraceID != "" {
+		members["trace_id"] = problem.TraceID
+	}
+	if problem.Code != "" {
+		members["code"] = problem.Code
+	}
+	names := make([]string, 0, len(members))
+	for k := range members {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names, members
+}
+
+// encodeRFC9457JSON writes the RFC 9457 JSON representation of problem to buf.
+func encodeRFC9457JSON(buf *bytes.Buffer, problem *Rfc7807Response) error {
+	first := true
+	member := func(name string, v interface{}) error {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		n, _ := json.Marshal(name)
+		buf.Write(n)
+		buf.WriteByte(':')
+		buf.Write(b)
+		return nil
+	}
+	buf.WriteByte('{')
+	for _, m := range []struct {
+		name  string
+		value string
+	}{{"type", problem.Type}, {"title", problem.Title}} {
+		if m.value == "" {
+			continue
+		}
+		if err := member(m.name, m.value); err != nil {
+			return err
+		}
+	}
+	if err := member("status", problem.Status); err != nil {
+		return err
+	}
+	for _, m := range []struct {
+		name  string
+		value string
+	}{{"detail", problem.Detail}, {"instance", problem.Instance}} {
+		if m.value == "" {
+			continue
+		}
+		if err := member(m.name, m.value); err != nil {
+			return err
+		}
+	}
+	names, members := rfc9457Members(problem)
+	for _, k := range names {
+		if err := member(k, members[k]); err != nil {
+			return err
+		}
+	}
+	buf.WriteString("}\n")
+	return nil
+}
+
+// encodeRFC9457XML writes the RFC 9457 XML representation of problem to buf, extension members
+// are children of the root element.
+func encodeRFC9457XML(buf *bytes.Buffer, problem *Rfc7807Response) error {
+	e := xml.NewEncoder(buf)
+	root := xml.StartElement{Name: xml.Name{Local: "problem"}, Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: Rfc7807XmlNamespace}}}
+	if err := e.EncodeToken(root); err != nil {
+		return err
+	}
+	for _, f := range []struct {
+		name  string
+		value string
+	}{
+		{"type", problem.Type},
+		{"title", problem.Title},
+		{"status", strconv.Itoa(problem.Status)},
+		{"detail", problem.Detail},
+		{"instance", problem.Instance},
+	} {
+		if f.value == "" {
+			continue
+		}
+		if err := e.EncodeElement(f.value, xml.StartElement{Name: xml.Name{Local: f.name}}); err != nil {
+			return err
+		}
+	}
+	names, members := rfc9457Members(problem)
+	for _, k := range names {
+		child := xml.StartElement{Name: xml.Name{Local: k}}
+		if !isXMLName(k) {
+			child = xml.StartElement{Name: xml.Name{Local: "entry"}, Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: k}}}
+		}
+		if err := encodeXMLValue(e, child, members[k]); err != nil {
+			return err
+		}
+	}
+	if err := e.EncodeToken(root.End()); err != nil {
+		return err
+	}
+	return e.Flush()
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/goadesign/goa"
+)
+
+// routeOptions contains the settings overridden for the actions of a controller.
+type routeOptions struct {
+	// controller and action identify the route, action is empty for all the actions of the
+	// controller.
+	controller, action string
+	// opts are the overriding options.
+	opts []Option
+	// resolved contains the handler settings followed by opts.
+	resolved *options
+}
+
+// WithRouteOptions overrides the handler settings with opts for the requests handled by the given
+// action of the goa controller, or by all its actions if action is empty, as reported by
+// goa.ContextController and goa.ContextAction. It lets the public and internal API groups of a
+// service have different error policies, for example:
+//
+//	middleware.Rfc7807Handler(service, false,
+//		middleware.WithTypeBaseURI("https://example.com/probs/"),
+//		middleware.WithRouteOptions("admin", "",
+//			middleware.WithVerbose(true),
+//			middleware.WithTypeBaseURI("https://internal.example.com/probs/"),
+//		),
+//	)
+//
+// The settings of the route are the handler settings followed by opts, the settings of an action
+// take precedence over the settings of its controller. Routes are not known to HTTPMiddleware
+// which always uses the handler settings.
+func WithRouteOptions(controller, action string, opts ...Option) Option {
+	return func(o *options) {
+		o.routes = append(o.routes, routeOptions{controller: controller, action: action, opts: opts})
+	}
+}
+
+// forRoute returns the settings of the route of the request with context ctx.
+func (o *options) forRoute(ctx context.Context) *options {
+	if len(o.routes) == 0 {
+		return o
+	}
+	controller, action := goa.ContextController(ctx), goa.ContextAction(ctx)
+	if controller == "" {
+		return o
+	}
+	var match *options
+	for i := range o.routes {
+		r := &o.routes[i]
+		if r.controller != controller {
+			continue
+		}
+		if r.action == action {
+			return r.resolved
+		}
+		if r.action == "" {
+			match = r.resolved
+		}
+	}
+	if match != nil {
+		return match
+	}
+	return o
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import "net/http"
+
+// DefaultSecurityHeaders is a set of security headers suitable for most APIs that may be given to
+// WithSecurityHeaders.
+var DefaultSecurityHeaders = map[string]string{
+	"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+	"X-Content-Type-Options":    "nosniff",
+	"X-Frame-Options":           "DENY",
+}
+
+// WithSecurityHeaders sets the given headers on all problem responses. Error responses often
+// bypass the middleware that sets security headers on successful responses, this option makes
+// sure they are present anyway. "X-Content-Type-Options: nosniff" is particularly relevant as it
+// prevents browsers from interpreting problem bodies as another content type.
+func WithSecurityHeaders(headers map[string]string) Option {
+	return func(o *options) {
+		o.securityHeaders = headers
+	}
+}
+
+// WithNoStore sets the "Cache-Control: no-store" and "Pragma: no-cache" headers on all problem
+// responses, replacing the caching headers set by downstream handlers, so that transient errors are
+// never cached by browsers or intermediaries. Combine it with WithSecurityHeaders to also prevent
+// content sniffing.
+func WithNoStore(enabled bool) Option {
+	return func(o *options) {
+		o.noStore = enabled
+	}
+}
+
+// setSecurityHeaders sets the configured security and caching headers on h.
+func (o *options) setSecurityHeaders(h http.Header) {
+	for k, v := range o.securityHeaders {
+		h.Set(k, v)
+	}
+	if o.noStore {
+		h.Set("Cache-Control", "no-store")
+		h.Set("Pragma", "no-cache")
+		h.Del("Expires")
+		h.Del("ETag")
+		h.Del("Last-Modified")
+	}
+}
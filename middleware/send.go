@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"sync"
+
+	"github.com/goadesign/goa"
+)
+
+// maxPooledBufferSize is the capacity above which serialization buffers are not returned to the
+// pool so that a few huge problems do not pin memory.
+const maxPooledBufferSize = 64 << 10
+
+// bufferPool recycles the buffers problems are serialized into.
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// encoderContentTypes maps the problem media types to the content types of the service encoders
+// used to serialize them.
+var encoderContentTypes = map[string]string{
+	Rfc7807JsonMediaIdentifier: "application/json",
+}
+
+// WithResponseSizeObserver sets a function called with the status and the size in bytes of the
+// serialized body of each problem response, for example to record a size metric.
+func WithResponseSizeObserver(f func(status int, bytes int)) Option {
+	return func(o *options) {
+		o.responseSizeObserver = f
+	}
+}
+
+// WithXMLDeclaration controls whether XML problems start with the
+// <?xml version="1.0" encoding="UTF-8"?> declaration, it is included by default.
+func WithXMLDeclaration(enabled bool) Option {
+	return func(o *options) {
+		o.xmlDeclaration = enabled
+	}
+}
+
+// sendProblem serializes problem into a pooled buffer, see encodeProblem, and writes the result
+// with the given status. When the serialization fails the failure is logged and a minimal JSON
+// problem with the status, title, detail and trace ID of problem is written instead so that
+// clients still get a problem. The status and length of the goa response data stored in the
+// context are updated so that goa logging and metrics report the problem response accurately
+// even when rw is not the response data itself, except in shadow mode and for the problems sent as
+// WebSocket close frames or Server-Sent Events, see renderJSON, which are not passed to the
+// response size observer and the dead letter sink either. Problems whose body exceeds the
+// maximum body size are replaced with a minimal problem, see WithMaxBodySize. sendProblem returns
+// the size of the body written.
+func (o *options) sendProblem(ctx context.Context, service *goa.Service, rw http.ResponseWriter, req *http.Request, status int, mediaType string, problem *Rfc7807Response) (int, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	err := o.encodeProblem(buf, service, mediaType, problem)
+	if err == nil && o.maxBodySize > 0 && buf.Len() > o.maxBodySize {
+		o.log(ctx, LevelWarn, "problem body too large", "body_size", buf.Len(), "max_body_size", o.maxBodySize, "status", status, "trace_id", problem.TraceID)
+		err = o.encodeMinimalProblem(buf, service, mediaType, problem)
+	}
+	if err != nil {
+		o.log(ctx, LevelError, "problem serialization failed", "err", err, "status", status, "media_type", mediaType)
+		buf.Reset()
+		mediaType = Rfc7807JsonMediaIdentifier
+		rw.Header().Set("Content-Type", o.contentType(mediaType))
+		encodeFastJSON(buf, &Rfc7807Response{
+			Type:     problem.Type,
+			Title:    problem.Title,
+			Status:   problem.Status,
+			Detail:   problem.Detail,
+			Instance: problem.Instance,
+			TraceID:  problem.TraceID,
+			Code:     problem.Code,
+		})
+	}
+	rw.WriteHeader(status)
+	n, err := rw.Write(buf.Bytes())
+	_, shadow := rw.(*shadowWriter)
+	_, captured := rw.(*captureWriter)
+	if resp := goa.ContextResponse(ctx); resp != nil && resp != rw && !shadow && !captured {
+		resp.Status = status
+		resp.Length += n
+	}
+	if captured {
+		return buf.Len(), err
+	}
+	if o.responseSizeObserver != nil {
+		o.responseSizeObserver(status, buf.Len())
+	}
+	o.deadLetter(req, status, o.contentType(mediaType), problem, buf.Bytes())
+	return buf.Len(), err
+}
+
+// encodeProblem serializes problem into buf using the serializer registered for mediaType, the
+// RFC 9457 encoders in RFC 9457 mode, encoding/xml for XML problems, encoding/json for renamed
+// JSON problems, the fast path for simple JSON problems or the service encoder of the
+// corresponding content type, or encoding/json without service. XML problems do not require an
+// XML service encoder so that they are available to JSON only services.
+func (o *options) encodeProblem(buf *bytes.Buffer, service *goa.Service, mediaType string, problem *Rfc7807Response) error {
+	if mediaType == Rfc7807XmlMediaIdentifier && o.xmlDeclaration {
+		buf.WriteString(xml.Header)
+	}
+	if f, ok := o.serializers[mediaType]; ok {
+		return f(buf, problem)
+	}
+	switch {
+	case o.rfc9457 && mediaType == Rfc7807JsonMediaIdentifier:
+		return encodeRFC9457JSON(buf, problem)
+	case o.rfc9457 && mediaType == Rfc7807XmlMediaIdentifier:
+		return encodeRFC9457XML(buf, problem)
+	case mediaType == Rfc7807XmlMediaIdentifier:
+		return xml.NewEncoder(buf).Encode(problem)
+	case len(o.jsonFieldNames) > 0 && mediaType == Rfc7807JsonMediaIdentifier:
+		return encodeRenamedJSON(buf, problem, o.jsonFieldNames)
+	case o.fastPathApplies(mediaType, problem):
+		encodeFastJSON(buf, problem)
+		return nil
+	case service == nil:
+		return json.NewEncoder(buf).Encode(problem)
+	}
+	return service.Encoder.Encode(problem, buf, encoderContentTypes[mediaType])
+}
+
+// getBuffer returns an empty buffer from the pool.
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// putBuffer returns buf to the pool unless it grew too large.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
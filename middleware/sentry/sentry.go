@@ -0,0 +1,51 @@
+// Package sentry reports the internal errors of the RFC 7807 middleware to Sentry
+// (github.com/getsentry/sentry-go).
+package sentry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	getsentry "github.com/getsentry/sentry-go"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+// reporter reports errors with a Sentry hub.
+type reporter struct{}
+
+// Reporter returns a middleware.ErrorReporter capturing errors with the hub of the request
+// context, as set by the sentry-go net/http integration, or a clone of the current hub. Sentry
+// extracts the stack traces of errors created with github.com/pkg/errors and similar packages,
+// the stack trace of *middleware.PanicError errors is added as the "panic_stack" extra. Events
+// are tagged with the problem status and trace ID.
+func Reporter() middleware.ErrorReporter {
+	return reporter{}
+}
+
+// Report implements middleware.ErrorReporter.
+func (reporter) Report(ctx context.Context, req *http.Request, err error, problem *middleware.Rfc7807Response) string {
+	hub := getsentry.GetHubFromContext(ctx)
+	if hub == nil {
+		hub = getsentry.CurrentHub().Clone()
+	}
+	var id *getsentry.EventID
+	hub.WithScope(func(scope *getsentry.Scope) {
+		scope.SetRequest(req)
+		scope.SetTag("problem.status", strconv.Itoa(problem.Status))
+		if problem.TraceID != "" {
+			scope.SetTag("trace_id", problem.TraceID)
+		}
+		var pe *middleware.PanicError
+		if errors.As(err, &pe) {
+			scope.SetExtra("panic_stack", string(pe.Stack))
+		}
+		id = hub.CaptureException(err)
+	})
+	if id == nil {
+		return ""
+	}
+	return string(*id)
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import "github.com/goadesign/goa"
+
+// WithPreferServiceErrorDetail makes verbose internal error responses use the detail of the
+// first goa.ServiceError found in the chain of wrapped errors rather than the message of the
+// outermost error, which is often an opaque wrapper message.
+func WithPreferServiceErrorDetail(enabled bool) Option {
+	return func(o *options) {
+		o.preferServiceErrorDetail = enabled
+	}
+}
+
+// serviceErrorDetail returns the detail of the first goa.ServiceError in the chain of errors
+// wrapped by e and true, or false if there is no service error in the chain.
+func serviceErrorDetail(e error, unwrap func(error) error) (string, bool) {
+	se, ok := cause(e, unwrap).(goa.ServiceError)
+	if !ok {
+		return "", false
+	}
+	if resp, ok := se.(*goa.ErrorResponse); ok {
+		return resp.Detail, true
+	}
+	return se.Error(), true
+}
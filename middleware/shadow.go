@@ -0,0 +1,39 @@
+package middleware
+
+import "net/http"
+
+// shadowWriter is the response writer problems are sent to in shadow mode, it discards them.
+type shadowWriter struct {
+	header http.Header
+	status int
+}
+
+// WithShadowMode enables the shadow mode used to validate the configuration of the handler in
+// production before switching the response format: the Rfc7807Handler middleware computes,
+// logs and records the metrics of the problems as usual but discards them and returns the errors
+// of the downstream handlers unchanged so that the goa ErrorHandler middleware registered before
+// it keeps sending the responses. The log entries contain the shadow key, auth failures are not
+// delayed and HTTPMiddleware is not affected.
+func WithShadowMode(enabled bool) Option {
+	return func(o *options) {
+		o.shadow = enabled
+	}
+}
+
+// Header returns the headers of the discarded response.
+func (w *shadowWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = make(http.Header)
+	}
+	return w.header
+}
+
+// Write discards b.
+func (w *shadowWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// WriteHeader records the status of the discarded response.
+func (w *shadowWriter) WriteHeader(status int) {
+	w.status = status
+}
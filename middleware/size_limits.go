@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/goadesign/goa"
+)
+
+const (
+	// TruncatedMetaKey is the key of the meta value set to true when the detail or the meta
+	// values of a problem were truncated.
+	TruncatedMetaKey = "truncated"
+	// truncationMarker is appended to truncated strings.
+	truncationMarker = "..."
+)
+
+// WithMaxDetailLength sets the maximum length in characters of the detail of problems, longer
+// details are truncated, the truncation marker "..." included, and the problem gets the
+// TruncatedMetaKey meta value. The detail is truncated before it is logged and again before the
+// problem is sent. 0 disables the limit.
+func WithMaxDetailLength(n int) Option {
+	return func(o *options) {
+		o.maxDetailLength = n
+	}
+}
+
+// WithMaxMetaSize sets the maximum size in bytes of the JSON representation of the meta values of
+// problems. When the limit is exceeded the meta values are kept in key order as long as they fit
+// and the others are dropped, the problem gets the TruncatedMetaKey meta value. 0 disables the
+// limit.
+func WithMaxMetaSize(n int) Option {
+	return func(o *options) {
+		o.maxMetaSize = n
+	}
+}
+
+// WithMaxLogErrorLength sets the maximum length in characters of the error written in log
+// entries, e.g. the output of %+v for errors carrying huge stack traces, longer errors are
+// truncated with the truncation marker "...". 0 disables the limit.
+func WithMaxLogErrorLength(n int) Option {
+	return func(o *options) {
+		o.maxLogErrorLength = n
+	}
+}
+
+// WithMaxBodySize sets the maximum size in bytes of the serialized body of problems, for example
+// to guard against pathological payloads such as huge validation dumps. Problems whose body is
+// larger are logged at the warn level with their body size and sent as a minimal problem without
+// meta values and extensions, and without detail if it is still too large, with the
+// TruncatedMetaKey meta value. 0 disables the limit.
+func WithMaxBodySize(n int) Option {
+	return func(o *options) {
+		o.maxBodySize = n
+	}
+}
+
+// encodeMinimalProblem serializes into buf the minimal problem sent in place of problem, whose
+// body exceeds the maximum body size.
+func (o *options) encodeMinimalProblem(buf *bytes.Buffer, service *goa.Service, mediaType string, problem *Rfc7807Response) error {
+	minimal := &Rfc7807Response{
+		Type:     problem.Type,
+		Title:    problem.Title,
+		Status:   problem.Status,
+		Detail:   problem.Detail,
+		Instance: problem.Instance,
+		TraceID:  problem.TraceID,
+		Code:     problem.Code,
+		Meta:     map[string]interface{}{TruncatedMetaKey: true},
+	}
+	buf.Reset()
+	if err := o.encodeProblem(buf, service, mediaType, minimal); err != nil || buf.Len() <= o.maxBodySize {
+		return err
+	}
+	minimal.Detail = ""
+	buf.Reset()
+	return o.encodeProblem(buf, service, mediaType, minimal)
+}
+
+// truncateDetail truncates the detail of problem to the maximum detail length.
+func (o *options) truncateDetail(problem *Rfc7807Response) {
+	if d, ok := truncate(problem.Detail, o.maxDetailLength); ok {
+		problem.Detail = d
+		problem.setMeta(TruncatedMetaKey, true)
+	}
+}
+
+// limitMeta drops the meta values of problem that do not fit in the maximum meta size.
+func (o *options) limitMeta(problem *Rfc7807Response) {
+	if o.maxMetaSize <= 0 || len(problem.Meta) == 0 {
+		return
+	}
+	if b, err := json.Marshal(problem.Meta); err != nil || len(b) <= o.maxMetaSize {
+		return
+	}
+	keys := make([]string, 0, len(problem.Meta))
+	for k := range problem.Meta {
+		if k != TruncatedMetaKey {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	kept := map[string]interface{}{TruncatedMetaKey: true}
+	size := len(`{"truncated":true}`)
+	for _, k := range keys {
+		b, err := json.Marshal(map[string]interface{}{k: problem.Meta[k]})
+		if err != nil {
+			continue
+		}
+		// the member is added after a comma in place of the braces of its own object
+		if n := len(b) - 1; size+n <= o.maxMetaSize {
+			kept[k] = problem.Meta[k]
+			size += n
+		}
+	}
+	problem.Meta = kept
+}
+
+// truncateLogError truncates the logged error s to the maximum log error length.
+func (o *options) truncateLogError(s string) string {
+	t, _ := truncate(s, o.maxLogErrorLength)
+	return t
+}
+
+// truncate returns s truncated to n characters, the truncation marker included, and true if s is
+// longer than n characters. s is returned unchanged if n is not positive.
+func truncate(s string, n int) (string, bool) {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s, false
+	}
+	keep := n - len(truncationMarker)
+	if keep < 0 {
+		keep = 0
+	}
+	i := 0
+	for j := range s {
+		if keep == 0 {
+			i = j
+			break
+		}
+		keep--
+	}
+	return s[:i] + truncationMarker, true
+}
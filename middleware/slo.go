@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// SLOConfig configures an SLOReporter.
+	SLOConfig struct {
+		// Window is the duration of the windows the problem responses are counted in, it
+		// defaults to one minute. Windows are aligned on multiples of the duration.
+		Window time.Duration
+		// Statuses lists the statuses of the problem responses burning the error budget, all the
+		// 5xx statuses when nil.
+		Statuses []int
+		// Types lists the types of the problem responses burning the error budget whatever their
+		// status.
+		Types []string
+		// ExcludedTypes lists the types of the problem responses that never burn the error
+		// budget, e.g. MaintenanceType for planned maintenance windows.
+		ExcludedTypes []string
+		// OnWindow is called with each window once it has ended, if not nil. Windows end when a
+		// problem is recorded or Current is called after their end, windows without problem
+		// responses are not reported.
+		OnWindow func(SLOWindow)
+		// Clock tells the time Current is called at, it defaults to the system clock. Problems
+		// are recorded at the time of the handler clock, so both are set to the same clock in
+		// tests, see WithClock.
+		Clock Clock
+	}
+
+	// SLOWindow holds the counts of the problem responses of a window.
+	SLOWindow struct {
+		// Start is the start of the window.
+		Start time.Time
+		// End is the end of the window.
+		End time.Time
+		// Problems is the number of problem responses sent during the window.
+		Problems int
+		// Burning is the number of problem responses burning the error budget.
+		Burning int
+	}
+
+	// SLOReporter classifies the problem responses as burning the error budget of the service or
+	// not and counts them per window so that teams can wire error responses into burn-rate
+	// alerts, see WithSLOReporter. It is safe for concurrent use.
+	SLOReporter struct {
+		config  SLOConfig
+		mu      sync.Mutex
+		current SLOWindow
+	}
+)
+
+// NewSLOReporter returns a reporter configured with c.
+func NewSLOReporter(c SLOConfig) *SLOReporter {
+	if c.Window <= 0 {
+		c.Window = time.Minute
+	}
+	if c.Clock == nil {
+		c.Clock = ClockFunc(time.Now)
+	}
+	return &SLOReporter{config: c}
+}
+
+// WithSLOReporter makes the handler record the problem responses in r. The metrics hook then
+// receives the classification of each problem in ProblemLabels.BurnsBudget. The problem responses
+// of quiet requests are not recorded, see WithQuietPaths. Problems are recorded at the time of the
+// handler clock set with WithClock.
+func WithSLOReporter(r *SLOReporter) Option {
+	return func(o *options) {
+		o.sloReporter = r
+	}
+}
+
+// Burns returns true if the problem response described by labels burns the error budget: its
+// type is not excluded and its type or status is configured as burning.
+func (r *SLOReporter) Burns(labels ProblemLabels) bool {
+	for _, t := range r.config.ExcludedTypes {
+		if labels.Type == t {
+			return false
+		}
+	}
+	for _, t := range r.config.Types {
+		if labels.Type == t {
+			return true
+		}
+	}
+	if r.config.Statuses == nil {
+		return labels.Status >= 500 && labels.Status <= 599
+	}
+	for _, s := range r.config.Statuses {
+		if labels.Status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Current returns the counts of the window in progress.
+func (r *SLOReporter) Current() SLOWindow {
+	r.mu.Lock()
+	ended, ok := r.roll(r.config.Clock.Now())
+	current := r.current
+	r.mu.Unlock()
+	if ok {
+		r.config.OnWindow(ended)
+	}
+	return current
+}
+
+// record counts the problem response described by labels sent at now and returns true if it burns
+// the error budget.
+func (r *SLOReporter) record(labels ProblemLabels, now time.Time) bool {
+	burns := r.Burns(labels)
+	r.mu.Lock()
+	ended, ok := r.roll(now)
+	r.current.Problems++
+	if burns {
+		r.current.Burning++
+	}
+	r.mu.Unlock()
+	if ok {
+		r.config.OnWindow(ended)
+	}
+	return burns
+}
+
+// roll starts the window containing now if the current window has ended and returns the ended
+// window if it must be reported to the OnWindow function. It must be called with the mutex held.
+func (r *SLOReporter) roll(now time.Time) (SLOWindow, bool) {
+	if now.Before(r.current.End) {
+		return SLOWindow{}, false
+	}
+	ended := r.current
+	start := now.Truncate(r.config.Window)
+	r.current = SLOWindow{Start: start, End: start.Add(r.config.Window)}
+	return ended, r.config.OnWindow != nil && ended.Problems > 0
+}
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/goadesign/goa"
+)
+
+// EventStreamMediaIdentifier is the media type of Server-Sent Events streams.
+const EventStreamMediaIdentifier = "text/event-stream"
+
+// ProblemEvent is the name of the event carrying the problems of failed Server-Sent Events
+// streams.
+const ProblemEvent = "problem"
+
+// captureWriter is the response writer problems sent in another form than an HTTP response are
+// rendered with.
+type captureWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// Header returns the headers of the rendered problem.
+func (w *captureWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = make(http.Header)
+	}
+	return w.header
+}
+
+// Write records b.
+func (w *captureWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// WriteHeader records the status of the rendered problem.
+func (w *captureWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+// SSEWriter is a response writer for Server-Sent Events streams. When the handler writing the
+// stream fails after the stream started, the Rfc7807Handler middleware and HTTPMiddleware end the
+// stream with a terminal "problem" event whose data is the JSON problem instead of only logging
+// the error and dropping the connection, for example:
+//
+//	func (c *EventsController) Stream(ctx *app.StreamEventsContext) error {
+//		w := middleware.NewSSEWriter(ctx.ResponseData)
+//		for ev := range events {
+//			if err := process(ev); err != nil {
+//				return err // sent as "event: problem"
+//			}
+//			w.Event("update", ev.JSON())
+//		}
+//		return nil
+//	}
+//
+// The streams are recognized by their text/event-stream Content-Type so that streams written
+// without SSEWriter also get the problem event.
+type SSEWriter struct {
+	http.ResponseWriter
+}
+
+// NewSSEWriter returns a writer of Server-Sent Events to rw, it sets the Content-Type and
+// Cache-Control headers of the stream.
+func NewSSEWriter(rw http.ResponseWriter) *SSEWriter {
+	rw.Header().Set("Content-Type", EventStreamMediaIdentifier)
+	rw.Header().Set("Cache-Control", "no-cache")
+	return &SSEWriter{ResponseWriter: rw}
+}
+
+// Event writes an event with the given name, the default "message" event if empty, and data then
+// flushes the stream. Multi-line data is sent as multiple data lines.
+func (w *SSEWriter) Event(name, data string) error {
+	var buf bytes.Buffer
+	writeEvent(&buf, name, data)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
+
+// Flush flushes the stream if the underlying writer supports it.
+func (w *SSEWriter) Flush() {
+	flush(w.ResponseWriter)
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *SSEWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// isEventStream returns true if the response written with rw is a Server-Sent Events stream.
+func isEventStream(rw http.ResponseWriter) bool {
+	mt, _, err := mime.ParseMediaType(rw.Header().Get("Content-Type"))
+	return err == nil && mt == EventStreamMediaIdentifier
+}
+
+// sendProblemEvent ends the Server-Sent Events stream written with rw, which is committed, with a
+// problem event for e. The error is logged like the errors occurring after the response is
+// committed, the committed response action does not apply.
+func (p *ProblemHandler) sendProblemEvent(ctx context.Context, rw http.ResponseWriter, req *http.Request, e error) error {
+	o := p.settings(ctx).forRoute(ctx)
+	if _, ok := RequestID(ctx); !ok {
+		ctx = WithRequestID(ctx, o.newTraceID(ctx, req))
+	}
+	o.logCommitted(ctx, req, e)
+	_, body := p.renderJSON(req.WithContext(ctx), e)
+	var buf bytes.Buffer
+	writeEvent(&buf, ProblemEvent, string(bytes.TrimSpace(body)))
+	if _, err := rw.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	flush(rw)
+	return nil
+}
+
+// renderJSON renders err as a JSON problem like sendError does for req and returns its status
+// and body. The problem is only rendered, the side effects of sendError such as logging, metrics,
+// audit records and the authentication failure delay do not apply.
+func (p *ProblemHandler) renderJSON(req *http.Request, err error) (int, []byte) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept", Rfc7807JsonMediaIdentifier)
+	var w captureWriter
+	p.sendError(req.Context(), &w, req, err)
+	return w.status, w.body.Bytes()
+}
+
+// flush flushes rw if it or one of the writers it wraps, e.g. the writer of goa.ResponseData,
+// supports it.
+func flush(rw http.ResponseWriter) {
+	for rw != nil {
+		if f, ok := rw.(http.Flusher); ok {
+			f.Flush()
+			return
+		}
+		switch w := rw.(type) {
+		case *goa.ResponseData:
+			rw = w.ResponseWriter
+		case interface{ Unwrap() http.ResponseWriter }:
+			rw = w.Unwrap()
+		default:
+			return
+		}
+	}
+}
+
+// writeEvent writes the event with the given name and data to buf.
+func writeEvent(buf *bytes.Buffer, name, data string) {
+	if name != "" {
+		buf.WriteString("event: " + name + "\n")
+	}
+	for _, line := range strings.Split(data, "\n") {
+		buf.WriteString("data: " + strings.TrimSuffix(line, "\r") + "\n")
+	}
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"reflect"
+	"runtime"
+)
+
+// StackMetaKey is the meta key of the stack trace of errors, see WithStackTrace.
+const StackMetaKey = "stack"
+
+// StackFrame is a frame of the stack trace of an error.
+type StackFrame struct {
+	// Func is the fully qualified name of the function.
+	Func string `json:"func" xml:"func"`
+	// File is the path of the source file.
+	File string `json:"file" xml:"file"`
+	// Line is the line number in the source file.
+	Line int `json:"line" xml:"line"`
+}
+
+// WithStackTrace makes the problems whose details are sent, e.g. in verbose mode, carry the stack
+// trace of the error as an array of {func, file, line} objects in the "stack" meta value so that
+// clients do not need to parse the %+v representation of the error. The stack trace is the one
+// of the deepest error with a StackTrace method returning a slice of program counters, such as
+// the errors created with github.com/pkg/errors, or of the panic recovered by Recover.
+func WithStackTrace(enabled bool) Option {
+	return func(o *options) {
+		o.stackTrace = enabled
+	}
+}
+
+// setStackTrace adds the stack trace of e to problem if enabled.
+func (o *options) setStackTrace(problem *Rfc7807Response, e error) {
+	if !o.stackTrace {
+		return
+	}
+	if frames := stackFrames(e, o.unwrap()); len(frames) > 0 {
+		problem.setMeta(StackMetaKey, frames)
+	}
+}
+
+// stackFrames returns the stack trace of the deepest error with one in the chain of errors
+// wrapped by e.
+func stackFrames(e error, unwrap func(error) error) []StackFrame {
+	var pcs []uintptr
+	for ; e != nil; e = unwrap(e) {
+		if p, ok := e.(*PanicError); ok && len(p.Callers) > 0 {
+			pcs = p.Callers
+		} else if s, ok := stackTraceOf(e); ok {
+			pcs = s
+		}
+	}
+	if len(pcs) == 0 {
+		return nil
+	}
+	var frames []StackFrame
+	it := runtime.CallersFrames(pcs)
+	for {
+		f, more := it.Next()
+		if f.Function != "" {
+			frames = append(frames, StackFrame{Func: f.Function, File: f.File, Line: f.Line})
+		}
+		if !more {
+			return frames
+		}
+	}
+}
+
+// stackTraceOf returns the program counters returned by the StackTrace method of e, if any. The
+// method must return a slice of unsigned integers, the frames of github.com/pkg/errors are return
+// addresses like the program counters returned by runtime.Callers.
+func stackTraceOf(e error) ([]uintptr, bool) {
+	m := reflect.ValueOf(e).MethodByName("StackTrace")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return nil, false
+	}
+	t := m.Type().Out(0)
+	if t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.Uintptr {
+		return nil, false
+	}
+	s := m.Call(nil)[0]
+	pcs := make([]uintptr, s.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(s.Index(i).Uint())
+	}
+	return pcs, len(pcs) > 0
+}
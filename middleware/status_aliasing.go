@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// statusCodes are the codes of the goa errors sent with each status, see aliasStatus.
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            "bad_request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusNotFound:              "not_found",
+	http.StatusMethodNotAllowed:      "method_not_allowed",
+	http.StatusRequestEntityTooLarge: "request_too_large",
+	http.StatusInternalServerError:   "internal",
+}
+
+// WithStatusAliasing makes the handler respond with the status aliases[s] in place of the status
+// s of the error. Aliased problems get the code, type and title of a genuine error of the alias
+// status, their detail is reset to the status text and their meta values are dropped except for
+// the error report ID, so that clients cannot tell aliased responses from genuine ones. For
+// example mapping 403 to 404 prevents resource enumeration by making forbidden resources look
+// like they do not exist.
+func WithStatusAliasing(aliases map[int]int) Option {
+	return func(o *options) {
+		o.statusAliases = aliases
+	}
+}
+
+// aliasStatus applies the configured status alias to problem and returns the resulting status and
+// problem type, t if problem is not aliased. The Allow header h set for 405 problems is removed.
+func (o *options) aliasStatus(h http.Header, problem *Rfc7807Response, t ProblemType) (int, ProblemType) {
+	alias, ok := o.statusAliases[problem.Status]
+	if !ok || alias == problem.Status {
+		return problem.Status, t
+	}
+	if problem.Status == http.StatusMethodNotAllowed {
+		h.Del("Allow")
+	}
+	problem.Status = alias
+	problem.Code = aliasCode(alias)
+	problem.Type = ""
+	problem.Title = http.StatusText(alias)
+	problem.Detail = http.StatusText(alias)
+	t, ok = o.lookupProblemType(problem.Code)
+	if ok {
+		problem.Type = t.VersionedURI()
+		if t.Title != "" {
+			problem.Title = t.Title
+		}
+	}
+	id, reported := problem.Meta[ReportIDMetaKey]
+	problem.Meta = nil
+	if reported {
+		problem.setMeta(ReportIDMetaKey, id)
+	}
+	return alias, t
+}
+
+// aliasCode returns the code of the goa errors sent with status, the status text in snake case
+// for the statuses goa does not define errors for, e.g. "forbidden".
+func aliasCode(status int) string {
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	return strings.Replace(strings.ToLower(http.StatusText(status)), " ", "_", -1)
+}
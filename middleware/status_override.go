@@ -0,0 +1,46 @@
+package middleware
+
+import "sync"
+
+var (
+	// statusOverridesMu protects statusOverrides.
+	statusOverridesMu sync.RWMutex
+	// statusOverrides contains the statuses set with SetStatusFor indexed by error code.
+	statusOverrides = make(map[string]int)
+)
+
+// SetStatusFor makes all the handlers respond to the goa.ServiceError errors with the given code
+// with status in place of the status of the error, e.g. SetStatusFor("invalid_request", 422)
+// sends 422 Unprocessable Entity problems for the validation errors, such as out of range values,
+// that goa reports as 400 Bad Request. The code is the Code of goa.ErrorResponse errors, e.g.
+// "invalid_request" or "invalid_encoding", the type of problems or the token of other service
+// errors, the codes of the field errors of validation problems such as "invalid_range" do not
+// match. A zero status removes the override.
+func SetStatusFor(code string, status int) {
+	statusOverridesMu.Lock()
+	defer statusOverridesMu.Unlock()
+	if status == 0 {
+		delete(statusOverrides, code)
+		return
+	}
+	statusOverrides[code] = status
+}
+
+// WithStatusOverrides sets status overrides specific to the handler indexed by error code, they
+// take precedence over the ones set with SetStatusFor.
+func WithStatusOverrides(overrides map[string]int) Option {
+	return func(o *options) {
+		o.statusOverrides = overrides
+	}
+}
+
+// overrideStatus returns the status overriding the status of the errors with the given code.
+func (o *options) overrideStatus(code string) (int, bool) {
+	if s, ok := o.statusOverrides[code]; ok {
+		return s, true
+	}
+	statusOverridesMu.RLock()
+	defer statusOverridesMu.RUnlock()
+	s, ok := statusOverrides[code]
+	return s, ok
+}
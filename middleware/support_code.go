@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"io"
+)
+
+// supportCodeAlphabet contains the characters of support codes, characters that are easily
+// confused with one another such as 0 and O or 1 and I are excluded.
+const supportCodeAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// WithSupportCodeGenerator sets the function used to mint a support code for each error response.
+// The code is sent to the client in the "support_code" meta value and the X-Support-Code header
+// and is included in the error log entry so that support staff can find the log from the code
+// reported by users. NewSupportCode may be used as generator.
+func WithSupportCodeGenerator(f func() string) Option {
+	return func(o *options) {
+		o.supportCodeGenerator = f
+	}
+}
+
+// NewSupportCode returns a random 6 characters long code made of uppercase letters and digits
+// that is easy to read out and type.
+func NewSupportCode() string {
+	b := make([]byte, 6)
+	io.ReadFull(rand.Reader, b)
+	for i := range b {
+		b[i] = supportCodeAlphabet[int(b[i])%len(supportCodeAlphabet)]
+	}
+	return string(b)
+}
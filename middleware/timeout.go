@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/goadesign/goa"
+)
+
+// TimeoutMetaKey is the meta key of the time budget of the requests that timed out, see Timeout.
+const TimeoutMetaKey = "timeout"
+
+// timeoutError is the error of a request that did not complete within the budget of the Timeout
+// middleware.
+type timeoutError struct {
+	wrappedError
+	budget time.Duration
+}
+
+// Timeout returns a middleware that gives the downstream handlers a context whose deadline is d
+// from now so that the work they do with it is canceled once the deadline is exceeded. Requests
+// that exceed the deadline fail with a 504 Gateway Timeout problem of type DeadlineExceededType
+// carrying the budget d in the timeout meta value, e.g. "2s", unless the handler returned a goa
+// service error. Handlers must honor the cancellation of the context, the middleware does not
+// abandon them. When the response was already committed the problem is not written, the handler
+// takes the action set with WithCommittedResponse instead, and requests that completed
+// successfully are left untouched. Deadlines of the parent context that are shorter than d are not
+// reported as timeouts of the middleware, a zero or negative d disables the middleware.
+func Timeout(d time.Duration) goa.Middleware {
+	return func(h goa.Handler) goa.Handler {
+		if d <= 0 {
+			return h
+		}
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			parent := ctx
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			err := h(ctx, rw, req.WithContext(ctx))
+			if ctx.Err() != context.DeadlineExceeded || parent.Err() != nil {
+				return err
+			}
+			if err == nil {
+				if isCommitted(rw) {
+					return nil
+				}
+				err = context.DeadlineExceeded
+			}
+			return &timeoutError{wrappedError: wrappedError{err}, budget: d}
+		}
+	}
+}
+
+// mapTimeoutError returns the problem of the requests that exceeded the deadline of the Timeout
+// middleware.
+func mapTimeoutError(e error) (*Rfc7807Response, bool) {
+	var te *timeoutError
+	if !errors.As(e, &te) {
+		return nil, false
+	}
+	problem := &Rfc7807Response{
+		Type:   DeadlineExceededType,
+		Status: http.StatusGatewayTimeout,
+		Detail: fmt.Sprintf("The request did not complete within %s.", te.budget),
+	}
+	problem.setMeta(TimeoutMetaKey, te.budget.String())
+	return problem, true
+}
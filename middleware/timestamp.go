@@ -0,0 +1,54 @@
+package middleware
+
+import "time"
+
+// TimestampMetaKey is the meta key of the time problems were sent, see WithTimestamp.
+const TimestampMetaKey = "timestamp"
+
+// Clock tells the current time, see WithClock.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+}
+
+// ClockFunc is a function used as a Clock.
+type ClockFunc func() time.Time
+
+// Now returns f().
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// WithTimestamp makes the handler add the time problems are sent to them as an RFC 3339 UTC
+// timestamp in the "timestamp" meta value so that clients and support staff can correlate the
+// problems they report with the server logs. The timestamp is sent regardless of the verbosity.
+func WithTimestamp(enabled bool) Option {
+	return func(o *options) {
+		o.timestamp = enabled
+	}
+}
+
+// WithClock sets the clock telling the time of the problem timestamps, audit records, dead letter
+// entries and SLO windows, for example to freeze time in tests:
+//
+//	middleware.WithClock(middleware.ClockFunc(func() time.Time { return frozen }))
+func WithClock(c Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// now returns the current time according to the configured clock.
+func (o *options) now() time.Time {
+	if o.clock != nil {
+		return o.clock.Now()
+	}
+	return time.Now()
+}
+
+// setTimestamp adds the timestamp meta value to problem if enabled.
+func (o *options) setTimestamp(problem *Rfc7807Response) {
+	if o.timestamp {
+		problem.setMeta(TimestampMetaKey, o.now().UTC().Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import "net/http"
+
+// WithDefaultTitle sets the title of problems that do not have one, by default the title is the
+// text of the problem status.
+func WithDefaultTitle(title string) Option {
+	return func(o *options) {
+		o.title = title
+	}
+}
+
+// defaultTitle sets the title of problem if empty, an empty title is never useful to clients.
+func (o *options) defaultTitle(problem *Rfc7807Response) {
+	if problem.Title != "" {
+		return
+	}
+	if o.title != "" {
+		problem.Title = o.title
+		return
+	}
+	problem.Title = http.StatusText(problem.Status)
+	if problem.Title == "" {
+		problem.Title = http.StatusText(http.StatusInternalServerError)
+		if problem.Status >= 400 && problem.Status < 500 {
+			problem.Title = http.StatusText(http.StatusBadRequest)
+		}
+	}
+}
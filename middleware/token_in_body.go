@@ -0,0 +1,23 @@
+package middleware
+
+import "context"
+
+// WithTokenInBody sets a function that decides per request whether the error token and code are
+// included in the trace_id and code members of problems, for example to only expose tokens to
+// internal callers. Tokens are logged regardless: the handler records them in the goa response data
+// ErrorCode field which is logged by the LogRequest middleware and includes them in its own error
+// log entries.
+func WithTokenInBody(f func(context.Context) bool) Option {
+	return func(o *options) {
+		o.tokenInBody = f
+	}
+}
+
+// hideToken removes the error token and code from problem if it must not be sent for the request
+// with context ctx.
+func (o *options) hideToken(ctx context.Context, problem *Rfc7807Response) {
+	if o.tokenInBody != nil && !o.tokenInBody(ctx) {
+		problem.TraceID = ""
+		problem.Code = ""
+	}
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// WithTraceIDComposer sets the function used to generate trace IDs for errors that do not carry
+// one already, in place of the default random short ID. Characters that are not safe in an HTTP
+// header value are replaced with "-" in the composed ID. Empty IDs fall back to a short ID.
+func WithTraceIDComposer(f func(context.Context, *http.Request) string) Option {
+	return func(o *options) {
+		o.traceIDComposer = f
+	}
+}
+
+// WithContextTraceID makes the ID returned by f for the request context, when not empty, the trace
+// ID of all problems, for example the ID of the distributed trace of the request. It is applied
+// before the problems are logged so that the id and trace_id log fields match the trace ID of the
+// responses.
+func WithContextTraceID(f func(context.Context) string) Option {
+	return func(o *options) {
+		o.contextTraceIDFunc = f
+	}
+}
+
+// contextTraceID returns the trace ID of the problems of the request with context ctx set with
+// WithContextTraceID, if any.
+func (o *options) contextTraceID(ctx context.Context) string {
+	if o.contextTraceIDFunc == nil {
+		return ""
+	}
+	return headerSafe(o.contextTraceIDFunc(ctx))
+}
+
+// IDGenerator produces the trace IDs of internal errors.
+type IDGenerator interface {
+	// NewID returns a new unique ID.
+	NewID() string
+}
+
+// IDGeneratorFunc is an IDGenerator implemented by a function.
+type IDGeneratorFunc func() string
+
+// NewID calls f.
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
+// WithIDGenerator sets the generator of trace IDs replacing the default random short ID, see
+// UUIDGenerator, ULIDGenerator, KSUIDGenerator and PrefixedGenerator for built-in generators. A trace
+// ID composer set with WithTraceIDComposer takes precedence over g.
+func WithIDGenerator(g IDGenerator) Option {
+	return func(o *options) {
+		o.idGenerator = g
+	}
+}
+
+// WithTraceIDParts configures trace IDs composed of the service name, the region and a random
+// suffix separated with dashes, e.g. "svcA-euw1-ab12cd", so that IDs are easy to grep for.
+func WithTraceIDParts(service, region string) Option {
+	return WithTraceIDComposer(func(context.Context, *http.Request) string {
+		return compositeID(service, region)
+	})
+}
+
+// newTraceID produces a trace ID using the configured composer or generator or a short ID by
+// default.
+func (o *options) newTraceID(ctx context.Context, req *http.Request) string {
+	var id string
+	switch {
+	case o.traceIDComposer != nil:
+		id = headerSafe(o.traceIDComposer(ctx, req))
+	case o.idGenerator != nil:
+		id = headerSafe(o.idGenerator.NewID())
+	}
+	if id == "" {
+		return shortID()
+	}
+	return id
+}
+
+// compositeID joins the non empty parts and a 6 characters random hex suffix with dashes.
+func compositeID(parts ...string) string {
+	elems := make([]string, 0, len(parts)+1)
+	for _, p := range parts {
+		if p != "" {
+			elems = append(elems, p)
+		}
+	}
+	b := make([]byte, 3)
+	io.ReadFull(rand.Reader, b)
+	return strings.Join(append(elems, hex.EncodeToString(b)), "-")
+}
+
+// headerSafe replaces the characters of s that may not appear in an HTTP header value token with
+// "-" and trims surrounding whitespace.
+func headerSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(s))
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// TraceparentHeader is the W3C Trace Context header that carries the trace ID of a request.
+const TraceparentHeader = "traceparent"
+
+// WithTraceparent makes the handler use the trace-id of the W3C traceparent request header as the
+// request ID when the context records none, so that the trace IDs of internal errors and the log
+// entries line up with the traces collected by W3C compliant tracers. Invalid headers are ignored,
+// as is the tracestate header which only carries vendor specific data.
+func WithTraceparent(enabled bool) Option {
+	return func(o *options) {
+		o.traceparent = enabled
+	}
+}
+
+// Traceparent returns the trace-id and parent-id of the traceparent header of req if it is valid
+// as defined by the W3C Trace Context recommendation.
+func Traceparent(req *http.Request) (traceID, parentID string, ok bool) {
+	v := strings.TrimSpace(req.Header.Get(TraceparentHeader))
+	if len(v) < 55 || (len(v) > 55 && v[55] != '-') {
+		return "", "", false
+	}
+	version, traceID, parentID, flags := v[0:2], v[3:35], v[36:52], v[53:55]
+	if v[2] != '-' || v[35] != '-' || v[52] != '-' {
+		return "", "", false
+	}
+	if !isLowerHex(version) || version == "ff" || (version == "00" && len(v) != 55) {
+		return "", "", false
+	}
+	if !isLowerHex(traceID) || !isLowerHex(parentID) || !isLowerHex(flags) {
+		return "", "", false
+	}
+	if strings.Trim(traceID, "0") == "" || strings.Trim(parentID, "0") == "" {
+		return "", "", false
+	}
+	return traceID, parentID, true
+}
+
+// traceparentRequestID returns a copy of ctx recording the trace-id of the traceparent header of
+// req as the request ID if enabled and ctx records none.
+func (o *options) traceparentRequestID(ctx context.Context, req *http.Request) context.Context {
+	if !o.traceparent {
+		return ctx
+	}
+	if _, ok := RequestID(ctx); ok {
+		return ctx
+	}
+	if traceID, _, ok := Traceparent(req); ok {
+		return WithRequestID(ctx, traceID)
+	}
+	return ctx
+}
+
+// isLowerHex returns true if s only contains lowercase hexadecimal digits.
+func isLowerHex(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
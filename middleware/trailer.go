@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+// TraceIDTrailer is the name of the trailer that carries the request trace ID.
+const TraceIDTrailer = "X-Trace-Id"
+
+// WithTraceIDTrailer declares the X-Trace-Id trailer on all responses and sets it to the request
+// trace ID once the downstream handler returns, whether it succeeded or not. This lets clients of
+// streaming endpoints correlate responses that fail after the status and part of the body have
+// been sent. The trace ID is read from the context or generated when the request starts so that
+// error log entries use the same ID.
+func WithTraceIDTrailer(enabled bool) Option {
+	return func(o *options) {
+		o.traceIDTrailer = enabled
+	}
+}
+
+// declareTraceIDTrailer declares the trace ID trailer and makes sure ctx records a trace ID.
+func (o *options) declareTraceIDTrailer(ctx context.Context, rw http.ResponseWriter, req *http.Request) context.Context {
+	if !o.traceIDTrailer {
+		return ctx
+	}
+	if _, ok := RequestID(ctx); !ok {
+		ctx = WithRequestID(ctx, o.newTraceID(ctx, req))
+	}
+	rw.Header().Add("Trailer", TraceIDTrailer)
+	return ctx
+}
+
+// setTraceIDTrailer sets the trace ID trailer declared by declareTraceIDTrailer.
+func (o *options) setTraceIDTrailer(ctx context.Context, rw http.ResponseWriter) {
+	if !o.traceIDTrailer {
+		return
+	}
+	id, _ := RequestID(ctx)
+	rw.Header().Set(TraceIDTrailer, id)
+}
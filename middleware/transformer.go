@@ -0,0 +1,31 @@
+package middleware
+
+import "context"
+
+// Transformer adjusts the problem about to be sent in response to the request with context ctx,
+// see WithTransformers.
+type Transformer func(ctx context.Context, problem *Rfc7807Response)
+
+// WithTransformers appends transformers applied in registration order, across all uses of the
+// option, to problems right before they are serialized, for cross-cutting adjustments such as
+// adding tenant IDs, rewriting legacy fields or normalizing titles:
+//
+//	middleware.WithTransformers(func(ctx context.Context, problem *middleware.Rfc7807Response) {
+//		problem.Title = strings.ToUpper(problem.Title[:1]) + problem.Title[1:]
+//	})
+//
+// Transformers run after the before send hooks and all the built-in processing, including the
+// detail filters and size limits, so their changes are final and are what observers, metrics and
+// audit records see. The status of the response cannot be changed, use an interceptor for that.
+func WithTransformers(ts ...Transformer) Option {
+	return func(o *options) {
+		o.transformers = append(o.transformers, ts...)
+	}
+}
+
+// transform applies the transformers to problem.
+func (o *options) transform(ctx context.Context, problem *Rfc7807Response) {
+	for _, t := range o.transformers {
+		t(ctx, problem)
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// WithTrustedProxies sets the networks of the reverse proxies in front of the service given in
+// CIDR notation, e.g. "10.0.0.0/8", so that the per client log rate limits identify clients by the
+// right-most X-Forwarded-For hop that is not a trusted proxy when the request comes from one.
+// Without trusted proxies, or for requests not coming from one, clients are identified by the
+// remote address of the connection as X-Forwarded-For is controlled by clients. It panics if one
+// of the CIDRs is invalid.
+func WithTrustedProxies(cidrs ...string) Option {
+	networks := mustParseNetworks(cidrs)
+	return func(o *options) {
+		o.trustedProxies = append(o.trustedProxies, networks...)
+	}
+}
+
+// clientIP returns the IP of the client of req according to the trusted proxies.
+func (o *options) clientIP(req *http.Request) string {
+	return clientIP(req, o.trustedProxies)
+}
+
+// parseNetworks returns the networks given in CIDR notation or an error if one of the CIDRs is
+// invalid.
+func parseNetworks(cidrs []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %s", c, err)
+		}
+		networks = append(networks, n)
+	}
+	return networks, nil
+}
+
+// mustParseNetworks is parseNetworks panicking on invalid CIDRs so that misconfigured networks
+// fail at startup rather than trusting nothing.
+func mustParseNetworks(cidrs []string) []*net.IPNet {
+	networks, err := parseNetworks(cidrs)
+	if err != nil {
+		panic(err)
+	}
+	return networks
+}
+
+// clientIP returns the IP of the client of req: the remote address of the connection unless it is
+// one of the trusted networks, the right-most X-Forwarded-For hop that is not a trusted proxy
+// otherwise, or the left-most hop if they all are.
+func clientIP(req *http.Request, trusted []*net.IPNet) string {
+	ip := remoteIP(req)
+	if !inNetworks(trusted, ip) {
+		return ip
+	}
+	var hops []string
+	for _, h := range req.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(h, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		if !inNetworks(trusted, hops[i]) {
+			return hops[i]
+		}
+	}
+	if len(hops) > 0 {
+		return hops[0]
+	}
+	return ip
+}
+
+// remoteIP returns the IP of the remote address of the connection of req.
+func remoteIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// inNetworks returns true if ip is in one of networks.
+func inNetworks(networks []*net.IPNet, ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range networks {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package middleware
+
+// WithExtraUnwrapMethods makes the handler also recognize the Reason() error and Underlying() error
+// methods exposed by some error libraries when looking for the cause of errors, in addition to
+// the Cause() error and Unwrap() error methods recognized by default.
+func WithExtraUnwrapMethods(enabled bool) Option {
+	return func(o *options) {
+		o.extraUnwrapMethods = enabled
+	}
+}
+
+// unwrap returns the function used to unwrap errors when looking for their cause.
+func (o *options) unwrap() func(error) error {
+	if o.extraUnwrapMethods {
+		return unwrapExtra
+	}
+	return unwrapCause
+}
+
+// unwrapExtra is unwrapCause extended with the Reason() error and Underlying() error methods.
+func unwrapExtra(e error) error {
+	if c := unwrapCause(e); c != nil {
+		return c
+	}
+	switch w := e.(type) {
+	case interface{ Reason() error }:
+		return w.Reason()
+	case interface{ Underlying() error }:
+		return w.Underlying()
+	}
+	return nil
+}
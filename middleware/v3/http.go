@@ -0,0 +1,108 @@
+package v3
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"net/http"
+	"strings"
+
+	goahttp "goa.design/goa/v3/http"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+type (
+	// markerKey is the context key of the problem marker.
+	markerKey struct{}
+
+	// problemMarker records whether the error formatter produced the response body.
+	problemMarker struct {
+		problem bool
+	}
+
+	// problemWriter sets the problem media type of responses marked as problems.
+	problemWriter struct {
+		http.ResponseWriter
+		marker *problemMarker
+	}
+)
+
+// NewErrorHandler returns a goa v3 error handler that writes err as a problem, use it as the
+// errhandler argument of the generated server constructors. The problem is serialized as XML if
+// the Accept header stored in the context by the generated code prefers XML and as JSON
+// otherwise.
+func NewErrorHandler(opts ...Option) func(ctx context.Context, w http.ResponseWriter, err error) {
+	o := newOptions(opts...)
+	return func(ctx context.Context, w http.ResponseWriter, err error) {
+		problem := o.newProblem(ctx, err)
+		accept, _ := ctx.Value(goahttp.AcceptTypeKey).(string)
+		if prefersXML(accept) {
+			w.Header().Set("Content-Type", middleware.Rfc7807XmlMediaIdentifier)
+			w.WriteHeader(problem.Status)
+			w.Write([]byte(xml.Header))
+			xml.NewEncoder(w).Encode(problem)
+			return
+		}
+		w.Header().Set("Content-Type", middleware.Rfc7807JsonMediaIdentifier)
+		w.WriteHeader(problem.Status)
+		json.NewEncoder(w).Encode(problem)
+	}
+}
+
+// ProblemContentType is an HTTP middleware that gives the responses produced with the error
+// formatter returned by NewErrorFormatter a problem media type: the goa v3 response encoders set
+// the content type to application/json or application/xml which is changed to
+// application/problem+json or application/problem+xml respectively. Other responses are left
+// unchanged.
+func ProblemContentType(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		marker := &problemMarker{}
+		ctx := context.WithValue(r.Context(), markerKey{}, marker)
+		h.ServeHTTP(&problemWriter{ResponseWriter: w, marker: marker}, r.WithContext(ctx))
+	})
+}
+
+// WriteHeader sets the problem media type if the response is a problem and writes the header.
+func (w *problemWriter) WriteHeader(status int) {
+	if w.marker.problem {
+		mt, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+		switch mt {
+		case "application/json":
+			w.Header().Set("Content-Type", middleware.Rfc7807JsonMediaIdentifier)
+		case "application/xml":
+			w.Header().Set("Content-Type", middleware.Rfc7807XmlMediaIdentifier)
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap returns the wrapped response writer for http.ResponseController.
+func (w *problemWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// markProblem marks the response of the request with context ctx as a problem.
+func markProblem(ctx context.Context) {
+	if m, ok := ctx.Value(markerKey{}).(*problemMarker); ok {
+		m.problem = true
+	}
+}
+
+// prefersXML returns true if the first media range of accept that names JSON or XML names XML.
+func prefersXML(accept string) bool {
+	for _, r := range strings.Split(accept, ",") {
+		mt, _, err := mime.ParseMediaType(strings.TrimSpace(r))
+		if err != nil {
+			continue
+		}
+		switch {
+		case mt == "application/xml" || mt == "text/xml" || strings.HasSuffix(mt, "+xml"):
+			return true
+		case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+			return false
+		}
+	}
+	return false
+}
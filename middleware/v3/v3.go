@@ -0,0 +1,166 @@
+// Package v3 adapts the RFC 7807 problem responses of package middleware to goa v3 services. It
+// shares the problem representation, the problem type registry and the ID generators of the goa
+// v1 middleware and provides an error formatter, an error handler and an HTTP middleware that
+// plug into the servers generated by goa v3.
+package v3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	goahttp "goa.design/goa/v3/http"
+	goa "goa.design/goa/v3/pkg"
+
+	"github.com/blueoceans/goans/middleware"
+)
+
+type (
+	// Problem is the goa v3 representation of a problem. It implements goahttp.Statuser so that
+	// it can be returned by error formatters and serializes like middleware.Rfc7807Response.
+	Problem struct {
+		*middleware.Rfc7807Response
+	}
+
+	// Option configures the goa v3 adapters.
+	Option func(*options)
+
+	// options holds the settings of the goa v3 adapters.
+	options struct {
+		// verbose includes the details of internal errors in responses.
+		verbose bool
+		// idGenerator produces the trace IDs of errors without ID.
+		idGenerator middleware.IDGenerator
+		// problemTypes is the registry of problem types used in place of middleware.ProblemTypes
+		// when not nil.
+		problemTypes *middleware.ProblemTypeRegistry
+		// typeBaseURI is the URI relative problem types are resolved against.
+		typeBaseURI string
+	}
+)
+
+// StatusCode returns the HTTP status of the problem.
+func (p *Problem) StatusCode() int {
+	return p.Status
+}
+
+// WithVerbose sets whether the details of internal errors are included in responses.
+func WithVerbose(verbose bool) Option {
+	return func(o *options) {
+		o.verbose = verbose
+	}
+}
+
+// WithIDGenerator sets the generator of the trace IDs of errors that do not have an ID, it
+// defaults to goa.NewErrorID.
+func WithIDGenerator(g middleware.IDGenerator) Option {
+	return func(o *options) {
+		o.idGenerator = g
+	}
+}
+
+// WithProblemTypes sets the registry consulted in place of middleware.ProblemTypes. The codes of
+// goa v3 service errors are their names, e.g. "missing_field".
+func WithProblemTypes(r *middleware.ProblemTypeRegistry) Option {
+	return func(o *options) {
+		o.problemTypes = r
+	}
+}
+
+// WithTypeBaseURI sets the absolute URI that relative problem type references are resolved
+// against.
+func WithTypeBaseURI(base string) Option {
+	return func(o *options) {
+		o.typeBaseURI = base
+	}
+}
+
+// NewErrorFormatter returns a goa v3 error formatter producing problems, use it as the formatter
+// argument of the generated server constructors together with ProblemContentType so that the
+// responses have a problem media type.
+func NewErrorFormatter(opts ...Option) func(ctx context.Context, err error) goahttp.Statuser {
+	o := newOptions(opts...)
+	return func(ctx context.Context, err error) goahttp.Statuser {
+		markProblem(ctx)
+		return o.newProblem(ctx, err)
+	}
+}
+
+// newOptions returns the adapter settings resulting from applying opts in order.
+func newOptions(opts ...Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// newProblem creates the problem corresponding to err. goa service errors are mapped to the same
+// status as the default goa v3 error response, errors implementing goahttp.Statuser use their
+// status and other errors are internal errors whose detail is only sent in verbose mode.
+func (o *options) newProblem(ctx context.Context, err error) *Problem {
+	problem := &middleware.Rfc7807Response{Status: http.StatusInternalServerError, Detail: err.Error()}
+	var (
+		gerr     *goa.ServiceError
+		statuser goahttp.Statuser
+	)
+	switch {
+	case errors.As(err, &gerr):
+		problem.Status = goahttp.NewErrorResponse(ctx, gerr).StatusCode()
+		problem.Detail = gerr.Message
+		problem.TraceID = gerr.ID
+		problem.Code = gerr.Name
+		if gerr.Field != nil {
+			problem.Meta = map[string]interface{}{"field": *gerr.Field}
+		}
+		o.applyProblemType(gerr.Name, problem)
+	case errors.As(err, &statuser):
+		problem.Status = statuser.StatusCode()
+	}
+	if problem.TraceID == "" {
+		problem.TraceID = o.newID()
+	}
+	if problem.Status == http.StatusInternalServerError && !o.verbose {
+		problem.Detail = fmt.Sprintf("%s [%s]", http.StatusText(http.StatusInternalServerError), problem.TraceID)
+		problem.Meta = nil
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	return &Problem{problem}
+}
+
+// newID returns a new trace ID.
+func (o *options) newID() string {
+	if o.idGenerator != nil {
+		if id := o.idGenerator.NewID(); id != "" {
+			return id
+		}
+	}
+	return goa.NewErrorID()
+}
+
+// applyProblemType sets the type and title of problem to the ones registered for name.
+func (o *options) applyProblemType(name string, problem *middleware.Rfc7807Response) {
+	r := o.problemTypes
+	if r == nil {
+		r = middleware.ProblemTypes
+	}
+	t, ok := r.Lookup(name)
+	if !ok {
+		return
+	}
+	problem.Type, problem.Title = t.URI, t.Title
+	if o.typeBaseURI == "" || problem.Type == "" {
+		return
+	}
+	ref, err := url.Parse(problem.Type)
+	if err != nil || ref.IsAbs() {
+		return
+	}
+	if base, err := url.Parse(o.typeBaseURI); err == nil {
+		problem.Type = base.ResolveReference(ref).String()
+	}
+}
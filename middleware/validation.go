@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/goadesign/goa"
+)
+
+// ValidationErrorsMetaKey is the meta key of the per field errors of validation problems.
+const ValidationErrorsMetaKey = "errors"
+
+// FieldError describes the failed validation of a request parameter, header or payload field.
+type FieldError struct {
+	// Field is the name of the parameter or header or the dotted path of the payload field.
+	Field string `json:"field" xml:"field" form:"field"`
+	// Code identifies the validation that failed, e.g. "missing" or "invalid_pattern".
+	Code string `json:"code" xml:"code" form:"code"`
+	// Message is the goa validation error message.
+	Message string `json:"message" xml:"message" form:"message"`
+	// Pointer is the JSON pointer [RFC6901] to the payload field, it is empty for parameters and
+	// headers.
+	Pointer string `json:"pointer,omitempty" xml:"pointer,omitempty" form:"pointer,omitempty"`
+}
+
+// validationPatterns recognizes the messages of the goa validation errors. The first submatch is
+// the field, kind tells whether it is a payload attribute, a parameter or a header.
+var validationPatterns = []struct {
+	re   *regexp.Regexp
+	code string
+	kind string
+}{
+	{regexp.MustCompile(`^invalid value .* for parameter "(.+?)", must be a `), "invalid_type", "param"},
+	{regexp.MustCompile(`^missing required parameter "(.+?)"$`), "missing", "param"},
+	{regexp.MustCompile(`^missing required HTTP header "(.+?)"$`), "missing", "header"},
+	{regexp.MustCompile(`^attribute "(.+?)" of (.+?) is missing and required$`), "missing", "attribute"},
+	{regexp.MustCompile(`^type of (.+?) must be .+ but got value `), "invalid_type", "attribute"},
+	{regexp.MustCompile(`^value of (.+?) must be one of .+ but got value `), "invalid_enum", "attribute"},
+	{regexp.MustCompile(`^length of (.+?) must be (?:greater|less) than or equal to `), "invalid_length", "attribute"},
+	{regexp.MustCompile(`^(.+?) must be formatted as a .+ but got value `), "invalid_format", "attribute"},
+	{regexp.MustCompile(`^(.+?) must match the regexp .+ but got value `), "invalid_pattern", "attribute"},
+	{regexp.MustCompile(`^(.+?) must be (?:greater|less) than or equal to .+ but got value `), "invalid_range", "attribute"},
+}
+
+var (
+	// indexRegexp matches the array indexes of goa attribute paths, e.g. "[2]".
+	indexRegexp = regexp.MustCompile(`\[(\d+)\]`)
+	// pointerEscaper escapes the reference tokens of JSON pointers.
+	pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+)
+
+// validationErrors returns the per field errors described by the detail of the goa validation
+// error resp, goa merges the messages of multiple validation errors with "; ". It returns nil if
+// resp is not a validation error or if none of its messages is recognized.
+func validationErrors(resp *goa.ErrorResponse) []FieldError {
+	if resp.Code != "invalid_request" && resp.Code != "bad_request" {
+		return nil
+	}
+	var (
+		errs       []FieldError
+		recognized bool
+	)
+	for _, msg := range strings.Split(resp.Detail, "; ") {
+		fe := FieldError{Code: "invalid", Message: msg}
+		for _, p := range validationPatterns {
+			m := p.re.FindStringSubmatch(msg)
+			if m == nil {
+				continue
+			}
+			fe.Code, fe.Field = p.code, m[1]
+			if p.kind == "attribute" {
+				if len(m) > 2 {
+					fe.Field = m[2] + "." + m[1]
+				}
+				fe.Field = attributeField(fe.Field)
+				fe.Pointer = jsonPointer(fe.Field)
+			}
+			recognized = true
+			break
+		}
+		errs = append(errs, fe)
+	}
+	if !recognized {
+		return nil
+	}
+	return errs
+}
+
+// attributeField strips the "raw" or "payload" root of the goa attribute path p.
+func attributeField(p string) string {
+	for _, root := range []string{"raw", "payload"} {
+		switch {
+		case p == root:
+			return ""
+		case strings.HasPrefix(p, root+"."):
+			return p[len(root)+1:]
+		case strings.HasPrefix(p, root+"["):
+			return p[len(root):]
+		}
+	}
+	return p
+}
+
+// jsonPointer returns the JSON pointer corresponding to the dotted field path f.
+func jsonPointer(f string) string {
+	f = indexRegexp.ReplaceAllString(f, ".$1")
+	var b strings.Builder
+	for _, tok := range strings.Split(strings.TrimPrefix(f, "."), ".") {
+		if tok == "" {
+			continue
+		}
+		b.WriteString("/" + pointerEscaper.Replace(tok))
+	}
+	return b.String()
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"mime"
+	"strings"
+)
+
+// vendorFormatPlaceholder is the placeholder of the format in vendor media type templates.
+const vendorFormatPlaceholder = "{format}"
+
+// WithVendorMediaType sends the JSON and XML problems with a vendor media type in place of
+// application/problem+json and application/problem+xml, for API programs that require branded error
+// types. template is a media type in which "{format}" is replaced with "json" and "xml", e.g.
+// "application/vnd.acme.error+{format}". Templates without placeholder get the "+json" and "+xml"
+// suffixes in place of their own +json or +xml suffix, if any, so that
+// "application/vnd.acme.error+json" gives "application/vnd.acme.error+json" and
+// "application/vnd.acme.error+xml". The structured syntax suffix still signals the format of
+// problems to generic clients. Serialization is unchanged, the vendor media types are negotiated
+// with the Accept header like the standard ones, which stay accepted, and are set in the
+// Content-Type header of all responses of the handler serializing problems as JSON or XML.
+func WithVendorMediaType(template string) Option {
+	return func(o *options) {
+		if !strings.Contains(template, vendorFormatPlaceholder) {
+			template = strings.TrimSpace(template)
+			for _, suffix := range []string{"+json", "+xml"} {
+				if strings.HasSuffix(strings.ToLower(template), suffix) {
+					template = template[:len(template)-len(suffix)]
+				}
+			}
+			template += "+" + vendorFormatPlaceholder
+		}
+		o.vendorMediaTypes = map[string]string{
+			Rfc7807JsonMediaIdentifier: vendorMediaType(template, "json"),
+			Rfc7807XmlMediaIdentifier:  vendorMediaType(template, "xml"),
+		}
+	}
+}
+
+// vendorMediaType returns the lowercase media type of template for format.
+func vendorMediaType(template, format string) string {
+	mt := strings.Replace(template, vendorFormatPlaceholder, format, -1)
+	if parsed, _, err := mime.ParseMediaType(mt); err == nil {
+		return parsed
+	}
+	return strings.ToLower(mt)
+}
+
+// contentType returns the Content-Type of problems of the canonical media type mediaType, the
+// vendor media type if one is configured.
+func (o *options) contentType(mediaType string) string {
+	if vt, ok := o.vendorMediaTypes[mediaType]; ok {
+		return vt
+	}
+	return mediaType
+}
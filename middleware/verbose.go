@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/goadesign/goa"
+)
+
+// WithVerbose sets whether the details of internal errors are included in responses, this is the
+// verbose flag of Rfc7807Handler.
+func WithVerbose(verbose bool) Option {
+	return func(o *options) {
+		o.verbose = verbose
+	}
+}
+
+// WithVerboseFromContext sets a function that decides per request whether the details of internal
+// errors are included in responses, for example to only expose them to trusted callers. The
+// function takes precedence over the verbose flag given to Rfc7807Handler or WithVerbose which is used
+// when f is nil.
+func WithVerboseFromContext(f func(context.Context) bool) Option {
+	return func(o *options) {
+		o.verboseFromContext = f
+	}
+}
+
+// isVerbose returns whether the details of internal errors may be sent in the response to the
+// request with context ctx.
+func (o *options) isVerbose(ctx context.Context) bool {
+	if o.verboseFromContext != nil {
+		return o.verboseFromContext(ctx)
+	}
+	return o.verbose
+}
+
+// VerbosityFunc decides whether the details of the error err sent with the given status are
+// included in the response to the request with context ctx.
+type VerbosityFunc func(ctx context.Context, err error, status int) bool
+
+// WithVerbosityFunc sets the verbosity policy of the handler, for example to send the details of
+// client errors but not those of server errors:
+//
+//	middleware.WithVerbosityFunc(func(_ context.Context, _ error, status int) bool {
+//		return status < 500
+//	})
+//
+// Unlike the verbose flag which only applies to internal errors, f is called for every problem and
+// the detail and meta values of the problems it returns false for are replaced with the status
+// text. f takes precedence over WithVerbose and WithVerboseFromContext. ForceVerbose, ForceTerse,
+// WithVerboseTokens, WithDebugToken and WithDebugNetworks take precedence over f.
+func WithVerbosityFunc(f VerbosityFunc) Option {
+	return func(o *options) {
+		o.verbosityFunc = f
+	}
+}
+
+// isVerboseFor returns whether the details of err sent with status may be included in the
+// response to req with context ctx.
+func (o *options) isVerboseFor(ctx context.Context, req *http.Request, err error, status int) bool {
+	if v, ok := forcedVerbosity(ctx); ok {
+		return v
+	}
+	if o.isVerboseToken(err) || o.isDebugRequest(req) {
+		return true
+	}
+	if o.verbosityFunc != nil {
+		return o.verbosityFunc(ctx, err, status)
+	}
+	return status != http.StatusInternalServerError || o.isVerbose(ctx)
+}
+
+// WithVerboseTokens lists the tokens of internal errors whose details are safe to send to clients
+// even when the handler is not verbose. The token of goa.ErrorResponse errors is their Code, the
+// token of other goa.ServiceError errors is the value returned by their Token method.
+func WithVerboseTokens(tokens ...string) Option {
+	return func(o *options) {
+		if o.verboseTokens == nil {
+			o.verboseTokens = make(map[string]bool, len(tokens))
+		}
+		for _, t := range tokens {
+			o.verboseTokens[t] = true
+		}
+	}
+}
+
+// isVerboseToken returns true if the details of err may be sent regardless of the verbosity.
+func (o *options) isVerboseToken(err error) bool {
+	if len(o.verboseTokens) == 0 {
+		return false
+	}
+	if se, ok := err.(goa.ServiceError); ok {
+		return o.verboseTokens[errorCode(se)]
+	}
+	return false
+}
+
+// ForceVerbose makes the handler send the details of the error returned for the request with
+// context ctx regardless of the verbosity settings, for example in internal admin endpoints. ctx
+// must derive from the context given to the handler by the Rfc7807Handler middleware or
+// HTTPMiddleware, see WithProblemMeta.
+func ForceVerbose(ctx context.Context) {
+	forceVerbosity(ctx, 1)
+}
+
+// ForceTerse makes the handler replace the details of the error returned for the request with
+// context ctx with the status text regardless of the verbosity settings, see ForceVerbose.
+func ForceTerse(ctx context.Context) {
+	forceVerbosity(ctx, -1)
+}
+
+// forceVerbosity records the verbosity v, 1 for verbose and -1 for terse, in ctx.
+func forceVerbosity(ctx context.Context, v int) {
+	pe, ok := ctx.Value(problemEnrichmentKey).(*problemEnrichment)
+	if !ok {
+		return
+	}
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pe.verbosity = v
+}
+
+// forcedVerbosity returns the verbosity recorded in ctx by ForceVerbose or ForceTerse if any.
+func forcedVerbosity(ctx context.Context) (bool, bool) {
+	pe, ok := ctx.Value(problemEnrichmentKey).(*problemEnrichment)
+	if !ok {
+		return false, false
+	}
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	return pe.verbosity > 0, pe.verbosity != 0
+}
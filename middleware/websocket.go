@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+)
+
+// The WebSocket close codes defined in RFC 6455 Section 7.4.1 used by CloseMessage.
+const (
+	CloseNormalClosure   = 1000
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+	CloseTryAgainLater   = 1013
+)
+
+const (
+	// maxCloseReasonLength is the maximum length of the reason of close frames.
+	maxCloseReasonLength = 123
+	// closeFrameCodeLength is the length of the code of close frames.
+	closeFrameCodeLength = 2
+)
+
+// CloseCode returns the WebSocket close code corresponding to the HTTP status of a problem: 1007
+// for unprocessable payloads, 1009 for too large payloads, 1013 for 429 and 503, 1011 for the other
+// server errors and 1008 for the other client errors.
+func CloseCode(status int) int {
+	switch {
+	case status == http.StatusBadRequest || status == http.StatusUnprocessableEntity ||
+		status == http.StatusUnsupportedMediaType:
+		return CloseInvalidPayload
+	case status == http.StatusRequestEntityTooLarge:
+		return CloseMessageTooBig
+	case status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable:
+		return CloseTryAgainLater
+	case status >= 500:
+		return CloseInternalError
+	case status >= 400:
+		return ClosePolicyViolation
+	}
+	return CloseNormalClosure
+}
+
+// CloseMessage converts err into the code and reason of the close frame ending the WebSocket
+// connection upgraded from req, so that socket clients get structured failures too. err is mapped,
+// typed and masked like the errors of HTTP requests but only rendered: it is not logged, reported,
+// counted, audited or delayed, the handler returning it records the failure. The code is given by
+// CloseCode and the reason is a compact JSON problem with the status, code, trace ID, type, title
+// and detail members. Members are dropped, starting with the detail and the title, until the reason
+// fits the 123 bytes limit of close frames. For example with github.com/gorilla/websocket:
+//
+//	code, reason := p.CloseMessage(req, err)
+//	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+func (p *ProblemHandler) CloseMessage(req *http.Request, err error) (int, string) {
+	status, body := p.renderJSON(req, err)
+	var problem Rfc7807Response
+	if json.Unmarshal(body, &problem) != nil || problem.Status == 0 {
+		problem = Rfc7807Response{Status: status}
+	}
+	if problem.Status == 0 {
+		problem.Status = http.StatusInternalServerError
+	}
+	return CloseCode(problem.Status), closeReason(&problem)
+}
+
+// CloseFramePayload returns the payload of a close frame with the given code and reason for
+// WebSocket libraries that write raw control frames.
+func CloseFramePayload(code int, reason string) []byte {
+	b := make([]byte, closeFrameCodeLength, closeFrameCodeLength+len(reason))
+	binary.BigEndian.PutUint16(b, uint16(code))
+	return append(b, reason...)
+}
+
+// closeReason returns the JSON representation of problem that fits a close frame.
+func closeReason(problem *Rfc7807Response) string {
+	members := []struct {
+		name  string
+		value string
+	}{
+		{"code", problem.Code},
+		{"trace_id", problem.TraceID},
+		{"type", problem.Type},
+		{"title", problem.Title},
+		{"detail", problem.Detail},
+	}
+	for n := len(members); n >= 0; n-- {
+		var buf bytes.Buffer
+		buf.WriteString(`{"status":`)
+		b, _ := json.Marshal(problem.Status)
+		buf.Write(b)
+		for _, m := range members[:n] {
+			if m.value == "" {
+				continue
+			}
+			buf.WriteString(`,"` + m.name + `":`)
+			writeJSONString(&buf, m.value)
+		}
+		buf.WriteByte('}')
+		if buf.Len() <= maxCloseReasonLength {
+			return buf.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import "fmt"
+
+// wrappedError is embedded by the errors that wrap an error to carry extra data, e.g. a retry
+// delay, without changing its message and formatting.
+type wrappedError struct {
+	err error
+}
+
+// Error returns the message of the wrapped error.
+func (e wrappedError) Error() string {
+	return e.err.Error()
+}
+
+// Format formats the wrapped error so that %+v prints its stack trace if it has one.
+func (e wrappedError) Format(s fmt.State, verb rune) {
+	if f, ok := e.err.(fmt.Formatter); ok {
+		f.Format(s, verb)
+		return
+	}
+	fmt.Fprint(s, e.err.Error())
+}
+
+// Unwrap returns the wrapped error.
+func (e wrappedError) Unwrap() error {
+	return e.err
+}